@@ -0,0 +1,6 @@
+package dto
+
+// ReplayCaptureDTO 重放抓取的请求, Model 为空时使用原模型
+type ReplayCaptureDTO struct {
+	Model string `json:"model"`
+}
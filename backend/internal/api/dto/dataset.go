@@ -0,0 +1,31 @@
+package dto
+
+type CreateDatasetDTO struct {
+	PromptID    string `json:"promptId" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateDatasetDTO struct {
+	ID          string `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type CreateDatasetRowDTO struct {
+	DatasetID string            `json:"datasetId" binding:"required"`
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+	Reference string            `json:"reference"`
+}
+
+type UpdateDatasetRowDTO struct {
+	ID        string            `json:"id" binding:"required"`
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+	Reference string            `json:"reference"`
+}
+
+type CreateDatasetVersionDTO struct {
+	ChangeLog string `json:"changeLog"`
+}
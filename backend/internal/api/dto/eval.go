@@ -0,0 +1,99 @@
+package dto
+
+import "backend/internal/service/eval/scorer"
+
+// CreateEvalRunDTO 支持 JSON 或 multipart 表单, 表单上传 file 时不使用数据集
+type CreateEvalRunDTO struct {
+	VersionID      string   `json:"versionId" form:"versionId" binding:"required"`
+	Model          string   `json:"model" form:"model" binding:"required"`
+	DatasetID      string   `json:"datasetId" form:"datasetId"`
+	DatasetVersion int      `json:"datasetVersion" form:"datasetVersion"`
+	Concurrency    int      `json:"concurrency" form:"concurrency"`
+	Temperature    *float64 `json:"temperature" form:"temperature"`
+	MaxTokens      int      `json:"maxTokens" form:"maxTokens"`
+}
+
+type ScoreDTO struct {
+	Items []scorer.Item `json:"items" binding:"required"`
+	// Assertions 与 AssertionSetID 二选一, 都提供时使用 Assertions
+	Assertions     []scorer.Assertion `json:"assertions"`
+	AssertionSetID string             `json:"assertionSetId"`
+}
+
+type CreateAssertionSetDTO struct {
+	PromptID    string             `json:"promptId" binding:"required"`
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Assertions  []scorer.Assertion `json:"assertions" binding:"required"`
+}
+
+type UpdateAssertionSetDTO struct {
+	ID          string             `json:"id" binding:"required"`
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Assertions  []scorer.Assertion `json:"assertions" binding:"required"`
+}
+
+type JudgeItemDTO struct {
+	Input     string            `json:"input"`
+	Output    string            `json:"output"`
+	Expected  string            `json:"expected"`
+	Reference string            `json:"reference"`
+	Variables map[string]string `json:"variables"`
+}
+
+// JudgeDTO 评分标准是 RubricPath 对应的提示词, RubricVersion 为空时使用已发布版本
+type JudgeDTO struct {
+	RubricPath    string         `json:"rubricPath" binding:"required"`
+	RubricVersion string         `json:"rubricVersion"`
+	Model         string         `json:"model" binding:"required"`
+	Items         []JudgeItemDTO `json:"items" binding:"required"`
+	// MinScore / MaxScore 分数范围, 默认 1 ~ 10; PassScore 不为空时分数不低于该值才算通过
+	MinScore  *float64 `json:"minScore"`
+	MaxScore  *float64 `json:"maxScore"`
+	PassScore *float64 `json:"passScore"`
+	// Retries 评审结果无法解析时的重试次数
+	Retries     *int     `json:"retries"`
+	Concurrency int      `json:"concurrency"`
+	Temperature *float64 `json:"temperature"`
+	// StructuredOutput 是否发送 response_format 要求模型返回 JSON, 默认 true
+	StructuredOutput *bool `json:"structuredOutput"`
+}
+
+// CreateEvalCompareDTO 两个版本使用相同的输入和模型参数, 输入来源与 CreateEvalRunDTO 相同
+type CreateEvalCompareDTO struct {
+	BaseVersionID      string   `json:"baseVersionId" form:"baseVersionId" binding:"required"`
+	CandidateVersionID string   `json:"candidateVersionId" form:"candidateVersionId" binding:"required"`
+	Model              string   `json:"model" form:"model" binding:"required"`
+	DatasetID          string   `json:"datasetId" form:"datasetId"`
+	DatasetVersion     int      `json:"datasetVersion" form:"datasetVersion"`
+	Concurrency        int      `json:"concurrency" form:"concurrency"`
+	Temperature        *float64 `json:"temperature" form:"temperature"`
+	MaxTokens          int      `json:"maxTokens" form:"maxTokens"`
+	// Assertions 仅支持 JSON 请求体, 与 AssertionSetID 都提供时使用 Assertions
+	Assertions          []scorer.Assertion `json:"assertions" form:"-"`
+	AssertionSetID      string             `json:"assertionSetId" form:"assertionSetId"`
+	RegressionThreshold float64            `json:"regressionThreshold" form:"regressionThreshold"`
+}
+
+type GateCaseDTO struct {
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+}
+
+// SaveGateDTO 保存提示词的发布门禁, 已存在时覆盖; Assertions 为空时要求输出与 expected 相等,
+// MinPassRate 默认 1
+type SaveGateDTO struct {
+	PromptID    string             `json:"promptId" binding:"required"`
+	Model       string             `json:"model" binding:"required"`
+	Cases       []GateCaseDTO      `json:"cases" binding:"required"`
+	Assertions  []scorer.Assertion `json:"assertions"`
+	MinPassRate *float64           `json:"minPassRate"`
+	Temperature *float64           `json:"temperature"`
+	MaxTokens   int                `json:"maxTokens"`
+	Enabled     *bool              `json:"enabled"`
+}
+
+type CheckGateDTO struct {
+	VersionID string `json:"versionId" binding:"required"`
+}
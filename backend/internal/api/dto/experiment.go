@@ -0,0 +1,15 @@
+package dto
+
+// ExperimentVariantDTO 实验的一个分组, 按 Weight 占所有分组权重之和的比例分配流量
+type ExperimentVariantDTO struct {
+	Name      string `json:"name"`
+	VersionID string `json:"versionId"`
+	Weight    int    `json:"weight"`
+}
+
+// SaveExperimentDTO 保存提示词的 A/B 分流实验, 已存在时覆盖; Enabled 默认 true
+type SaveExperimentDTO struct {
+	PromptID string                 `json:"promptId" binding:"required"`
+	Variants []ExperimentVariantDTO `json:"variants" binding:"required"`
+	Enabled  *bool                  `json:"enabled"`
+}
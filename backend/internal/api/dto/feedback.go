@@ -0,0 +1,11 @@
+package dto
+
+// SubmitFeedbackDTO 下游应用上报对一次输出的评价; Path 与 VersionID 取自获取提示词内容接口的返回
+type SubmitFeedbackDTO struct {
+	Path      string   `json:"path" binding:"required"`
+	VersionID string   `json:"versionId" binding:"required"`
+	TraceID   string   `json:"traceId"`
+	Score     *float64 `json:"score" binding:"required"`
+	Tags      []string `json:"tags"`
+	Comment   string   `json:"comment"`
+}
@@ -9,6 +9,8 @@ type CreatePromptVersionDTO struct {
 	CreatedBy string `json:"createdBy" binding:"required"`
 	Username  string `json:"username" binding:"required"`
 	IsPublish bool   `json:"isPublish"`
+	// Override 管理员发布时跳过质量门禁
+	Override bool `json:"override"`
 }
 
 type UpdatePromptVersionDTO struct {
@@ -18,6 +20,7 @@ type UpdatePromptVersionDTO struct {
 	Variables string `json:"variables"`
 	ChangeLog string `json:"changeLog"`
 	IsPublish bool   `json:"isPublish"`
+	Override  bool   `json:"override"`
 }
 
 type ListPromptVersionDTO struct {
@@ -0,0 +1,30 @@
+package dto
+
+// CreateProviderDTO 创建上游服务
+type CreateProviderDTO struct {
+	Name string `json:"name" binding:"required"`
+	// Type 上游协议, 为空时为 openai
+	Type    string `json:"type" binding:"omitempty,oneof=openai anthropic gemini ollama"`
+	ApiBase string `json:"apiBase" binding:"required"`
+	// ApiKey 除 ollama 外必填
+	ApiKey string `json:"apiKey"`
+	// Headers 转发到该服务时附加的请求头, 如 OpenAI-Organization / OpenAI-Project
+	Headers map[string]string `json:"headers"`
+}
+
+// UpdateProviderDTO 更新上游服务, ApiKey 为空时保留原 key
+type UpdateProviderDTO struct {
+	Name    string            `json:"name" binding:"required"`
+	Type    string            `json:"type" binding:"omitempty,oneof=openai anthropic gemini ollama"`
+	ApiBase string            `json:"apiBase" binding:"required"`
+	ApiKey  string            `json:"apiKey"`
+	Headers map[string]string `json:"headers"`
+}
+
+// ProxyModelDTO 创建/更新代理模型, ProviderIDs 的顺序即轮询顺序
+type ProxyModelDTO struct {
+	Name        string   `json:"name" binding:"required"`
+	InputPrice  float64  `json:"inputPrice" binding:"min=0"`
+	OutputPrice float64  `json:"outputPrice" binding:"min=0"`
+	ProviderIDs []string `json:"providerIds"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type CreateVirtualKeyDTO struct {
+	Name          string   `json:"name" binding:"required"`
+	AllowedModels []string `json:"allowedModels"`
+	SpendLimit    float64  `json:"spendLimit" binding:"min=0"`
+	// ExpiresAt 格式 2006-01-02 15:04:05, 为空表示永不过期
+	ExpiresAt string `json:"expiresAt"`
+}
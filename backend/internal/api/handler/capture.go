@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
+	"backend/internal/api/vo"
+	"backend/internal/model"
+	captureRepo "backend/internal/repository/capture"
+	captureService "backend/internal/service/capture"
+	"backend/pkg/config"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+)
+
+type CaptureHandler struct {
+	service *captureService.Service
+	admin   *middleware.AdminMiddleware
+	// proxyURL 模型代理地址, 重放请求经代理转发以复用鉴权、计费与抓取
+	proxyURL *url.URL
+}
+
+func CreateCaptureHandler(service *captureService.Service, admin *middleware.AdminMiddleware, cfg *config.Config) *CaptureHandler {
+	return &CaptureHandler{
+		service: service,
+		admin:   admin,
+		proxyURL: &url.URL{
+			Scheme: "http",
+			Host:   fmt.Sprintf("%s:%v", cfg.Proxy.Server.Host, cfg.Proxy.Server.Port),
+		},
+	}
+}
+
+// List 普通用户只能查看自己的记录, 管理员可按 userId 过滤
+func (h *CaptureHandler) List(c *gin.Context) {
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	filter := captureRepo.Filter{
+		UserID:   userID,
+		PromptID: c.Query("promptId"),
+		Model:    c.Query("model"),
+	}
+	if h.admin.IsAdmin(username) {
+		filter.UserID, _ = strconv.ParseInt(c.Query("userId"), 10, 64)
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		limit = 10
+	}
+
+	list, total, err := h.service.List(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromCaptures(list), total, offset, limit))
+}
+
+func (h *CaptureHandler) GetByID(c *gin.Context) {
+	record, ok := h.load(c)
+	if !ok {
+		return
+	}
+	response.Success(c, vo.FromCaptureDetail(record))
+}
+
+// Replay 将抓取的请求改写为指定模型后重新发往代理, 原样返回代理的响应
+func (h *CaptureHandler) Replay(c *gin.Context) {
+	var req dto.ReplayCaptureDTO
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "invalid request body",
+		})
+		return
+	}
+
+	record, ok := h.load(c)
+	if !ok {
+		return
+	}
+	body, err := h.service.ReplayBody(record, req.Model)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	// 沿用当前用户的 JWT, 重放产生的费用计入重放者
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.Method = http.MethodPost
+			r.URL.Scheme = h.proxyURL.Scheme
+			r.URL.Host = h.proxyURL.Host
+			r.URL.Path = "/v1" + record.Path
+			r.URL.RawQuery = ""
+			r.Host = h.proxyURL.Host
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Del("Accept-Encoding")
+			r.Header.Set("X-Proxy-Replay-Of", record.ID)
+			r.Header.Set("X-Proxy-Priority", "interactive")
+			if record.PromptID != "" {
+				r.Header.Set("X-Prompt-Id", record.PromptID)
+			}
+		},
+		FlushInterval: -1,
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+// load 读取抓取记录并校验访问权限, 失败时已写出响应
+func (h *CaptureHandler) load(c *gin.Context) (*model.ProxyCapture, bool) {
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return nil, false
+	}
+
+	record, err := h.service.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return nil, false
+	}
+	// 他人的记录按不存在处理
+	if record.UserID != userID && !h.admin.IsAdmin(username) {
+		h.error(c, captureService.ErrCaptureNotFound)
+		return nil, false
+	}
+	return record, true
+}
+
+func (h *CaptureHandler) error(c *gin.Context, err error) {
+	switch err {
+	case captureService.ErrCaptureNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case captureService.ErrNotReplayable:
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
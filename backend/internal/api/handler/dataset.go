@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
+	"backend/internal/api/vo"
+	datasetService "backend/internal/service/dataset"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// maxDatasetImportBytes 导入文件大小上限
+const maxDatasetImportBytes = 32 << 20
+
+type DatasetHandler struct {
+	service *datasetService.Service
+}
+
+func CreateDatasetHandler(service *datasetService.Service) *DatasetHandler {
+	return &DatasetHandler{
+		service: service,
+	}
+}
+
+func (h *DatasetHandler) Create(c *gin.Context) {
+	var req dto.CreateDatasetDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.Create(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDataset(d))
+}
+
+func (h *DatasetHandler) GetByID(c *gin.Context) {
+	d, err := h.service.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDataset(d))
+}
+
+func (h *DatasetHandler) List(c *gin.Context) {
+	offset, limit := h.page(c)
+	list, total, err := h.service.List(c.Request.Context(), c.Query("promptId"), offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromDatasets(list), total, offset, limit))
+}
+
+func (h *DatasetHandler) Update(c *gin.Context) {
+	var req dto.UpdateDatasetDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	d, err := h.service.Update(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDataset(d))
+}
+
+func (h *DatasetHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ListRows version 为空或 0 时返回当前可编辑数据
+func (h *DatasetHandler) ListRows(c *gin.Context) {
+	version, ok := h.version(c)
+	if !ok {
+		return
+	}
+	offset, limit := h.page(c)
+	list, total, err := h.service.ListRows(c.Request.Context(), c.Param("id"), version, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromDatasetRows(list), total, offset, limit))
+}
+
+func (h *DatasetHandler) CreateRow(c *gin.Context) {
+	var req dto.CreateDatasetRowDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	row, err := h.service.CreateRow(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDatasetRow(row))
+}
+
+func (h *DatasetHandler) UpdateRow(c *gin.Context) {
+	var req dto.UpdateDatasetRowDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	row, err := h.service.UpdateRow(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDatasetRow(row))
+}
+
+func (h *DatasetHandler) DeleteRow(c *gin.Context) {
+	if err := h.service.DeleteRow(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Import 上传 multipart 字段 file, format 为空时按扩展名判断, mode=replace 时覆盖原有数据
+func (h *DatasetHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDatasetImportBytes)
+	header, err := c.FormFile("file")
+	if err != nil {
+		h.badRequest(c, "missing import file")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		h.badRequest(c, "missing import file")
+		return
+	}
+	defer file.Close()
+
+	format := datasetService.DetectFormat(c.Query("format"), header.Filename)
+	n, err := h.service.Import(c.Request.Context(), c.Param("id"), format, file, c.Query("mode") == "replace")
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, gin.H{"imported": n})
+}
+
+// Export 以附件形式下载指定版本的数据, format 为 csv(默认) 或 jsonl
+func (h *DatasetHandler) Export(c *gin.Context) {
+	version, ok := h.version(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", datasetService.FormatCSV)
+	id := c.Param("id")
+
+	data, err := h.service.Export(c.Request.Context(), id, version, format)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == datasetService.FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dataset-%s-v%d.%s"`, id, version, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func (h *DatasetHandler) CreateVersion(c *gin.Context) {
+	var req dto.CreateDatasetVersionDTO
+	// 请求体可为空
+	_ = c.ShouldBindJSON(&req)
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.CreateVersion(c.Request.Context(), c.Param("id"), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDatasetVersion(v))
+}
+
+func (h *DatasetHandler) ListVersions(c *gin.Context) {
+	list, err := h.service.ListVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromDatasetVersions(list))
+}
+
+func (h *DatasetHandler) user(c *gin.Context) (int64, string, bool) {
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+	}
+	return userID, username, ok
+}
+
+func (h *DatasetHandler) page(c *gin.Context) (int, int) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		limit = 10
+	}
+	return offset, limit
+}
+
+func (h *DatasetHandler) version(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+	if err != nil || version < 0 {
+		h.badRequest(c, "invalid version")
+		return 0, false
+	}
+	return version, true
+}
+
+func (h *DatasetHandler) badRequest(c *gin.Context, msg string) {
+	response.Error(c, http.StatusBadRequest, response.Response{
+		Code:    errors.DefaultError,
+		Data:    nil,
+		Message: msg,
+	})
+}
+
+func (h *DatasetHandler) error(c *gin.Context, err error) {
+	if _, ok := err.(*datasetService.ImportError); ok {
+		h.badRequest(c, err.Error())
+		return
+	}
+	switch err {
+	case datasetService.ErrDatasetNotFound, datasetService.ErrPromptNotFound,
+		datasetService.ErrRowNotFound, datasetService.ErrVersionNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case datasetService.ErrRowReadonly, datasetService.ErrEmptyDataset,
+		datasetService.ErrUnsupportedFormat, datasetService.ErrTooManyRows:
+		h.badRequest(c, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
+	"backend/internal/api/vo"
+	"backend/internal/model"
+	evalRepo "backend/internal/repository/eval"
+	datasetService "backend/internal/service/dataset"
+	evalService "backend/internal/service/eval"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxScoreBytes 断言评分 / 模型评审的请求体大小上限
+const maxScoreBytes = 4 << 20
+
+type EvalHandler struct {
+	service *evalService.Service
+}
+
+func CreateEvalHandler(service *evalService.Service) *EvalHandler {
+	return &EvalHandler{
+		service: service,
+	}
+}
+
+// CreateRun 支持 JSON(使用数据集) 或 multipart 表单(上传 csv / jsonl 字段 file)
+func (h *EvalHandler) CreateRun(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDatasetImportBytes)
+	var req dto.CreateEvalRunDTO
+	if err := c.ShouldBind(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	var file io.Reader
+	var format string
+	if header, err := c.FormFile("file"); err == nil {
+		f, err := header.Open()
+		if err != nil {
+			h.badRequest(c, "invalid upload file")
+			return
+		}
+		defer f.Close()
+		file = f
+		format = datasetService.DetectFormat(c.PostForm("format"), header.Filename)
+	}
+
+	run, err := h.service.CreateRun(c.Request.Context(), userID, username, req, file, format)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalRun(run))
+}
+
+// CreateComparison 与 CreateRun 一样支持 JSON 或 multipart 表单, 两个版本使用同一批输入
+func (h *EvalHandler) CreateComparison(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDatasetImportBytes)
+	var req dto.CreateEvalCompareDTO
+	if err := c.ShouldBind(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	var file io.Reader
+	var format string
+	if header, err := c.FormFile("file"); err == nil {
+		f, err := header.Open()
+		if err != nil {
+			h.badRequest(c, "invalid upload file")
+			return
+		}
+		defer f.Close()
+		file = f
+		format = datasetService.DetectFormat(c.PostForm("format"), header.Filename)
+	}
+
+	comparison, err := h.service.CreateComparison(c.Request.Context(), userID, username, req, file, format)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalComparison(comparison))
+}
+
+func (h *EvalHandler) ListComparisons(c *gin.Context) {
+	offset, limit := h.page(c)
+	list, total, err := h.service.ListComparisons(c.Request.Context(), c.Query("promptId"), offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromEvalComparisons(list), total, offset, limit))
+}
+
+// CompareReport change 可选 regression / improvement / unchanged, 行按 offset / limit 分页
+func (h *EvalHandler) CompareReport(c *gin.Context) {
+	change := c.Query("change")
+	switch change {
+	case "", evalService.ChangeRegression, evalService.ChangeImprovement, evalService.ChangeUnchanged:
+	default:
+		h.badRequest(c, "invalid change")
+		return
+	}
+	offset, limit := h.page(c)
+	report, err := h.service.CompareReport(c.Request.Context(), c.Param("id"), change, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, report)
+}
+
+func (h *EvalHandler) CancelComparison(c *gin.Context) {
+	if err := h.service.CancelComparison(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *EvalHandler) GetRun(c *gin.Context) {
+	run, err := h.service.GetRun(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalRun(run))
+}
+
+func (h *EvalHandler) ListRuns(c *gin.Context) {
+	offset, limit := h.page(c)
+	filter := evalRepo.RunFilter{
+		PromptID:  c.Query("promptId"),
+		VersionID: c.Query("versionId"),
+	}
+	list, total, err := h.service.ListRuns(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromEvalRuns(list), total, offset, limit))
+}
+
+// ListResults status 可选 pending / succeeded / failed / canceled
+func (h *EvalHandler) ListResults(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", model.EvalResultPending, model.EvalResultSucceeded, model.EvalResultFailed, model.EvalResultCanceled:
+	default:
+		h.badRequest(c, "invalid status")
+		return
+	}
+	offset, limit := h.page(c)
+	list, total, err := h.service.ListResults(c.Request.Context(), c.Param("id"), status, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromEvalResults(list), total, offset, limit))
+}
+
+func (h *EvalHandler) Cancel(c *gin.Context) {
+	if err := h.service.Cancel(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Score 对一批输出执行断言, 断言直接传入或引用保存的断言集
+func (h *EvalHandler) Score(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxScoreBytes)
+	var req dto.ScoreDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	report, err := h.service.Score(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, report)
+}
+
+// Judge 用提示词形式的评分标准, 经模型代理评审一批输出
+func (h *EvalHandler) Judge(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxScoreBytes)
+	var req dto.JudgeDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.Judge(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, report)
+}
+
+// SaveGate 创建或覆盖提示词的发布门禁
+func (h *EvalHandler) SaveGate(c *gin.Context) {
+	var req dto.SaveGateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	gate, err := h.service.SaveGate(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalGate(gate))
+}
+
+func (h *EvalHandler) GetGate(c *gin.Context) {
+	gate, err := h.service.GetGate(c.Request.Context(), c.Param("promptId"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalGate(gate))
+}
+
+func (h *EvalHandler) DeleteGate(c *gin.Context) {
+	if err := h.service.DeleteGate(c.Request.Context(), c.Param("promptId")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+// CheckGate 对已有版本试运行门禁
+func (h *EvalHandler) CheckGate(c *gin.Context) {
+	var req dto.CheckGateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.CheckGate(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, report)
+}
+
+func (h *EvalHandler) CreateAssertionSet(c *gin.Context) {
+	var req dto.CreateAssertionSetDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	set, err := h.service.CreateAssertionSet(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalAssertionSet(set))
+}
+
+func (h *EvalHandler) UpdateAssertionSet(c *gin.Context) {
+	var req dto.UpdateAssertionSetDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	set, err := h.service.UpdateAssertionSet(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalAssertionSet(set))
+}
+
+func (h *EvalHandler) GetAssertionSet(c *gin.Context) {
+	set, err := h.service.GetAssertionSet(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromEvalAssertionSet(set))
+}
+
+func (h *EvalHandler) ListAssertionSets(c *gin.Context) {
+	offset, limit := h.page(c)
+	list, total, err := h.service.ListAssertionSets(c.Request.Context(), c.Query("promptId"), offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromEvalAssertionSets(list), total, offset, limit))
+}
+
+func (h *EvalHandler) DeleteAssertionSet(c *gin.Context) {
+	if err := h.service.DeleteAssertionSet(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *EvalHandler) user(c *gin.Context) (int64, string, bool) {
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+	}
+	return userID, username, ok
+}
+
+// maxPageLimit 分页接口单次最多返回的条数
+const maxPageLimit = 100
+
+func (h *EvalHandler) page(c *gin.Context) (int, int) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	return max(offset, 0), min(limit, maxPageLimit)
+}
+
+func (h *EvalHandler) badRequest(c *gin.Context, msg string) {
+	response.Error(c, http.StatusBadRequest, response.Response{
+		Code:    errors.DefaultError,
+		Data:    nil,
+		Message: msg,
+	})
+}
+
+func (h *EvalHandler) error(c *gin.Context, err error) {
+	switch err.(type) {
+	case *datasetService.ImportError, *evalService.AssertionError:
+		h.badRequest(c, err.Error())
+		return
+	}
+	switch err {
+	case evalService.ErrRunNotFound, evalService.ErrVersionNotFound,
+		evalService.ErrPromptNotFound, evalService.ErrAssertionSetNotFound, evalService.ErrRubricNotFound,
+		evalService.ErrComparisonNotFound, evalService.ErrGateNotFound,
+		datasetService.ErrDatasetNotFound, datasetService.ErrVersionNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case evalService.ErrNoInput, evalService.ErrNoRows, evalService.ErrTooManyRows, evalService.ErrNoAssertions,
+		evalService.ErrInvalidScale, evalService.ErrRubricNotPublished, evalService.ErrVersionMismatch,
+		evalService.ErrTooManyCases, evalService.ErrInvalidPassRate,
+		evalService.ErrDatasetMismatch, datasetService.ErrUnsupportedFormat, datasetService.ErrTooManyRows:
+		h.badRequest(c, err.Error())
+	case evalService.ErrRunFinished:
+		response.Error(c, http.StatusConflict, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case evalService.ErrGateTimeout:
+		response.Error(c, http.StatusGatewayTimeout, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
+	"backend/internal/api/vo"
+	experimentService "backend/internal/service/experiment"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ExperimentHandler struct {
+	service *experimentService.Service
+}
+
+func CreateExperimentHandler(service *experimentService.Service) *ExperimentHandler {
+	return &ExperimentHandler{
+		service: service,
+	}
+}
+
+// Save 创建或覆盖提示词的 A/B 分流实验
+func (h *ExperimentHandler) Save(c *gin.Context) {
+	var req dto.SaveExperimentDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	e, err := h.service.Save(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromPromptExperiment(e))
+}
+
+func (h *ExperimentHandler) Get(c *gin.Context) {
+	e, err := h.service.Get(c.Request.Context(), c.Param("promptId"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromPromptExperiment(e))
+}
+
+func (h *ExperimentHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("promptId")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *ExperimentHandler) badRequest(c *gin.Context, msg string) {
+	response.Error(c, http.StatusBadRequest, response.Response{
+		Code:    errors.DefaultError,
+		Data:    nil,
+		Message: msg,
+	})
+}
+
+func (h *ExperimentHandler) error(c *gin.Context, err error) {
+	switch err {
+	case experimentService.ErrPromptNotFound, experimentService.ErrExperimentNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case experimentService.ErrVersionNotFound, experimentService.ErrVersionNotPublish,
+		experimentService.ErrInvalidVariants:
+		h.badRequest(c, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/vo"
+	feedbackRepo "backend/internal/repository/feedback"
+	feedbackService "backend/internal/service/feedback"
+	"backend/pkg/common"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type FeedbackHandler struct {
+	service *feedbackService.Service
+}
+
+func CreateFeedbackHandler(service *feedbackService.Service) *FeedbackHandler {
+	return &FeedbackHandler{
+		service: service,
+	}
+}
+
+// Submit 供下游应用上报评价, 与获取提示词内容接口一样不需要登录
+func (h *FeedbackHandler) Submit(c *gin.Context) {
+	var req dto.SubmitFeedbackDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "invalid request body")
+		return
+	}
+
+	f, err := h.service.Submit(c.Request.Context(), c.ClientIP(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, gin.H{"id": f.ID})
+}
+
+func (h *FeedbackHandler) List(c *gin.Context) {
+	filter, ok := h.filter(c)
+	if !ok {
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		limit = 10
+	}
+
+	list, total, err := h.service.List(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.NewPageData(vo.FromFeedbacks(list), total, offset, limit))
+}
+
+// Summary 按版本汇总提示词的反馈, promptId 必填
+func (h *FeedbackHandler) Summary(c *gin.Context) {
+	filter, ok := h.filter(c)
+	if !ok {
+		return
+	}
+	if filter.PromptID == "" {
+		h.badRequest(c, "promptId is required")
+		return
+	}
+
+	summary, err := h.service.Summary(c.Request.Context(), filter)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, summary)
+}
+
+// filter from / to 格式为 2006-01-02 15:04:05, 按服务器本地时间解析
+func (h *FeedbackHandler) filter(c *gin.Context) (feedbackRepo.Filter, bool) {
+	filter := feedbackRepo.Filter{
+		PromptID:  c.Query("promptId"),
+		VersionID: c.Query("versionId"),
+		TraceID:   c.Query("traceId"),
+		Tag:       c.Query("tag"),
+	}
+	for _, t := range []struct {
+		key string
+		dst *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := c.Query(t.key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation(common.DateTimeLayout, value, time.Local)
+		if err != nil {
+			h.badRequest(c, "invalid "+t.key)
+			return filter, false
+		}
+		*t.dst = parsed
+	}
+	return filter, true
+}
+
+func (h *FeedbackHandler) badRequest(c *gin.Context, msg string) {
+	response.Error(c, http.StatusBadRequest, response.Response{
+		Code:    errors.DefaultError,
+		Data:    nil,
+		Message: msg,
+	})
+}
+
+func (h *FeedbackHandler) error(c *gin.Context, err error) {
+	if _, ok := err.(*feedbackService.ScoreError); ok {
+		h.badRequest(c, err.Error())
+		return
+	}
+	switch err {
+	case feedbackService.ErrPromptNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case feedbackService.ErrVersionNotFound, feedbackService.ErrTooManyTags,
+		feedbackService.ErrTraceIDTooLong, feedbackService.ErrCommentTooLong:
+		h.badRequest(c, err.Error())
+	case feedbackService.ErrTooManyRequests:
+		c.Header("Retry-After", "60")
+		response.Error(c, http.StatusTooManyRequests, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
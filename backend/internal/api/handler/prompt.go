@@ -4,6 +4,7 @@ import (
 	"backend/internal/api/dto"
 	"backend/internal/api/vo"
 	"backend/internal/model"
+	experimentService "backend/internal/service/experiment"
 	promptService "backend/internal/service/prompt"
 	versionService "backend/internal/service/version"
 	"backend/pkg/errors"
@@ -18,14 +19,16 @@ import (
 )
 
 type PromptHandler struct {
-	service        *promptService.Service
-	versionService *versionService.Service
+	service           *promptService.Service
+	versionService    *versionService.Service
+	experimentService *experimentService.Service
 }
 
-func CreatePromptHandler(service *promptService.Service, versionService *versionService.Service) *PromptHandler {
+func CreatePromptHandler(service *promptService.Service, versionService *versionService.Service, experimentService *experimentService.Service) *PromptHandler {
 	return &PromptHandler{
-		service:        service,
-		versionService: versionService,
+		service:           service,
+		versionService:    versionService,
+		experimentService: experimentService,
 	}
 }
 
@@ -107,8 +110,31 @@ func (s *PromptHandler) GetPromptByPath(c *gin.Context) {
 		return
 	}
 
-	// 根据prompt表的version字段(存储的是版本ID)查询版本详情
-	version, err := s.versionService.GetByID(c.Request.Context(), p.LatestVersion)
+	// 有进行中的分流实验时按调用方提供的 subject 选择版本, 否则使用 prompt 表的 version 字段(存储的是版本ID)
+	subjectID := c.Query("subjectId")
+	if subjectID == "" {
+		subjectID = c.GetHeader("X-Subject-Id")
+	}
+	variant, err := s.experimentService.Assign(c.Request.Context(), p.ID, subjectID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+	versionID := p.LatestVersion
+	if variant != nil {
+		versionID = variant.VersionID
+	}
+
+	version, err := s.versionService.GetByID(c.Request.Context(), versionID)
+	if err == nil && version == nil && variant != nil {
+		// 分组引用的版本已被删除, 回退到已发布版本
+		variant = nil
+		version, err = s.versionService.GetByID(c.Request.Context(), p.LatestVersion)
+	}
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, response.Response{
 			Code:    errors.ServerError,
@@ -121,6 +147,7 @@ func (s *PromptHandler) GetPromptByPath(c *gin.Context) {
 	response.Success(c, gin.H{
 		"prompt":  vo.FromPrompt(p),
 		"version": vo.FromPromptVersion(version),
+		"variant": variant,
 	})
 }
 
@@ -225,6 +252,12 @@ func (s *PromptHandler) ReverseProxy(target string) gin.HandlerFunc {
 		req.URL.Host = targetURL.Host
 		req.Host = targetURL.Host
 		req.URL.Path = targetURL.Path
+		// 页面调试属于交互式请求, 在代理排队时优先处理
+		req.Header.Set("X-Proxy-Priority", "interactive")
+		// 调试请求关联到 prompt, 便于按 prompt 查看抓取记录
+		if promptID := req.URL.Query().Get("promptId"); promptID != "" {
+			req.Header.Set("X-Prompt-Id", promptID)
+		}
 	}
 
 	return func(c *gin.Context) {
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
 	"backend/internal/api/vo"
 	"backend/internal/model"
+	evalService "backend/internal/service/eval"
 	versionService "backend/internal/service/version"
 	"backend/pkg/errors"
 	"backend/pkg/response"
@@ -14,14 +16,55 @@ import (
 
 type PromptVersionHandler struct {
 	service *versionService.Service
+	admin   *middleware.AdminMiddleware
 }
 
-func CreatePromptVersionHandler(service *versionService.Service) *PromptVersionHandler {
+func CreatePromptVersionHandler(service *versionService.Service, admin *middleware.AdminMiddleware) *PromptVersionHandler {
 	return &PromptVersionHandler{
 		service: service,
+		admin:   admin,
 	}
 }
 
+// publisher 取当前用户作为发布人, 非管理员请求跳过门禁时返回 403
+func (h *PromptVersionHandler) publisher(c *gin.Context, override bool) (versionService.Publisher, bool) {
+	userID, username, _ := middleware.GetUserFromContext(c)
+	if override && !h.admin.IsAdmin(username) {
+		response.Error(c, http.StatusForbidden, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "admin permission required to override the publish gate",
+		})
+		return versionService.Publisher{}, false
+	}
+	return versionService.Publisher{UserID: userID, Username: username, Override: override}, true
+}
+
+// publishError 门禁未通过时返回 412 及门禁报告, 门禁超时返回 504
+func (h *PromptVersionHandler) publishError(c *gin.Context, err error) {
+	if gateErr, ok := err.(*versionService.GateError); ok {
+		response.Error(c, http.StatusPreconditionFailed, response.Response{
+			Code:    errors.DefaultError,
+			Data:    gateErr.Report,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err == evalService.ErrGateTimeout {
+		response.Error(c, http.StatusGatewayTimeout, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, response.Response{
+		Code:    errors.ServerError,
+		Data:    nil,
+		Message: err.Error(),
+	})
+}
+
 func (h *PromptVersionHandler) Create(c *gin.Context) {
 	var req dto.CreatePromptVersionDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -33,13 +76,14 @@ func (h *PromptVersionHandler) Create(c *gin.Context) {
 		return
 	}
 
-	v, err := h.service.Create(c.Request.Context(), req)
+	pub, ok := h.publisher(c, req.Override)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.Create(c.Request.Context(), req, pub)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, response.Response{
-			Code:    errors.ServerError,
-			Data:    nil,
-			Message: err.Error(),
-		})
+		h.publishError(c, err)
 		return
 	}
 	response.Success(c, vo.FromPromptVersion(v))
@@ -134,12 +178,13 @@ func (h *PromptVersionHandler) Update(c *gin.Context) {
 		IsPublish: req.IsPublish,
 	}
 
-	if err := h.service.Update(c.Request.Context(), v); err != nil {
-		response.Error(c, http.StatusInternalServerError, response.Response{
-			Code:    errors.ServerError,
-			Data:    nil,
-			Message: err.Error(),
-		})
+	pub, ok := h.publisher(c, req.Override)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Update(c.Request.Context(), v, pub); err != nil {
+		h.publishError(c, err)
 		return
 	}
 	response.Success(c, nil)
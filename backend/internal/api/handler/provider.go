@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/vo"
+	"backend/internal/proxy"
+	providerService "backend/internal/service/provider"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ProviderHandler struct {
+	service *providerService.Service
+	proxy   *proxy.ProxyServer
+}
+
+func CreateProviderHandler(service *providerService.Service, proxy *proxy.ProxyServer) *ProviderHandler {
+	return &ProviderHandler{
+		service: service,
+		proxy:   proxy,
+	}
+}
+
+func (h *ProviderHandler) CreateProvider(c *gin.Context) {
+	var req dto.CreateProviderDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c)
+		return
+	}
+
+	p, err := h.service.CreateProvider(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, vo.FromProvider(p))
+}
+
+func (h *ProviderHandler) ListProviders(c *gin.Context) {
+	list, err := h.service.ListProviders(c.Request.Context())
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromProviders(list))
+}
+
+func (h *ProviderHandler) UpdateProvider(c *gin.Context) {
+	var req dto.UpdateProviderDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c)
+		return
+	}
+
+	p, err := h.service.UpdateProvider(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, vo.FromProvider(p))
+}
+
+func (h *ProviderHandler) DeleteProvider(c *gin.Context) {
+	if err := h.service.DeleteProvider(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, nil)
+}
+
+func (h *ProviderHandler) CreateModel(c *gin.Context) {
+	var req dto.ProxyModelDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c)
+		return
+	}
+
+	m, err := h.service.CreateModel(c.Request.Context(), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, vo.FromProxyModel(m))
+}
+
+func (h *ProviderHandler) ListModels(c *gin.Context) {
+	list, err := h.service.ListModels(c.Request.Context())
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromProxyModels(list))
+}
+
+func (h *ProviderHandler) UpdateModel(c *gin.Context) {
+	var req dto.ProxyModelDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c)
+		return
+	}
+
+	m, err := h.service.UpdateModel(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, vo.FromProxyModel(m))
+}
+
+func (h *ProviderHandler) DeleteModel(c *gin.Context) {
+	if err := h.service.DeleteModel(c.Request.Context(), c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	h.proxy.Refresh()
+	response.Success(c, nil)
+}
+
+func (h *ProviderHandler) badRequest(c *gin.Context) {
+	response.Error(c, http.StatusBadRequest, response.Response{
+		Code:    errors.DefaultError,
+		Data:    nil,
+		Message: "invalid request body",
+	})
+}
+
+func (h *ProviderHandler) error(c *gin.Context, err error) {
+	switch err {
+	case providerService.ErrProviderNotFound, providerService.ErrModelNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case providerService.ErrProviderExists, providerService.ErrModelExists, providerService.ErrProviderInUse,
+		providerService.ErrApiKeyRequired:
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"backend/internal/proxy"
+	"backend/pkg/config"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ProxyAdminHandler struct {
+	proxy *proxy.ProxyServer
+}
+
+func CreateProxyAdminHandler(proxy *proxy.ProxyServer) *ProxyAdminHandler {
+	return &ProxyAdminHandler{
+		proxy: proxy,
+	}
+}
+
+// Reload 重新读取配置文件并热替换代理的模型/端点配置
+func (h *ProxyAdminHandler) Reload(c *gin.Context) {
+	cfg, err := config.Reload()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: "reload config failed: " + err.Error(),
+		})
+		return
+	}
+
+	models := h.proxy.Reload(cfg.Proxy)
+	response.Success(c, gin.H{
+		"models": models,
+	})
+}
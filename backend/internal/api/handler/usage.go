@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"backend/internal/api/middleware"
+	usageService "backend/internal/service/usage"
+	userService "backend/internal/service/user"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type UsageHandler struct {
+	service     *usageService.Service
+	userService *userService.Service
+}
+
+func CreateUsageHandler(service *usageService.Service, userService *userService.Service) *UsageHandler {
+	return &UsageHandler{
+		service:     service,
+		userService: userService,
+	}
+}
+
+// Summary 当前用户及其部门的模型调用花费与预算
+func (h *UsageHandler) Summary(c *gin.Context) {
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	u, err := h.userService.Get(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.service.Summary(c.Request.Context(), userID, username, u.Department)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+	response.Success(c, summary)
+}
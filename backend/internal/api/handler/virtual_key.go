@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/api/middleware"
+	"backend/internal/api/vo"
+	virtualKeyService "backend/internal/service/virtual_key"
+	"backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type VirtualKeyHandler struct {
+	service *virtualKeyService.Service
+}
+
+func CreateVirtualKeyHandler(service *virtualKeyService.Service) *VirtualKeyHandler {
+	return &VirtualKeyHandler{
+		service: service,
+	}
+}
+
+func (h *VirtualKeyHandler) Create(c *gin.Context) {
+	var req dto.CreateVirtualKeyDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "invalid request body",
+		})
+		return
+	}
+
+	userID, username, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	k, plain, err := h.service.Create(c.Request.Context(), userID, username, req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromVirtualKeyWithSecret(k, plain))
+}
+
+func (h *VirtualKeyHandler) List(c *gin.Context) {
+	userID, _, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	list, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromVirtualKeys(list))
+}
+
+func (h *VirtualKeyHandler) Rotate(c *gin.Context) {
+	userID, _, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	k, plain, err := h.service.Rotate(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, vo.FromVirtualKeyWithSecret(k, plain))
+}
+
+func (h *VirtualKeyHandler) Revoke(c *gin.Context) {
+	userID, _, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: "unauthorized",
+		})
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.error(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (h *VirtualKeyHandler) error(c *gin.Context, err error) {
+	switch err {
+	case virtualKeyService.ErrKeyNotFound:
+		response.Error(c, http.StatusNotFound, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	case virtualKeyService.ErrKeyRevoked, virtualKeyService.ErrInvalidExpiresAt:
+		response.Error(c, http.StatusBadRequest, response.Response{
+			Code:    errors.DefaultError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, response.Response{
+			Code:    errors.ServerError,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"backend/pkg/config"
+	customeErr "backend/pkg/errors"
+	"backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminMiddleware struct {
+	admins map[string]struct{}
+}
+
+func CreateAdminMiddleware(cfg *config.Config) *AdminMiddleware {
+	admins := make(map[string]struct{}, len(cfg.Security.Admins))
+	for _, name := range cfg.Security.Admins {
+		admins[strings.ToLower(name)] = struct{}{}
+	}
+	return &AdminMiddleware{admins: admins}
+}
+
+// Handler 需挂在 JWTMiddleware 之后, 只允许 security.admins 中的用户访问
+func (m *AdminMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, username, ok := GetUserFromContext(c)
+		if !ok || !m.IsAdmin(username) {
+			response.Error(c, http.StatusForbidden, response.Response{
+				Code:    customeErr.DefaultError,
+				Data:    nil,
+				Message: "admin permission required",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (m *AdminMiddleware) IsAdmin(username string) bool {
+	_, ok := m.admins[strings.ToLower(username)]
+	return ok
+}
@@ -17,6 +17,7 @@ func SetupRouter(
 	recoveryMiddleware *middleware.Recovery,
 	corsMiddleware *middleware.Cors,
 	jwtMiddleware *middleware.JWTMiddleware,
+	adminMiddleware *middleware.AdminMiddleware,
 	userHandler *handler.UserHandler,
 	promptHandler *handler.PromptHandler,
 	versionHandler *handler.PromptVersionHandler,
@@ -24,12 +25,25 @@ func SetupRouter(
 	favoriteHandler *handler.FavoriteHandler,
 	recentlyUsedHandler *handler.RecentlyUsedHandler,
 	remoteLogHandler *handler.RemoteLogHandler,
+	usageHandler *handler.UsageHandler,
+	virtualKeyHandler *handler.VirtualKeyHandler,
+	proxyAdminHandler *handler.ProxyAdminHandler,
+	providerHandler *handler.ProviderHandler,
+	captureHandler *handler.CaptureHandler,
+	datasetHandler *handler.DatasetHandler,
+	evalHandler *handler.EvalHandler,
+	experimentHandler *handler.ExperimentHandler,
+	feedbackHandler *handler.FeedbackHandler,
 ) *gin.Engine {
 	if cfg.Server.Env == "prod" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	r := gin.New()
+	// 默认不信任任何代理, ClientIP 取连接地址, 避免伪造 X-Forwarded-For 绕过按 IP 的限流
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic(fmt.Sprintf("invalid server.trustedProxies: %s", err.Error()))
+	}
 
 	// 全局中间件
 	r.Use(
@@ -53,6 +67,7 @@ func SetupRouter(
 			response.Success(c, "pong")
 		})
 		api.GET("/prompt/content/*path", promptHandler.GetPromptByPath)
+		api.POST("/feedback/submit", feedbackHandler.Submit)
 		api.POST("/remote/log/push", remoteLogHandler.Handler)
 	}
 
@@ -123,6 +138,108 @@ func SetupRouter(
 			recentlyUsedAPI.GET("/list", recentlyUsedHandler.List)
 			recentlyUsedAPI.POST("/clean", recentlyUsedHandler.Clean)
 		}
+
+		// model proxy usage api
+		usageAPI := authAPI.Group("/usage")
+		{
+			usageAPI.GET("/summary", usageHandler.Summary)
+		}
+
+		// model proxy virtual key api
+		virtualKeyAPI := authAPI.Group("/proxy-key")
+		{
+			virtualKeyAPI.POST("/create", virtualKeyHandler.Create)
+			virtualKeyAPI.GET("/list", virtualKeyHandler.List)
+			virtualKeyAPI.POST("/rotate/:id", virtualKeyHandler.Rotate)
+			virtualKeyAPI.POST("/revoke/:id", virtualKeyHandler.Revoke)
+		}
+
+		// prompt evaluation dataset api
+		datasetAPI := authAPI.Group("/dataset")
+		{
+			datasetAPI.POST("/create", datasetHandler.Create)
+			datasetAPI.GET("/info/:id", datasetHandler.GetByID)
+			datasetAPI.GET("/list", datasetHandler.List)
+			datasetAPI.POST("/update", datasetHandler.Update)
+			datasetAPI.POST("/delete/:id", datasetHandler.Delete)
+
+			datasetAPI.GET("/rows/:id", datasetHandler.ListRows)
+			datasetAPI.POST("/row/create", datasetHandler.CreateRow)
+			datasetAPI.POST("/row/update", datasetHandler.UpdateRow)
+			datasetAPI.POST("/row/delete/:id", datasetHandler.DeleteRow)
+
+			datasetAPI.POST("/import/:id", datasetHandler.Import)
+			datasetAPI.GET("/export/:id", datasetHandler.Export)
+
+			datasetAPI.POST("/version/create/:id", datasetHandler.CreateVersion)
+			datasetAPI.GET("/versions/:id", datasetHandler.ListVersions)
+		}
+
+		// prompt batch evaluation api
+		evalAPI := authAPI.Group("/eval")
+		{
+			evalAPI.POST("/run/create", evalHandler.CreateRun)
+			evalAPI.GET("/run/list", evalHandler.ListRuns)
+			evalAPI.GET("/run/info/:id", evalHandler.GetRun)
+			evalAPI.GET("/run/results/:id", evalHandler.ListResults)
+			evalAPI.POST("/run/cancel/:id", evalHandler.Cancel)
+			evalAPI.POST("/compare/create", evalHandler.CreateComparison)
+			evalAPI.GET("/compare/list", evalHandler.ListComparisons)
+			evalAPI.GET("/compare/report/:id", evalHandler.CompareReport)
+			evalAPI.POST("/compare/cancel/:id", evalHandler.CancelComparison)
+			// 修改或删除门禁等同于跳过门禁, 与 override 一样仅管理员可用
+			evalAPI.POST("/gate/save", adminMiddleware.Handler(), evalHandler.SaveGate)
+			evalAPI.GET("/gate/info/:promptId", evalHandler.GetGate)
+			evalAPI.POST("/gate/delete/:promptId", adminMiddleware.Handler(), evalHandler.DeleteGate)
+			evalAPI.POST("/gate/check", evalHandler.CheckGate)
+			evalAPI.POST("/score", evalHandler.Score)
+			evalAPI.POST("/judge", evalHandler.Judge)
+			evalAPI.POST("/assertion/create", evalHandler.CreateAssertionSet)
+			evalAPI.POST("/assertion/update", evalHandler.UpdateAssertionSet)
+			evalAPI.GET("/assertion/info/:id", evalHandler.GetAssertionSet)
+			evalAPI.GET("/assertion/list", evalHandler.ListAssertionSets)
+			evalAPI.POST("/assertion/delete/:id", evalHandler.DeleteAssertionSet)
+		}
+
+		// prompt A/B traffic splitting api
+		experimentAPI := authAPI.Group("/experiment")
+		{
+			experimentAPI.POST("/save", experimentHandler.Save)
+			experimentAPI.GET("/info/:promptId", experimentHandler.Get)
+			experimentAPI.POST("/delete/:promptId", experimentHandler.Delete)
+		}
+
+		// prompt feedback api
+		feedbackAPI := authAPI.Group("/feedback")
+		{
+			feedbackAPI.GET("/list", feedbackHandler.List)
+			feedbackAPI.GET("/summary", feedbackHandler.Summary)
+		}
+
+		// model proxy capture api
+		captureAPI := authAPI.Group("/capture")
+		{
+			captureAPI.GET("/list", captureHandler.List)
+			captureAPI.GET("/info/:id", captureHandler.GetByID)
+			captureAPI.POST("/replay/:id", captureHandler.Replay)
+		}
+
+		// model proxy admin api
+		proxyAdminAPI := authAPI.Group("/proxy")
+		proxyAdminAPI.Use(adminMiddleware.Handler())
+		{
+			proxyAdminAPI.POST("/reload", proxyAdminHandler.Reload)
+
+			proxyAdminAPI.POST("/provider/create", providerHandler.CreateProvider)
+			proxyAdminAPI.GET("/provider/list", providerHandler.ListProviders)
+			proxyAdminAPI.POST("/provider/update/:id", providerHandler.UpdateProvider)
+			proxyAdminAPI.POST("/provider/delete/:id", providerHandler.DeleteProvider)
+
+			proxyAdminAPI.POST("/model/create", providerHandler.CreateModel)
+			proxyAdminAPI.GET("/model/list", providerHandler.ListModels)
+			proxyAdminAPI.POST("/model/update/:id", providerHandler.UpdateModel)
+			proxyAdminAPI.POST("/model/delete/:id", providerHandler.DeleteModel)
+		}
 	}
 
 	return r
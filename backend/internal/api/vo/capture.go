@@ -0,0 +1,71 @@
+package vo
+
+import (
+	"backend/internal/model"
+	"backend/pkg/common"
+	"encoding/json"
+)
+
+type CaptureVO struct {
+	ID         string `json:"id"`
+	UserID     int64  `json:"userId"`
+	Username   string `json:"username"`
+	KeyID      string `json:"keyId"`
+	PromptID   string `json:"promptId"`
+	ReplayOf   string `json:"replayOf"`
+	Model      string `json:"model"`
+	Endpoint   string `json:"endpoint"`
+	Path       string `json:"path"`
+	Stream     bool   `json:"stream"`
+	StatusCode int    `json:"statusCode"`
+	LatencyMs  int64  `json:"latencyMs"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// CaptureDetailVO 抓取详情, 包含脱敏后的请求头与请求/响应体
+type CaptureDetailVO struct {
+	CaptureVO
+	RequestHeaders map[string][]string `json:"requestHeaders"`
+	RequestBody    string              `json:"requestBody"`
+	UpstreamURL    string              `json:"upstreamUrl"`
+	UpstreamBody   string              `json:"upstreamBody"`
+	ResponseBody   string              `json:"responseBody"`
+}
+
+func FromCapture(c *model.ProxyCapture) *CaptureVO {
+	return &CaptureVO{
+		ID:         c.ID,
+		UserID:     c.UserID,
+		Username:   c.Username,
+		KeyID:      c.KeyID,
+		PromptID:   c.PromptID,
+		ReplayOf:   c.ReplayOf,
+		Model:      c.Model,
+		Endpoint:   c.Endpoint,
+		Path:       c.Path,
+		Stream:     c.Stream,
+		StatusCode: c.StatusCode,
+		LatencyMs:  c.LatencyMs,
+		CreatedAt:  common.FormatTime(c.CreatedAt),
+	}
+}
+
+func FromCaptures(list []*model.ProxyCapture) []*CaptureVO {
+	res := make([]*CaptureVO, 0, len(list))
+	for _, c := range list {
+		res = append(res, FromCapture(c))
+	}
+	return res
+}
+
+func FromCaptureDetail(c *model.ProxyCapture) *CaptureDetailVO {
+	res := &CaptureDetailVO{
+		CaptureVO:    *FromCapture(c),
+		RequestBody:  c.RequestBody,
+		UpstreamURL:  c.UpstreamURL,
+		UpstreamBody: c.UpstreamBody,
+		ResponseBody: c.ResponseBody,
+	}
+	_ = json.Unmarshal([]byte(c.RequestHeaders), &res.RequestHeaders)
+	return res
+}
@@ -0,0 +1,117 @@
+package vo
+
+import (
+	"backend/internal/model"
+	"backend/pkg/common"
+)
+
+type DatasetVO struct {
+	ID          string `json:"id"`
+	PromptID    string `json:"promptId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     int    `json:"version"`
+	RowCount    int    `json:"rowCount"`
+	CreatedBy   string `json:"createdBy"`
+	Username    string `json:"username"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+func FromDataset(d *model.Dataset) *DatasetVO {
+	if d == nil {
+		return nil
+	}
+	return &DatasetVO{
+		ID:          d.ID,
+		PromptID:    d.PromptID,
+		Name:        d.Name,
+		Description: d.Description,
+		Version:     d.Version,
+		RowCount:    d.RowCount,
+		CreatedBy:   d.CreatedBy,
+		Username:    d.Username,
+		CreatedAt:   common.FormatTime(d.CreatedAt),
+		UpdatedAt:   common.FormatTime(d.UpdatedAt),
+	}
+}
+
+func FromDatasets(list []*model.Dataset) []*DatasetVO {
+	res := make([]*DatasetVO, 0, len(list))
+	for _, d := range list {
+		res = append(res, FromDataset(d))
+	}
+	return res
+}
+
+type DatasetRowVO struct {
+	ID        string            `json:"id"`
+	DatasetID string            `json:"datasetId"`
+	Version   int               `json:"version"`
+	Position  int               `json:"position"`
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+	Reference string            `json:"reference"`
+	CreatedAt string            `json:"createdAt"`
+	UpdatedAt string            `json:"updatedAt"`
+}
+
+func FromDatasetRow(r *model.DatasetRow) *DatasetRowVO {
+	if r == nil {
+		return nil
+	}
+	return &DatasetRowVO{
+		ID:        r.ID,
+		DatasetID: r.DatasetID,
+		Version:   r.Version,
+		Position:  r.Position,
+		Variables: r.VariableMap(),
+		Expected:  r.Expected,
+		Reference: r.Reference,
+		CreatedAt: common.FormatTime(r.CreatedAt),
+		UpdatedAt: common.FormatTime(r.UpdatedAt),
+	}
+}
+
+func FromDatasetRows(list []*model.DatasetRow) []*DatasetRowVO {
+	res := make([]*DatasetRowVO, 0, len(list))
+	for _, r := range list {
+		res = append(res, FromDatasetRow(r))
+	}
+	return res
+}
+
+type DatasetVersionVO struct {
+	ID        string `json:"id"`
+	DatasetID string `json:"datasetId"`
+	Version   int    `json:"version"`
+	RowCount  int    `json:"rowCount"`
+	ChangeLog string `json:"changeLog"`
+	CreatedBy string `json:"createdBy"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func FromDatasetVersion(v *model.DatasetVersion) *DatasetVersionVO {
+	if v == nil {
+		return nil
+	}
+	return &DatasetVersionVO{
+		ID:        v.ID,
+		DatasetID: v.DatasetID,
+		Version:   v.Version,
+		RowCount:  v.RowCount,
+		ChangeLog: v.ChangeLog,
+		CreatedBy: v.CreatedBy,
+		Username:  v.Username,
+		CreatedAt: common.FormatTime(v.CreatedAt),
+	}
+}
+
+func FromDatasetVersions(list []*model.DatasetVersion) []*DatasetVersionVO {
+	res := make([]*DatasetVersionVO, 0, len(list))
+	for _, v := range list {
+		res = append(res, FromDatasetVersion(v))
+	}
+	return res
+}
@@ -0,0 +1,257 @@
+package vo
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/service/eval/scorer"
+	"backend/pkg/common"
+	"encoding/json"
+)
+
+type EvalRunVO struct {
+	ID               string         `json:"id"`
+	PromptID         string         `json:"promptId"`
+	VersionID        string         `json:"versionId"`
+	DatasetID        string         `json:"datasetId"`
+	DatasetVersion   int            `json:"datasetVersion"`
+	Model            string         `json:"model"`
+	Params           map[string]any `json:"params"`
+	Concurrency      int            `json:"concurrency"`
+	Status           string         `json:"status"`
+	Total            int            `json:"total"`
+	Succeeded        int            `json:"succeeded"`
+	Failed           int            `json:"failed"`
+	Progress         float64        `json:"progress"`
+	PromptTokens     int64          `json:"promptTokens"`
+	CompletionTokens int64          `json:"completionTokens"`
+	Cost             float64        `json:"cost"`
+	Error            string         `json:"error"`
+	UserID           int64          `json:"userId"`
+	Username         string         `json:"username"`
+	StartedAt        string         `json:"startedAt"`
+	FinishedAt       string         `json:"finishedAt"`
+	CreatedAt        string         `json:"createdAt"`
+}
+
+func FromEvalRun(r *model.EvalRun) *EvalRunVO {
+	if r == nil {
+		return nil
+	}
+	params := make(map[string]any)
+	_ = json.Unmarshal([]byte(r.Params), &params)
+	// Progress 已完成行数占比, 0 ~ 1
+	var progress float64
+	if r.Total > 0 {
+		progress = float64(r.Succeeded+r.Failed) / float64(r.Total)
+	}
+	res := &EvalRunVO{
+		ID:               r.ID,
+		PromptID:         r.PromptID,
+		VersionID:        r.VersionID,
+		DatasetID:        r.DatasetID,
+		DatasetVersion:   r.DatasetVersion,
+		Model:            r.Model,
+		Params:           params,
+		Concurrency:      r.Concurrency,
+		Status:           r.Status,
+		Total:            r.Total,
+		Succeeded:        r.Succeeded,
+		Failed:           r.Failed,
+		Progress:         progress,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		Cost:             r.Cost,
+		Error:            r.Error,
+		UserID:           r.UserID,
+		Username:         r.Username,
+		CreatedAt:        common.FormatTime(r.CreatedAt),
+	}
+	if r.StartedAt != nil {
+		res.StartedAt = common.FormatTime(*r.StartedAt)
+	}
+	if r.FinishedAt != nil {
+		res.FinishedAt = common.FormatTime(*r.FinishedAt)
+	}
+	return res
+}
+
+func FromEvalRuns(list []*model.EvalRun) []*EvalRunVO {
+	res := make([]*EvalRunVO, 0, len(list))
+	for _, r := range list {
+		res = append(res, FromEvalRun(r))
+	}
+	return res
+}
+
+type EvalResultVO struct {
+	ID               string            `json:"id"`
+	RunID            string            `json:"runId"`
+	Position         int               `json:"position"`
+	Variables        map[string]string `json:"variables"`
+	Expected         string            `json:"expected"`
+	Reference        string            `json:"reference"`
+	Input            string            `json:"input"`
+	Output           string            `json:"output"`
+	Status           string            `json:"status"`
+	Error            string            `json:"error"`
+	LatencyMs        int64             `json:"latencyMs"`
+	PromptTokens     int64             `json:"promptTokens"`
+	CompletionTokens int64             `json:"completionTokens"`
+	Cost             float64           `json:"cost"`
+	UpdatedAt        string            `json:"updatedAt"`
+}
+
+func FromEvalResult(r *model.EvalResult) *EvalResultVO {
+	if r == nil {
+		return nil
+	}
+	row := model.DatasetRow{Variables: r.Variables}
+	return &EvalResultVO{
+		ID:               r.ID,
+		RunID:            r.RunID,
+		Position:         r.Position,
+		Variables:        row.VariableMap(),
+		Expected:         r.Expected,
+		Reference:        r.Reference,
+		Input:            r.Input,
+		Output:           r.Output,
+		Status:           r.Status,
+		Error:            r.Error,
+		LatencyMs:        r.LatencyMs,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		Cost:             r.Cost,
+		UpdatedAt:        common.FormatTime(r.UpdatedAt),
+	}
+}
+
+func FromEvalResults(list []*model.EvalResult) []*EvalResultVO {
+	res := make([]*EvalResultVO, 0, len(list))
+	for _, r := range list {
+		res = append(res, FromEvalResult(r))
+	}
+	return res
+}
+
+type EvalAssertionSetVO struct {
+	ID          string             `json:"id"`
+	PromptID    string             `json:"promptId"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Assertions  []scorer.Assertion `json:"assertions"`
+	CreatedBy   string             `json:"createdBy"`
+	Username    string             `json:"username"`
+	CreatedAt   string             `json:"createdAt"`
+	UpdatedAt   string             `json:"updatedAt"`
+}
+
+func FromEvalAssertionSet(s *model.EvalAssertionSet) *EvalAssertionSetVO {
+	if s == nil {
+		return nil
+	}
+	assertions := make([]scorer.Assertion, 0)
+	_ = json.Unmarshal([]byte(s.Assertions), &assertions)
+	return &EvalAssertionSetVO{
+		ID:          s.ID,
+		PromptID:    s.PromptID,
+		Name:        s.Name,
+		Description: s.Description,
+		Assertions:  assertions,
+		CreatedBy:   s.CreatedBy,
+		Username:    s.Username,
+		CreatedAt:   common.FormatTime(s.CreatedAt),
+		UpdatedAt:   common.FormatTime(s.UpdatedAt),
+	}
+}
+
+func FromEvalAssertionSets(list []*model.EvalAssertionSet) []*EvalAssertionSetVO {
+	res := make([]*EvalAssertionSetVO, 0, len(list))
+	for _, s := range list {
+		res = append(res, FromEvalAssertionSet(s))
+	}
+	return res
+}
+
+type EvalComparisonVO struct {
+	ID                  string             `json:"id"`
+	PromptID            string             `json:"promptId"`
+	BaseVersionID       string             `json:"baseVersionId"`
+	CandidateVersionID  string             `json:"candidateVersionId"`
+	BaseRunID           string             `json:"baseRunId"`
+	CandidateRunID      string             `json:"candidateRunId"`
+	Assertions          []scorer.Assertion `json:"assertions"`
+	RegressionThreshold float64            `json:"regressionThreshold"`
+	UserID              int64              `json:"userId"`
+	Username            string             `json:"username"`
+	CreatedAt           string             `json:"createdAt"`
+}
+
+func FromEvalComparison(c *model.EvalComparison) *EvalComparisonVO {
+	if c == nil {
+		return nil
+	}
+	assertions := make([]scorer.Assertion, 0)
+	_ = json.Unmarshal([]byte(c.Assertions), &assertions)
+	return &EvalComparisonVO{
+		ID:                  c.ID,
+		PromptID:            c.PromptID,
+		BaseVersionID:       c.BaseVersionID,
+		CandidateVersionID:  c.CandidateVersionID,
+		BaseRunID:           c.BaseRunID,
+		CandidateRunID:      c.CandidateRunID,
+		Assertions:          assertions,
+		RegressionThreshold: c.RegressionThreshold,
+		UserID:              c.UserID,
+		Username:            c.Username,
+		CreatedAt:           common.FormatTime(c.CreatedAt),
+	}
+}
+
+func FromEvalComparisons(list []*model.EvalComparison) []*EvalComparisonVO {
+	res := make([]*EvalComparisonVO, 0, len(list))
+	for _, c := range list {
+		res = append(res, FromEvalComparison(c))
+	}
+	return res
+}
+
+type EvalGateVO struct {
+	ID          string             `json:"id"`
+	PromptID    string             `json:"promptId"`
+	Model       string             `json:"model"`
+	Params      map[string]any     `json:"params"`
+	Cases       []dto.GateCaseDTO  `json:"cases"`
+	Assertions  []scorer.Assertion `json:"assertions"`
+	MinPassRate float64            `json:"minPassRate"`
+	Enabled     bool               `json:"enabled"`
+	CreatedBy   string             `json:"createdBy"`
+	Username    string             `json:"username"`
+	CreatedAt   string             `json:"createdAt"`
+	UpdatedAt   string             `json:"updatedAt"`
+}
+
+func FromEvalGate(g *model.EvalGate) *EvalGateVO {
+	if g == nil {
+		return nil
+	}
+	params := make(map[string]any)
+	_ = json.Unmarshal([]byte(g.Params), &params)
+	cases := make([]dto.GateCaseDTO, 0)
+	_ = json.Unmarshal([]byte(g.Cases), &cases)
+	assertions := make([]scorer.Assertion, 0)
+	_ = json.Unmarshal([]byte(g.Assertions), &assertions)
+	return &EvalGateVO{
+		ID:          g.ID,
+		PromptID:    g.PromptID,
+		Model:       g.Model,
+		Params:      params,
+		Cases:       cases,
+		Assertions:  assertions,
+		MinPassRate: g.MinPassRate,
+		Enabled:     g.Enabled,
+		CreatedBy:   g.CreatedBy,
+		Username:    g.Username,
+		CreatedAt:   common.FormatTime(g.CreatedAt),
+		UpdatedAt:   common.FormatTime(g.UpdatedAt),
+	}
+}
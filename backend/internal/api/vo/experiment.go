@@ -0,0 +1,37 @@
+package vo
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/pkg/common"
+	"encoding/json"
+)
+
+type PromptExperimentVO struct {
+	ID        string                     `json:"id"`
+	PromptID  string                     `json:"promptId"`
+	Variants  []dto.ExperimentVariantDTO `json:"variants"`
+	Enabled   bool                       `json:"enabled"`
+	CreatedBy string                     `json:"createdBy"`
+	Username  string                     `json:"username"`
+	CreatedAt string                     `json:"createdAt"`
+	UpdatedAt string                     `json:"updatedAt"`
+}
+
+func FromPromptExperiment(e *model.PromptExperiment) *PromptExperimentVO {
+	if e == nil {
+		return nil
+	}
+	variants := make([]dto.ExperimentVariantDTO, 0)
+	_ = json.Unmarshal([]byte(e.Variants), &variants)
+	return &PromptExperimentVO{
+		ID:        e.ID,
+		PromptID:  e.PromptID,
+		Variants:  variants,
+		Enabled:   e.Enabled,
+		CreatedBy: e.CreatedBy,
+		Username:  e.Username,
+		CreatedAt: common.FormatTime(e.CreatedAt),
+		UpdatedAt: common.FormatTime(e.UpdatedAt),
+	}
+}
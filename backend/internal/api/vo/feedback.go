@@ -0,0 +1,44 @@
+package vo
+
+import (
+	"backend/internal/model"
+	"backend/pkg/common"
+	"encoding/json"
+)
+
+type FeedbackVO struct {
+	ID        string   `json:"id"`
+	PromptID  string   `json:"promptId"`
+	VersionID string   `json:"versionId"`
+	TraceID   string   `json:"traceId"`
+	Score     float64  `json:"score"`
+	Tags      []string `json:"tags"`
+	Comment   string   `json:"comment"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+func FromFeedback(f *model.Feedback) *FeedbackVO {
+	if f == nil {
+		return nil
+	}
+	tags := make([]string, 0)
+	_ = json.Unmarshal([]byte(f.Tags), &tags)
+	return &FeedbackVO{
+		ID:        f.ID,
+		PromptID:  f.PromptID,
+		VersionID: f.VersionID,
+		TraceID:   f.TraceID,
+		Score:     f.Score,
+		Tags:      tags,
+		Comment:   f.Comment,
+		CreatedAt: common.FormatTime(f.CreatedAt),
+	}
+}
+
+func FromFeedbacks(list []*model.Feedback) []*FeedbackVO {
+	res := make([]*FeedbackVO, 0, len(list))
+	for _, f := range list {
+		res = append(res, FromFeedback(f))
+	}
+	return res
+}
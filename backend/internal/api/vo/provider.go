@@ -0,0 +1,77 @@
+package vo
+
+import (
+	"backend/internal/model"
+	providerService "backend/internal/service/provider"
+	"backend/pkg/common"
+)
+
+// ProviderVO 上游服务, api key 只写不读
+type ProviderVO struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	ApiBase   string            `json:"apiBase"`
+	Headers   map[string]string `json:"headers"`
+	CreatedAt string            `json:"createdAt"`
+	UpdatedAt string            `json:"updatedAt"`
+}
+
+type ProviderRefVO struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ProxyModelVO struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	InputPrice  float64          `json:"inputPrice"`
+	OutputPrice float64          `json:"outputPrice"`
+	Providers   []*ProviderRefVO `json:"providers"`
+	CreatedAt   string           `json:"createdAt"`
+	UpdatedAt   string           `json:"updatedAt"`
+}
+
+func FromProvider(p *model.ProxyProvider) *ProviderVO {
+	return &ProviderVO{
+		ID:        p.ID,
+		Name:      p.Name,
+		Type:      p.Type,
+		ApiBase:   p.ApiBase,
+		Headers:   p.ExtraHeaders(),
+		CreatedAt: common.FormatTime(p.CreatedAt),
+		UpdatedAt: common.FormatTime(p.UpdatedAt),
+	}
+}
+
+func FromProviders(list []*model.ProxyProvider) []*ProviderVO {
+	res := make([]*ProviderVO, 0, len(list))
+	for _, p := range list {
+		res = append(res, FromProvider(p))
+	}
+	return res
+}
+
+func FromProxyModel(m *providerService.ModelDetail) *ProxyModelVO {
+	providers := make([]*ProviderRefVO, 0, len(m.Providers))
+	for _, p := range m.Providers {
+		providers = append(providers, &ProviderRefVO{ID: p.ID, Name: p.Name})
+	}
+	return &ProxyModelVO{
+		ID:          m.ID,
+		Name:        m.Name,
+		InputPrice:  m.InputPrice,
+		OutputPrice: m.OutputPrice,
+		Providers:   providers,
+		CreatedAt:   common.FormatTime(m.CreatedAt),
+		UpdatedAt:   common.FormatTime(m.UpdatedAt),
+	}
+}
+
+func FromProxyModels(list []*providerService.ModelDetail) []*ProxyModelVO {
+	res := make([]*ProxyModelVO, 0, len(list))
+	for _, m := range list {
+		res = append(res, FromProxyModel(m))
+	}
+	return res
+}
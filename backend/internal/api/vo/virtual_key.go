@@ -0,0 +1,55 @@
+package vo
+
+import (
+	"backend/internal/model"
+	"backend/pkg/common"
+)
+
+type VirtualKeyVO struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Key           string   `json:"key,omitempty"`
+	KeyPrefix     string   `json:"keyPrefix"`
+	OwnerID       int64    `json:"ownerId"`
+	OwnerName     string   `json:"ownerName"`
+	AllowedModels []string `json:"allowedModels"`
+	SpendLimit    float64  `json:"spendLimit"`
+	ExpiresAt     string   `json:"expiresAt"`
+	IsRevoked     bool     `json:"isRevoked"`
+	CreatedAt     string   `json:"createdAt"`
+	UpdatedAt     string   `json:"updatedAt"`
+}
+
+func FromVirtualKey(k *model.VirtualKey) *VirtualKeyVO {
+	res := &VirtualKeyVO{
+		ID:            k.ID,
+		Name:          k.Name,
+		KeyPrefix:     k.KeyPrefix,
+		OwnerID:       k.OwnerID,
+		OwnerName:     k.OwnerName,
+		AllowedModels: k.Models(),
+		SpendLimit:    k.SpendLimit,
+		IsRevoked:     k.IsRevoked,
+		CreatedAt:     common.FormatTime(k.CreatedAt),
+		UpdatedAt:     common.FormatTime(k.UpdatedAt),
+	}
+	if k.ExpiresAt != nil {
+		res.ExpiresAt = common.FormatTime(*k.ExpiresAt)
+	}
+	return res
+}
+
+// FromVirtualKeyWithSecret 创建/轮换时附带一次性返回的明文 key
+func FromVirtualKeyWithSecret(k *model.VirtualKey, key string) *VirtualKeyVO {
+	res := FromVirtualKey(k)
+	res.Key = key
+	return res
+}
+
+func FromVirtualKeys(list []*model.VirtualKey) []*VirtualKeyVO {
+	res := make([]*VirtualKeyVO, 0, len(list))
+	for _, k := range list {
+		res = append(res, FromVirtualKey(k))
+	}
+	return res
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"backend/internal/proxy"
 	"backend/pkg/config"
 	"backend/pkg/logger"
 	"context"
@@ -21,7 +22,7 @@ type App struct {
 	db      *sqlx.DB
 	conf    *config.Config
 	logger  *zap.Logger
-	proxy   *ProxyServer
+	proxy   *proxy.ProxyServer
 	httpSrv *http.Server
 }
 
@@ -40,6 +41,7 @@ func createApp(
 	conf *config.Config,
 	logger *zap.Logger,
 	httpSrv *http.Server,
+	proxy *proxy.ProxyServer,
 ) (*App, error) {
 	if err := runMigrate(db, conf); err != nil {
 		return nil, err
@@ -48,6 +50,7 @@ func createApp(
 		db:      db,
 		conf:    conf,
 		logger:  logger,
+		proxy:   proxy,
 		httpSrv: httpSrv,
 	}, nil
 }
@@ -95,11 +98,10 @@ func Run() {
 	}
 
 	// 启动模型代理服务
-	proxy := CreateProxyServer(cfg.Proxy)
-	go proxy.Start(proxyCtx)
+	go app.proxy.Start(proxyCtx)
 
 	// 等待中断信号以优雅地关闭应用
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
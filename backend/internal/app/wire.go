@@ -7,19 +7,36 @@ import (
 	"backend/internal/api/handler"
 	"backend/internal/api/middleware"
 	"backend/internal/api/router"
+	"backend/internal/proxy"
+	captureRepo "backend/internal/repository/capture"
 	categoryRepo "backend/internal/repository/category"
+	datasetRepo "backend/internal/repository/dataset"
+	evalRepo "backend/internal/repository/eval"
+	experimentRepo "backend/internal/repository/experiment"
 	favoritesRepo "backend/internal/repository/favorites"
+	feedbackRepo "backend/internal/repository/feedback"
 	promptRepo "backend/internal/repository/prompt"
+	providerRepo "backend/internal/repository/provider"
 	recentlyUsedRepo "backend/internal/repository/recently_used"
+	usageRepo "backend/internal/repository/usage"
 	userRepo "backend/internal/repository/user"
 	versionRepo "backend/internal/repository/version"
+	virtualKeyRepo "backend/internal/repository/virtual_key"
+	captureService "backend/internal/service/capture"
 	categoryService "backend/internal/service/category"
+	datasetService "backend/internal/service/dataset"
+	evalService "backend/internal/service/eval"
+	experimentService "backend/internal/service/experiment"
 	favoritesService "backend/internal/service/favorites"
+	feedbackService "backend/internal/service/feedback"
 	promptService "backend/internal/service/prompt"
+	providerService "backend/internal/service/provider"
 	recentlyUsedService "backend/internal/service/recently_used"
 	remoteLogService "backend/internal/service/remote_log"
+	usageService "backend/internal/service/usage"
 	userService "backend/internal/service/user"
 	versionService "backend/internal/service/version"
+	virtualKeyService "backend/internal/service/virtual_key"
 	"backend/pkg/config"
 	"github.com/google/wire"
 	"go.uber.org/zap"
@@ -50,6 +67,33 @@ func wireApp(*config.Config, *lumberjack.Logger, *zap.Logger) (*App, func(), err
 			handler.CreatePromptVersionHandler,
 			remoteLogService.CreateLogService,
 			handler.CreateRemoteLogHandler,
+			usageRepo.CreateUsageRepo,
+			usageService.CreateUsageService,
+			handler.CreateUsageHandler,
+			virtualKeyRepo.CreateVirtualKeyRepo,
+			virtualKeyService.CreateVirtualKeyService,
+			handler.CreateVirtualKeyHandler,
+			providerRepo.CreateProviderRepo,
+			providerService.CreateProviderService,
+			handler.CreateProviderHandler,
+			captureRepo.CreateCaptureRepo,
+			captureService.CreateCaptureService,
+			handler.CreateCaptureHandler,
+			datasetRepo.CreateDatasetRepo,
+			datasetService.CreateDatasetService,
+			handler.CreateDatasetHandler,
+			evalRepo.CreateEvalRepo,
+			evalService.CreateEvalService,
+			handler.CreateEvalHandler,
+			experimentRepo.CreateExperimentRepo,
+			experimentService.CreateExperimentService,
+			handler.CreateExperimentHandler,
+			feedbackRepo.CreateFeedbackRepo,
+			feedbackService.CreateFeedbackService,
+			handler.CreateFeedbackHandler,
+			proxy.CreateProxyServer,
+			handler.CreateProxyAdminHandler,
+			middleware.CreateAdminMiddleware,
 			middleware.CreateRecoveryMiddleware,
 			middleware.CreateLoggerMiddleware,
 			middleware.CreateCORSMiddleware,
@@ -10,19 +10,36 @@ import (
 	"backend/internal/api/handler"
 	"backend/internal/api/middleware"
 	"backend/internal/api/router"
+	"backend/internal/proxy"
+	"backend/internal/repository/capture"
 	"backend/internal/repository/category"
+	"backend/internal/repository/dataset"
+	"backend/internal/repository/eval"
+	"backend/internal/repository/experiment"
 	"backend/internal/repository/favorites"
+	"backend/internal/repository/feedback"
 	"backend/internal/repository/prompt"
+	"backend/internal/repository/provider"
 	"backend/internal/repository/recently_used"
+	"backend/internal/repository/usage"
 	"backend/internal/repository/user"
 	"backend/internal/repository/version"
+	"backend/internal/repository/virtual_key"
+	capture2 "backend/internal/service/capture"
 	category2 "backend/internal/service/category"
+	dataset2 "backend/internal/service/dataset"
+	eval2 "backend/internal/service/eval"
+	experiment2 "backend/internal/service/experiment"
 	favorites2 "backend/internal/service/favorites"
+	feedback2 "backend/internal/service/feedback"
 	prompt2 "backend/internal/service/prompt"
+	provider2 "backend/internal/service/provider"
 	recently_used2 "backend/internal/service/recently_used"
 	"backend/internal/service/remote_log"
+	usage2 "backend/internal/service/usage"
 	user2 "backend/internal/service/user"
 	version2 "backend/internal/service/version"
+	virtual_key2 "backend/internal/service/virtual_key"
 	"backend/pkg/config"
 	"go.uber.org/zap"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -45,15 +62,22 @@ func wireApp(configConfig *config.Config, logger *lumberjack.Logger, zapLogger *
 	recovery := middleware.CreateRecoveryMiddleware(logger)
 	cors := middleware.CreateCORSMiddleware()
 	jwtMiddleware := middleware.CreateJWTMiddleware(configConfig)
+	adminMiddleware := middleware.CreateAdminMiddleware(configConfig)
 	repo := user.CreateRepo(db)
 	service := user2.CreateUserService(repo)
 	userHandler := handler.CreateUserHandler(service, configConfig)
 	promptRepo := prompt.CreatePromptRepo(db)
 	versionRepo := version.CreateVersionRepo(db)
 	promptService := prompt2.CreatePromptService(promptRepo, zapLogger, versionRepo)
-	versionService := version2.CreateVersionService(versionRepo, promptRepo, zapLogger)
-	promptHandler := handler.CreatePromptHandler(promptService, versionService)
-	promptVersionHandler := handler.CreatePromptVersionHandler(versionService)
+	evalRepo := eval.CreateEvalRepo(db)
+	datasetRepo := dataset.CreateDatasetRepo(db)
+	datasetService := dataset2.CreateDatasetService(datasetRepo, promptRepo, zapLogger)
+	evalService := eval2.CreateEvalService(evalRepo, versionRepo, promptRepo, datasetService, configConfig, zapLogger)
+	versionService := version2.CreateVersionService(versionRepo, promptRepo, evalService, zapLogger)
+	experimentRepo := experiment.CreateExperimentRepo(db)
+	experimentService := experiment2.CreateExperimentService(experimentRepo, promptRepo, versionRepo, zapLogger)
+	promptHandler := handler.CreatePromptHandler(promptService, versionService, experimentService)
+	promptVersionHandler := handler.CreatePromptVersionHandler(versionService, adminMiddleware)
 	categoryRepo := category.CreateCategoryRepo(db)
 	categoryService := category2.CreateCategoryService(categoryRepo, zapLogger)
 	categoryHandler := handler.CreateCategoryHandler(categoryService)
@@ -65,9 +89,29 @@ func wireApp(configConfig *config.Config, logger *lumberjack.Logger, zapLogger *
 	recentlyUsedHandler := handler.CreateRecentlyUsedHandler(recently_usedService)
 	logService, cleanup2 := remote_log.CreateLogService(configConfig, zapLogger)
 	remoteLogHandler := handler.CreateRemoteLogHandler(zapLogger, logService)
-	engine := router.SetupRouter(configConfig, middlewareLogger, recovery, cors, jwtMiddleware, userHandler, promptHandler, promptVersionHandler, categoryHandler, favoriteHandler, recentlyUsedHandler, remoteLogHandler)
+	usageRepo := usage.CreateUsageRepo(db)
+	usageService := usage2.CreateUsageService(usageRepo, configConfig, zapLogger)
+	usageHandler := handler.CreateUsageHandler(usageService, service)
+	virtual_keyRepo := virtual_key.CreateVirtualKeyRepo(db)
+	virtual_keyService := virtual_key2.CreateVirtualKeyService(virtual_keyRepo, usageRepo, zapLogger)
+	virtualKeyHandler := handler.CreateVirtualKeyHandler(virtual_keyService)
+	providerRepo := provider.CreateProviderRepo(db)
+	providerService := provider2.CreateProviderService(providerRepo, configConfig, zapLogger)
+	captureRepo := capture.CreateCaptureRepo(db)
+	captureService := capture2.CreateCaptureService(captureRepo, configConfig, zapLogger)
+	proxyServer := proxy.CreateProxyServer(configConfig, service, usageService, virtual_keyService, providerService, captureService)
+	proxyAdminHandler := handler.CreateProxyAdminHandler(proxyServer)
+	providerHandler := handler.CreateProviderHandler(providerService, proxyServer)
+	captureHandler := handler.CreateCaptureHandler(captureService, adminMiddleware, configConfig)
+	datasetHandler := handler.CreateDatasetHandler(datasetService)
+	evalHandler := handler.CreateEvalHandler(evalService)
+	experimentHandler := handler.CreateExperimentHandler(experimentService)
+	feedbackRepo := feedback.CreateFeedbackRepo(db)
+	feedbackService := feedback2.CreateFeedbackService(feedbackRepo, promptRepo, versionRepo, configConfig, zapLogger)
+	feedbackHandler := handler.CreateFeedbackHandler(feedbackService)
+	engine := router.SetupRouter(configConfig, middlewareLogger, recovery, cors, jwtMiddleware, adminMiddleware, userHandler, promptHandler, promptVersionHandler, categoryHandler, favoriteHandler, recentlyUsedHandler, remoteLogHandler, usageHandler, virtualKeyHandler, proxyAdminHandler, providerHandler, captureHandler, datasetHandler, evalHandler, experimentHandler, feedbackHandler)
 	server := createHttpServer(configConfig, engine)
-	app, err := createApp(db, configConfig, zapLogger, server)
+	app, err := createApp(db, configConfig, zapLogger, server, proxyServer)
 	if err != nil {
 		cleanup2()
 		cleanup()
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Dataset 对应 datasets 表（挂在提示词下的评测数据集）
+type Dataset struct {
+	ID          string `json:"id" db:"id"`
+	PromptID    string `json:"promptId" db:"prompt_id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// Version 最新快照版本号, 0 表示还没有生成快照
+	Version int `json:"version" db:"version"`
+	// RowCount 当前可编辑数据的行数
+	RowCount  int    `json:"rowCount" db:"row_count"`
+	CreatedBy string `json:"createdBy" db:"created_by"`
+	Username  string `json:"username" db:"username"`
+	BaseModel
+}
+
+func (Dataset) TableName() string {
+	return "datasets"
+}
+
+// DatasetVersion 对应 dataset_versions 表（数据集快照, 快照后的数据不可修改）
+type DatasetVersion struct {
+	ID        string    `json:"id" db:"id"`
+	DatasetID string    `json:"datasetId" db:"dataset_id"`
+	Version   int       `json:"version" db:"version"`
+	RowCount  int       `json:"rowCount" db:"row_count"`
+	ChangeLog string    `json:"changeLog" db:"change_log"`
+	CreatedBy string    `json:"createdBy" db:"created_by"`
+	Username  string    `json:"username" db:"username"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (DatasetVersion) TableName() string {
+	return "dataset_versions"
+}
+
+// DatasetDraft 数据行的 version 为 0 时表示当前可编辑数据
+const DatasetDraft = 0
+
+// DatasetRow 对应 dataset_rows 表（一条测试输入）
+type DatasetRow struct {
+	ID        string `json:"id" db:"id"`
+	DatasetID string `json:"datasetId" db:"dataset_id"`
+	Version   int    `json:"version" db:"version"`
+	Position  int    `json:"position" db:"position"`
+	// Variables 变量取值, JSON 对象
+	Variables string `json:"variables" db:"variables"`
+	// Expected 期望输出, Reference 参考资料, 均可为空
+	Expected  string `json:"expected" db:"expected"`
+	Reference string `json:"reference" db:"reference"`
+	BaseModel
+}
+
+func (DatasetRow) TableName() string {
+	return "dataset_rows"
+}
+
+// VariableMap 解析变量取值, 格式错误时返回空 map
+func (r *DatasetRow) VariableMap() map[string]string {
+	vars := make(map[string]string)
+	if r.Variables == "" {
+		return vars
+	}
+	_ = json.Unmarshal([]byte(r.Variables), &vars)
+	return vars
+}
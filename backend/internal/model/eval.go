@@ -0,0 +1,142 @@
+package model
+
+import "time"
+
+// 评测任务状态
+const (
+	EvalRunPending   = "pending"
+	EvalRunRunning   = "running"
+	EvalRunCompleted = "completed"
+	EvalRunFailed    = "failed"
+	EvalRunCanceled  = "canceled"
+)
+
+// 评测结果状态
+const (
+	EvalResultPending   = "pending"
+	EvalResultSucceeded = "succeeded"
+	EvalResultFailed    = "failed"
+	EvalResultCanceled  = "canceled"
+)
+
+// EvalRun 对应 eval_runs 表（用提示词版本批量跑一组变量）
+type EvalRun struct {
+	ID        string `json:"id" db:"id"`
+	PromptID  string `json:"promptId" db:"prompt_id"`
+	VersionID string `json:"versionId" db:"version_id"`
+	// DatasetID 为空表示使用创建任务时上传的文件
+	DatasetID      string `json:"datasetId" db:"dataset_id"`
+	DatasetVersion int    `json:"datasetVersion" db:"dataset_version"`
+	Model          string `json:"model" db:"model"`
+	// Params 模型参数(temperature / max_tokens), JSON 对象
+	Params      string `json:"params" db:"params"`
+	Concurrency int    `json:"concurrency" db:"concurrency"`
+	Status      string `json:"status" db:"status"`
+	// Total 总行数, Succeeded / Failed 已完成的行数
+	Total            int        `json:"total" db:"total"`
+	Succeeded        int        `json:"succeeded" db:"succeeded"`
+	Failed           int        `json:"failed" db:"failed"`
+	PromptTokens     int64      `json:"promptTokens" db:"prompt_tokens"`
+	CompletionTokens int64      `json:"completionTokens" db:"completion_tokens"`
+	Cost             float64    `json:"cost" db:"cost"`
+	Error            string     `json:"error" db:"error"`
+	UserID           int64      `json:"userId" db:"user_id"`
+	Username         string     `json:"username" db:"username"`
+	StartedAt        *time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt       *time.Time `json:"finishedAt" db:"finished_at"`
+	BaseModel
+}
+
+func (EvalRun) TableName() string {
+	return "eval_runs"
+}
+
+// Finished 任务已结束, 不会再有结果更新
+func (r *EvalRun) Finished() bool {
+	return r.Status == EvalRunCompleted || r.Status == EvalRunFailed || r.Status == EvalRunCanceled
+}
+
+// EvalResult 对应 eval_results 表（评测任务中一行的输入与输出）
+type EvalResult struct {
+	ID        string `json:"id" db:"id"`
+	RunID     string `json:"runId" db:"run_id"`
+	Position  int    `json:"position" db:"position"`
+	Variables string `json:"variables" db:"variables"`
+	Expected  string `json:"expected" db:"expected"`
+	Reference string `json:"reference" db:"reference"`
+	// Input 渲染变量后的提示词
+	Input            string  `json:"input" db:"input"`
+	Output           string  `json:"output" db:"output"`
+	Status           string  `json:"status" db:"status"`
+	Error            string  `json:"error" db:"error"`
+	LatencyMs        int64   `json:"latencyMs" db:"latency_ms"`
+	PromptTokens     int64   `json:"promptTokens" db:"prompt_tokens"`
+	CompletionTokens int64   `json:"completionTokens" db:"completion_tokens"`
+	Cost             float64 `json:"cost" db:"cost"`
+	BaseModel
+}
+
+func (EvalResult) TableName() string {
+	return "eval_results"
+}
+
+// EvalAssertionSet 对应 eval_assertion_sets 表（挂在提示词下、可复用的一组评分断言）
+type EvalAssertionSet struct {
+	ID          string `json:"id" db:"id"`
+	PromptID    string `json:"promptId" db:"prompt_id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// Assertions 断言列表, JSON 数组
+	Assertions string `json:"assertions" db:"assertions"`
+	CreatedBy  string `json:"createdBy" db:"created_by"`
+	Username   string `json:"username" db:"username"`
+	BaseModel
+}
+
+func (EvalAssertionSet) TableName() string {
+	return "eval_assertion_sets"
+}
+
+// EvalComparison 对应 eval_comparisons 表（两个提示词版本在相同输入上的成对评测任务）
+type EvalComparison struct {
+	ID                 string `json:"id" db:"id"`
+	PromptID           string `json:"promptId" db:"prompt_id"`
+	BaseVersionID      string `json:"baseVersionId" db:"base_version_id"`
+	CandidateVersionID string `json:"candidateVersionId" db:"candidate_version_id"`
+	BaseRunID          string `json:"baseRunId" db:"base_run_id"`
+	CandidateRunID     string `json:"candidateRunId" db:"candidate_run_id"`
+	// Assertions 用于逐行评分的断言, JSON 数组, 为空数组时只比较执行结果
+	Assertions string `json:"assertions" db:"assertions"`
+	// RegressionThreshold 候选版本得分下降超过该值时记为退化
+	RegressionThreshold float64 `json:"regressionThreshold" db:"regression_threshold"`
+	UserID              int64   `json:"userId" db:"user_id"`
+	Username            string  `json:"username" db:"username"`
+	BaseModel
+}
+
+func (EvalComparison) TableName() string {
+	return "eval_comparisons"
+}
+
+// EvalGate 对应 eval_gates 表（提示词的发布质量门禁, 每个提示词最多一个）
+type EvalGate struct {
+	ID       string `json:"id" db:"id"`
+	PromptID string `json:"promptId" db:"prompt_id"`
+	Model    string `json:"model" db:"model"`
+	// Params 模型参数(temperature / max_tokens), JSON 对象
+	Params string `json:"params" db:"params"`
+	// Cases 测试用例, JSON 数组, 每项为 {"variables": {...}, "expected": "..."}
+	Cases string `json:"cases" db:"cases"`
+	// Assertions 判断用例是否通过的断言, JSON 数组
+	Assertions string `json:"assertions" db:"assertions"`
+	// MinPassRate 最低通过率, 0 ~ 1
+	MinPassRate float64 `json:"minPassRate" db:"min_pass_rate"`
+	Enabled     bool    `json:"enabled" db:"enabled"`
+	CreatedBy   string  `json:"createdBy" db:"created_by"`
+	Username    string  `json:"username" db:"username"`
+	BaseModel
+}
+
+func (EvalGate) TableName() string {
+	return "eval_gates"
+}
@@ -0,0 +1,17 @@
+package model
+
+// PromptExperiment 对应 prompt_experiments 表（提示词的 A/B 分流实验, 每个提示词最多一个）
+type PromptExperiment struct {
+	ID       string `json:"id" db:"id"`
+	PromptID string `json:"promptId" db:"prompt_id"`
+	// Variants 分流配置, JSON 数组, 每项为 {"name": "...", "versionId": "...", "weight": 90}
+	Variants  string `json:"variants" db:"variants"`
+	Enabled   bool   `json:"enabled" db:"enabled"`
+	CreatedBy string `json:"createdBy" db:"created_by"`
+	Username  string `json:"username" db:"username"`
+	BaseModel
+}
+
+func (PromptExperiment) TableName() string {
+	return "prompt_experiments"
+}
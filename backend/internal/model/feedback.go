@@ -0,0 +1,38 @@
+package model
+
+// Feedback 对应 prompt_feedback 表（下游应用对提示词输出的评价）
+type Feedback struct {
+	ID        string `json:"id" db:"id"`
+	PromptID  string `json:"promptId" db:"prompt_id"`
+	VersionID string `json:"versionId" db:"version_id"`
+	// TraceID 下游的 trace / request ID, 用于关联具体一次调用
+	TraceID string  `json:"traceId" db:"trace_id"`
+	Score   float64 `json:"score" db:"score"`
+	// Tags 标签, JSON 数组; 另存一份到 prompt_feedback_tags 用于按版本统计
+	Tags    string `json:"tags" db:"tags"`
+	Comment string `json:"comment" db:"comment"`
+	BaseModel
+}
+
+func (Feedback) TableName() string {
+	return "prompt_feedback"
+}
+
+// FeedbackStats 一个版本的反馈汇总, 由 prompt_feedback 按 version_id 聚合得到
+type FeedbackStats struct {
+	VersionID string  `json:"versionId" db:"version_id"`
+	Count     int64   `json:"count" db:"count"`
+	AvgScore  float64 `json:"avgScore" db:"avg_score"`
+	MinScore  float64 `json:"minScore" db:"min_score"`
+	MaxScore  float64 `json:"maxScore" db:"max_score"`
+	// Positive / Negative 分数大于 0 / 小于 0 的条数, 点赞点踩按 1 / -1 上报时即赞踩数
+	Positive int64 `json:"positive" db:"positive"`
+	Negative int64 `json:"negative" db:"negative"`
+}
+
+// FeedbackTagCount 一个版本下某个标签出现的次数
+type FeedbackTagCount struct {
+	VersionID string `db:"version_id"`
+	Tag       string `db:"tag"`
+	Count     int64  `db:"count"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ProxyCapture 对应 proxy_captures 表（代理请求/响应抓取记录, 已脱敏）
+type ProxyCapture struct {
+	ID             string    `json:"id" db:"id"`
+	UserID         int64     `json:"userId" db:"user_id"`
+	Username       string    `json:"username" db:"username"`
+	KeyID          string    `json:"keyId" db:"key_id"`
+	PromptID       string    `json:"promptId" db:"prompt_id"`
+	ReplayOf       string    `json:"replayOf" db:"replay_of"`
+	Model          string    `json:"model" db:"model"`
+	Endpoint       string    `json:"endpoint" db:"endpoint"`
+	Path           string    `json:"path" db:"path"`
+	Stream         bool      `json:"stream" db:"stream"`
+	StatusCode     int       `json:"statusCode" db:"status_code"`
+	LatencyMs      int64     `json:"latencyMs" db:"latency_ms"`
+	RequestHeaders string    `json:"requestHeaders" db:"request_headers"` // JSON
+	RequestBody    string    `json:"requestBody" db:"request_body"`
+	UpstreamURL    string    `json:"upstreamUrl" db:"upstream_url"`
+	UpstreamBody   string    `json:"upstreamBody" db:"upstream_body"`
+	ResponseBody   string    `json:"responseBody" db:"response_body"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (ProxyCapture) TableName() string {
+	return "proxy_captures"
+}
@@ -0,0 +1,54 @@
+package model
+
+import "encoding/json"
+
+// ProxyProvider 对应 proxy_providers 表（上游服务, api key 加密存储）
+type ProxyProvider struct {
+	ID      string `json:"id" db:"id"`
+	Name    string `json:"name" db:"name"`
+	Type    string `json:"type" db:"type"` // openai | anthropic | gemini | ollama
+	ApiBase string `json:"apiBase" db:"api_base"`
+	ApiKey  string `json:"-" db:"api_key"`
+	// Headers 转发时附加的请求头(如 OpenAI-Organization), JSON 对象
+	Headers string `json:"headers" db:"headers"`
+	BaseModel
+}
+
+// ExtraHeaders 解析附加请求头, 格式错误时返回 nil
+func (p *ProxyProvider) ExtraHeaders() map[string]string {
+	if p.Headers == "" {
+		return nil
+	}
+	var h map[string]string
+	if err := json.Unmarshal([]byte(p.Headers), &h); err != nil {
+		return nil
+	}
+	return h
+}
+
+func (ProxyProvider) TableName() string {
+	return "proxy_providers"
+}
+
+// ProxyModel 对应 proxy_models 表（对外暴露的模型及单价）
+type ProxyModel struct {
+	ID          string  `json:"id" db:"id"`
+	Name        string  `json:"name" db:"name"`
+	InputPrice  float64 `json:"inputPrice" db:"input_price"`
+	OutputPrice float64 `json:"outputPrice" db:"output_price"`
+	BaseModel
+}
+
+func (ProxyModel) TableName() string {
+	return "proxy_models"
+}
+
+// ProxyModelEndpoint 对应 proxy_model_endpoints 表（模型 -> 上游服务映射）
+type ProxyModelEndpoint struct {
+	ModelID    string `json:"modelId" db:"model_id"`
+	ProviderID string `json:"providerId" db:"provider_id"`
+}
+
+func (ProxyModelEndpoint) TableName() string {
+	return "proxy_model_endpoints"
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// ProxyUsage 对应 proxy_usage 表（模型代理调用用量及费用）
+type ProxyUsage struct {
+	ID               string    `json:"id" db:"id"`
+	UserID           int64     `json:"userId" db:"user_id"`
+	Username         string    `json:"username" db:"username"`
+	Department       string    `json:"department" db:"department"`
+	KeyID            string    `json:"keyId" db:"key_id"`
+	Model            string    `json:"model" db:"model"`
+	Endpoint         string    `json:"endpoint" db:"endpoint"`
+	Stream           bool      `json:"stream" db:"stream"`
+	StatusCode       int       `json:"statusCode" db:"status_code"`
+	PromptTokens     int64     `json:"promptTokens" db:"prompt_tokens"`
+	CompletionTokens int64     `json:"completionTokens" db:"completion_tokens"`
+	Cost             float64   `json:"cost" db:"cost"`
+	CreatedAt        time.Time `json:"createdAt" db:"created_at"`
+}
+
+func (ProxyUsage) TableName() string {
+	return "proxy_usage"
+}
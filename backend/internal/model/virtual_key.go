@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// VirtualKey 对应 virtual_keys 表（代理虚拟 API key, 只保存哈希）
+type VirtualKey struct {
+	ID            string     `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"`
+	KeyPrefix     string     `json:"keyPrefix" db:"key_prefix"`
+	KeyHash       string     `json:"-" db:"key_hash"`
+	OwnerID       int64      `json:"ownerId" db:"owner_id"`
+	OwnerName     string     `json:"ownerName" db:"owner_name"`
+	AllowedModels string     `json:"allowedModels" db:"allowed_models"` // 逗号分隔, 为空不限制
+	SpendLimit    float64    `json:"spendLimit" db:"spend_limit"`       // 0 不限制
+	ExpiresAt     *time.Time `json:"expiresAt" db:"expires_at"`
+	IsRevoked     bool       `json:"isRevoked" db:"is_revoked"`
+	BaseModel
+}
+
+func (VirtualKey) TableName() string {
+	return "virtual_keys"
+}
+
+func (k *VirtualKey) Models() []string {
+	if strings.TrimSpace(k.AllowedModels) == "" {
+		return nil
+	}
+	res := make([]string, 0)
+	for _, m := range strings.Split(k.AllowedModels, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+func (k *VirtualKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 上游服务协议类型, 对应 endpoint 的 type 字段
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+	providerGemini    = "gemini"
+	providerOllama    = "ollama"
+)
+
+var (
+	errUnsupportedPath = errors.New("path not supported by this model's provider")
+	errInvalidRequest  = errors.New("invalid chat completions request")
+)
+
+// adapter 上游协议适配器, 对调用方统一暴露 OpenAI chat-completions 格式
+type adapter interface {
+	// newRequest 根据 OpenAI 格式的请求体构造上游请求
+	newRequest(ctx context.Context, c *gin.Context, client *APIClient, path string, body []byte, stream bool) (*http.Request, error)
+	// convertResponse 将上游非 stream 响应体(包括错误响应)转换为 OpenAI 格式, 无法识别时原样返回
+	convertResponse(body []byte, model string) []byte
+	// streamConverter 返回一个 writer, 写入上游 stream 响应, 转换为 OpenAI SSE 后写入 w
+	streamConverter(w io.Writer, model string) io.Writer
+}
+
+func adapterFor(kind string) (adapter, bool) {
+	switch kind {
+	case providerOpenAI:
+		return openAIAdapter{}, true
+	case providerAnthropic:
+		return anthropicAdapter{}, true
+	case providerGemini:
+		return geminiAdapter{}, true
+	case providerOllama:
+		return ollamaAdapter{}, true
+	}
+	return nil, false
+}
+
+// openAIAdapter OpenAI 兼容上游, 请求与响应原样透传
+type openAIAdapter struct{}
+
+func (openAIAdapter) newRequest(ctx context.Context, c *gin.Context, client *APIClient, path string, body []byte, stream bool) (*http.Request, error) {
+	// stream 请求要求上游在最后一个 chunk 返回 usage, 用于计费
+	if stream {
+		body = withStreamUsage(body)
+	}
+
+	url := client.apiBase + path
+	if c.Request.URL.RawQuery != "" {
+		url += "?" + c.Request.URL.RawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// 调用方的其余请求头由 headerPolicy 按规则转发
+	req.Header.Set("Authorization", "Bearer "+client.apiKey)
+	return req, nil
+}
+
+func (openAIAdapter) convertResponse(body []byte, _ string) []byte {
+	return body
+}
+
+func (openAIAdapter) streamConverter(w io.Writer, _ string) io.Writer {
+	return w
+}
+
+// chatRequest OpenAI chat-completions 请求, 只包含需要翻译的字段
+type chatRequest struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	Stream              bool            `json:"stream"`
+	MaxTokens           int64           `json:"max_tokens"`
+	MaxCompletionTokens int64           `json:"max_completion_tokens"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	Stop                json.RawMessage `json:"stop"`
+	Tools               []chatTool      `json:"tools"`
+	ToolChoice          json.RawMessage `json:"tool_choice"`
+	User                string          `json:"user"`
+}
+
+func (r *chatRequest) maxTokens() int64 {
+	return max(r.MaxTokens, r.MaxCompletionTokens)
+}
+
+// stopSequences stop 可以是字符串或字符串数组
+func (r *chatRequest) stopSequences() []string {
+	if len(r.Stop) == 0 {
+		return nil
+	}
+	var one string
+	if err := json.Unmarshal(r.Stop, &one); err == nil {
+		if one == "" {
+			return nil
+		}
+		return []string{one}
+	}
+	var list []string
+	_ = json.Unmarshal(r.Stop, &list)
+	return list
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCalls  []chatToolCall  `json:"tool_calls"`
+	ToolCallID string          `json:"tool_call_id"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+// parts content 可以是字符串、content part 数组或 null, 统一转为 part 数组
+func (m *chatMessage) parts() ([]chatContentPart, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []chatContentPart{{Type: "text", Text: text}}, nil
+	}
+	var parts []chatContentPart
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return nil, fmt.Errorf("%w: invalid content of %s message", errInvalidRequest, m.Role)
+	}
+	return parts, nil
+}
+
+// text 拼接 content 中的全部文本
+func (m *chatMessage) text() (string, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	for _, p := range parts {
+		if p.Type == "text" {
+			buf.WriteString(p.Text)
+		}
+	}
+	return buf.String(), nil
+}
+
+// parseToolChoice tool_choice: "auto" | "none" | "required" | {"type":"function","function":{"name":...}},
+// 返回的 mode 为 auto/none/required/function, 未设置或无法识别时为空
+func parseToolChoice(raw json.RawMessage) (mode, name string) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", ""
+	}
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "auto", "none", "required":
+			return mode, ""
+		}
+		return "", ""
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err == nil && named.Function.Name != "" {
+		return "function", named.Function.Name
+	}
+	return "", ""
+}
+
+// parseDataURL 解析 data:<mime>;base64,<data>
+func parseDataURL(u string) (mimeType, data string, ok bool) {
+	rest, found := strings.CutPrefix(u, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type chatToolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function chatFunctionCall `json:"function"`
+}
+
+type chatFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// chatCompletion OpenAI chat-completions 响应及 stream chunk
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *tokenUsage  `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int             `json:"index"`
+	Message      *chatOutMessage `json:"message,omitempty"`
+	Delta        *chatOutMessage `json:"delta,omitempty"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+type chatOutMessage struct {
+	Role      string         `json:"role,omitempty"`
+	Content   *string        `json:"content,omitempty"`
+	ToolCalls []chatToolCall `json:"tool_calls,omitempty"`
+}
+
+// newCompletion 上游未返回 id 时生成一个, 与 OpenAI 的 chatcmpl- 前缀保持一致
+func newCompletion(id, model string, object string) *chatCompletion {
+	if id == "" {
+		id = "chatcmpl-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	return &chatCompletion{
+		ID:      id,
+		Object:  object,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: make([]chatChoice, 0, 1),
+	}
+}
+
+// chatError OpenAI 格式的错误响应
+func chatError(message, errType string) []byte {
+	b, _ := json.Marshal(gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+			"code":    nil,
+		},
+	})
+	return b
+}
@@ -0,0 +1,462 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicVersion = "2023-06-01"
+	// Anthropic 要求必须指定 max_tokens, 请求未指定时使用该值
+	anthropicDefaultMaxTokens = 4096
+)
+
+// anthropicAdapter Anthropic Messages API (/v1/messages)
+type anthropicAdapter struct{}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int64                `json:"max_tokens"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *anthropicMetadata   `json:"metadata,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type string `json:"type"`
+	// text
+	Text string `json:"text,omitempty"`
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// promptTokens 缓存命中与写入的 token 同样计入输入
+func (u *anthropicUsage) promptTokens() int64 {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (anthropicAdapter) newRequest(ctx context.Context, c *gin.Context, client *APIClient, path string, body []byte, stream bool) (*http.Request, error) {
+	if path != "/chat/completions" {
+		return nil, errUnsupportedPath
+	}
+	var in chatRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidRequest, err.Error())
+	}
+	out, err := toAnthropicRequest(&in)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.apiBase+"/messages", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", client.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+func toAnthropicRequest(in *chatRequest) (*anthropicRequest, error) {
+	out := &anthropicRequest{
+		Model:         in.Model,
+		MaxTokens:     in.maxTokens(),
+		Temperature:   in.Temperature,
+		TopP:          in.TopP,
+		StopSequences: in.stopSequences(),
+		Stream:        in.Stream,
+	}
+	if out.MaxTokens <= 0 {
+		out.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if in.User != "" {
+		out.Metadata = &anthropicMetadata{UserID: in.User}
+	}
+
+	var system []string
+	for i := range in.Messages {
+		m := &in.Messages[i]
+		switch m.Role {
+		case "system", "developer":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			system = append(system, text)
+		case "user":
+			blocks, err := anthropicContent(m)
+			if err != nil {
+				return nil, err
+			}
+			out.appendMessage("user", blocks...)
+		case "assistant":
+			blocks, err := anthropicContent(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			out.appendMessage("assistant", blocks...)
+		case "tool":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			// tool 结果以 user 消息的 tool_result 块返回给 Anthropic
+			out.appendMessage("user", anthropicBlock{
+				Type:      "tool_result",
+				ToolUseID: m.ToolCallID,
+				Content:   text,
+			})
+		default:
+			return nil, fmt.Errorf("%w: unsupported role %q", errInvalidRequest, m.Role)
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, t := range in.Tools {
+		schema := t.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	out.ToolChoice = anthropicChoice(in.ToolChoice)
+	if out.ToolChoice != nil && out.ToolChoice.Type == "none" {
+		out.Tools = nil
+		out.ToolChoice = nil
+	}
+	return out, nil
+}
+
+// appendMessage Anthropic 要求 user/assistant 交替出现, 相邻同角色消息合并
+func (r *anthropicRequest) appendMessage(role string, blocks ...anthropicBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == role {
+		r.Messages[n-1].Content = append(r.Messages[n-1].Content, blocks...)
+		return
+	}
+	r.Messages = append(r.Messages, anthropicMessage{Role: role, Content: blocks})
+}
+
+func anthropicContent(m *chatMessage) ([]anthropicBlock, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]anthropicBlock, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: p.Text})
+			}
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: anthropicImage(p.ImageURL.URL)})
+		}
+	}
+	return blocks, nil
+}
+
+// anthropicImage data URL 转为 base64 图片, 其他按 url 图片处理
+func anthropicImage(url string) *anthropicImageSource {
+	if mediaType, data, ok := parseDataURL(url); ok {
+		return &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+	}
+	return &anthropicImageSource{Type: "url", URL: url}
+}
+
+func anthropicChoice(raw json.RawMessage) *anthropicToolChoice {
+	switch mode, name := parseToolChoice(raw); mode {
+	case "auto", "none":
+		return &anthropicToolChoice{Type: mode}
+	case "required":
+		return &anthropicToolChoice{Type: "any"}
+	case "function":
+		return &anthropicToolChoice{Type: "tool", Name: name}
+	}
+	return nil
+}
+
+func (anthropicAdapter) convertResponse(body []byte, model string) []byte {
+	var in anthropicResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return body
+	}
+	if in.Type == "error" && in.Error != nil {
+		return chatError(in.Error.Message, in.Error.Type)
+	}
+	if in.Type != "message" {
+		return body
+	}
+
+	msg := &chatOutMessage{Role: "assistant"}
+	var text strings.Builder
+	for _, b := range in.Content {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, chatToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: chatFunctionCall{Name: b.Name, Arguments: string(b.Input)},
+			})
+		}
+	}
+	if text.Len() > 0 || len(msg.ToolCalls) == 0 {
+		content := text.String()
+		msg.Content = &content
+	}
+
+	finish := anthropicFinishReason(in.StopReason)
+	out := newCompletion(in.ID, model, "chat.completion")
+	out.Choices = append(out.Choices, chatChoice{Message: msg, FinishReason: &finish})
+	out.Usage = &tokenUsage{
+		PromptTokens:     in.Usage.promptTokens(),
+		CompletionTokens: in.Usage.OutputTokens,
+		TotalTokens:      in.Usage.promptTokens() + in.Usage.OutputTokens,
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	}
+	return "stop"
+}
+
+func (anthropicAdapter) streamConverter(w io.Writer, model string) io.Writer {
+	return &anthropicStream{
+		w:         w,
+		model:     model,
+		toolIndex: make(map[int]int),
+	}
+}
+
+// anthropicStream 将 Anthropic SSE 事件转换为 OpenAI chat.completion.chunk
+type anthropicStream struct {
+	w       io.Writer
+	model   string
+	decoder sseDecoder
+	chunk   *chatCompletion
+	usage   anthropicUsage
+	// toolIndex content block 下标 -> tool_calls 下标
+	toolIndex map[int]int
+}
+
+func (s *anthropicStream) Write(p []byte) (int, error) {
+	for _, ev := range s.decoder.feed(p) {
+		if err := s.handle(ev); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *anthropicStream) handle(ev sseEvent) error {
+	var data struct {
+		Type    string            `json:"type"`
+		Message anthropicResponse `json:"message"`
+		Index   int               `json:"index"`
+		Block   anthropicBlock    `json:"content_block"`
+		Delta   struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		Usage *anthropicUsage `json:"usage"`
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(ev.data, &data); err != nil {
+		return nil
+	}
+
+	switch data.Type {
+	case "message_start":
+		s.chunk = newCompletion(data.Message.ID, s.model, "chat.completion.chunk")
+		s.usage = data.Message.Usage
+		empty := ""
+		return s.emit(&chatOutMessage{Role: "assistant", Content: &empty}, nil)
+	case "content_block_start":
+		if data.Block.Type != "tool_use" {
+			return nil
+		}
+		idx := len(s.toolIndex)
+		s.toolIndex[data.Index] = idx
+		return s.emit(&chatOutMessage{ToolCalls: []chatToolCall{{
+			Index:    &idx,
+			ID:       data.Block.ID,
+			Type:     "function",
+			Function: chatFunctionCall{Name: data.Block.Name},
+		}}}, nil)
+	case "content_block_delta":
+		switch data.Delta.Type {
+		case "text_delta":
+			text := data.Delta.Text
+			return s.emit(&chatOutMessage{Content: &text}, nil)
+		case "input_json_delta":
+			idx, ok := s.toolIndex[data.Index]
+			if !ok {
+				return nil
+			}
+			return s.emit(&chatOutMessage{ToolCalls: []chatToolCall{{
+				Index:    &idx,
+				Function: chatFunctionCall{Arguments: data.Delta.PartialJSON},
+			}}}, nil)
+		}
+	case "message_delta":
+		if data.Usage != nil {
+			s.usage.OutputTokens = data.Usage.OutputTokens
+		}
+		if data.Delta.StopReason == "" {
+			return nil
+		}
+		finish := anthropicFinishReason(data.Delta.StopReason)
+		return s.emit(&chatOutMessage{}, &finish)
+	case "message_stop":
+		// 与 stream_options.include_usage 一致, 最后一个 chunk 只携带 usage
+		usage := s.base()
+		usage.Usage = &tokenUsage{
+			PromptTokens:     s.usage.promptTokens(),
+			CompletionTokens: s.usage.OutputTokens,
+			TotalTokens:      s.usage.promptTokens() + s.usage.OutputTokens,
+		}
+		if err := writeSSEData(s.w, usage); err != nil {
+			return err
+		}
+		return writeSSEDone(s.w)
+	case "error":
+		if data.Error == nil {
+			return nil
+		}
+		// 上游错误后 stream 即结束, 补上 [DONE], 避免再被当作意外中断补发一次错误
+		if _, err := fmt.Fprintf(s.w, "data: %s\n\n", chatError(data.Error.Message, data.Error.Type)); err != nil {
+			return err
+		}
+		return writeSSEDone(s.w)
+	}
+	return nil
+}
+
+func (s *anthropicStream) base() chatCompletion {
+	if s.chunk == nil {
+		s.chunk = newCompletion("", s.model, "chat.completion.chunk")
+	}
+	c := *s.chunk
+	c.Choices = make([]chatChoice, 0, 1)
+	return c
+}
+
+func (s *anthropicStream) emit(delta *chatOutMessage, finish *string) error {
+	c := s.base()
+	c.Choices = append(c.Choices, chatChoice{Delta: delta, FinishReason: finish})
+	return writeSSEData(s.w, c)
+}
@@ -0,0 +1,338 @@
+package proxy
+
+import (
+	usageRepo "backend/internal/repository/usage"
+	usageService "backend/internal/service/usage"
+	"backend/pkg/config"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+// newTestProxy 使用内存 sqlite 构造只包含转发链路的代理, 返回代理的 /v1 入口
+func newTestProxy(t *testing.T, models map[string]config.ProxyModel) (http.Handler, *sqlx.DB) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Proxy.Models = models
+	return newTestProxyWithConfig(t, cfg)
+}
+
+func newTestProxyWithConfig(t *testing.T, cfg *config.Config) (http.Handler, *sqlx.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := sqlx.MustOpen("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	schema, err := os.ReadFile("../../scripts/sqlite.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &ProxyServer{
+		cfg:          cfg,
+		proxyCfg:     cfg.Proxy,
+		registry:     newModelRegistry(cfg.Proxy),
+		usageService: usageService.CreateUsageService(usageRepo.CreateUsageRepo(db), cfg, zap.NewNop()),
+		limiter:      newRateLimiter(),
+	}
+	p.nonStreamPool, p.streamPool = initPools(cfg.Proxy)
+	p.routePools = routePools(cfg.Proxy)
+
+	r := gin.New()
+	r.Any("/v1/*path", func(c *gin.Context) {
+		c.Set(callerContextKey, &caller{userID: 1, username: "tester"})
+	}, p.openAIProxyHandler())
+	return r, db
+}
+
+func anthropicModels(apiBase string) map[string]config.ProxyModel {
+	return map[string]config.ProxyModel{
+		"claude-test": {
+			Price: config.ModelPrice{Input: 3, Output: 15},
+			Endpoints: []config.ProxyEndpoint{{
+				Name:    "anthropic",
+				Type:    providerAnthropic,
+				ApiBase: apiBase + "/v1",
+				ApiKey:  "sk-ant-test",
+			}},
+		},
+	}
+}
+
+func postJSON(h http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+const anthropicChatRequest = `{
+	"model": "claude-test",
+	"messages": [
+		{"role": "system", "content": "be brief"},
+		{"role": "user", "content": "weather in Paris?"},
+		{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "toolu_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+		]},
+		{"role": "tool", "tool_call_id": "toolu_1", "content": "18C"},
+		{"role": "user", "content": [{"type": "text", "text": "and Rome?"}]}
+	],
+	"tools": [{"type": "function", "function": {"name": "get_weather", "description": "weather", "parameters": {"type": "object"}}}],
+	"tool_choice": "required",
+	"stop": "END"
+}`
+
+func TestAnthropicChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected upstream path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "sk-ant-test" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing anthropic auth headers: %v", r.Header)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.System != "be brief" || req.MaxTokens != anthropicDefaultMaxTokens {
+			t.Errorf("unexpected system/max_tokens: %q %d", req.System, req.MaxTokens)
+		}
+		// user, assistant(tool_use), user(tool_result + text)
+		if len(req.Messages) != 3 {
+			t.Fatalf("expected 3 alternating messages, got %+v", req.Messages)
+		}
+		if b := req.Messages[1].Content[0]; b.Type != "tool_use" || b.Name != "get_weather" || string(b.Input) != `{"city":"Paris"}` {
+			t.Errorf("unexpected tool_use block %+v", b)
+		}
+		if b := req.Messages[2].Content; len(b) != 2 || b[0].Type != "tool_result" || b[0].ToolUseID != "toolu_1" || b[0].Content != "18C" {
+			t.Errorf("unexpected tool_result message %+v", b)
+		}
+		if len(req.Tools) != 1 || req.ToolChoice == nil || req.ToolChoice.Type != "any" {
+			t.Errorf("unexpected tools %+v %+v", req.Tools, req.ToolChoice)
+		}
+		if len(req.StopSequences) != 1 || req.StopSequences[0] != "END" {
+			t.Errorf("unexpected stop sequences %v", req.StopSequences)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{
+			"id": "msg_1", "type": "message", "role": "assistant",
+			"content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {"city": "Rome"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 100, "output_tokens": 20, "cache_read_input_tokens": 10}
+		}`)
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, anthropicModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", anthropicChatRequest)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp chatCompletion
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Object != "chat.completion" || resp.Model != "claude-test" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content == nil || *choice.Message.Content != "Checking." {
+		t.Errorf("unexpected content %s", w.Body.String())
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "toolu_2" ||
+		choice.Message.ToolCalls[0].Function.Arguments != `{"city": "Rome"}` {
+		t.Errorf("unexpected tool calls %+v", choice.Message.ToolCalls)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != "tool_calls" {
+		t.Errorf("unexpected finish reason %v", choice.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 110 || resp.Usage.CompletionTokens != 20 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	var prompt, completion int64
+	if err := db.QueryRow(`SELECT prompt_tokens, completion_tokens FROM proxy_usage`).Scan(&prompt, &completion); err != nil {
+		t.Fatal(err)
+	}
+	if prompt != 110 || completion != 20 {
+		t.Errorf("unexpected recorded usage %d/%d", prompt, completion)
+	}
+}
+
+func TestAnthropicChatCompletionStream(t *testing.T) {
+	events := []string{
+		`event: message_start
+data: {"type":"message_start","message":{"id":"msg_2","type":"message","usage":{"input_tokens":50,"output_tokens":1}}}`,
+		`event: ping
+data: {"type":"ping"}`,
+		`event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		`event: content_block_stop
+data: {"type":"content_block_stop","index":0}`,
+		`event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_3","name":"get_weather","input":{}}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Rome\"}"}}`,
+		`event: content_block_stop
+data: {"type":"content_block_stop","index":1}`,
+		`event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`event: message_stop
+data: {"type":"message_stop"}`,
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("expected stream request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, ev := range events {
+			// 按半个事件写出, 验证跨读取边界的解析
+			half := len(ev) / 2
+			fmt.Fprint(w, ev[:half])
+			flusher.Flush()
+			fmt.Fprint(w, ev[half:]+"\n\n")
+			flusher.Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, anthropicModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"claude-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var (
+		content, args, finish string
+		usage                 *tokenUsage
+		done                  bool
+	)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk chatCompletion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		if chunk.Object != "chat.completion.chunk" || chunk.ID != "msg_2" {
+			t.Errorf("unexpected chunk %s", data)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content != nil {
+				content += *c.Delta.Content
+			}
+			for _, tc := range c.Delta.ToolCalls {
+				args += tc.Function.Arguments
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if content != "Hello" || args != `{"city":"Rome"}` || finish != "tool_calls" || !done {
+		t.Errorf("unexpected stream: content=%q args=%q finish=%q done=%v", content, args, finish, done)
+	}
+	if usage == nil || usage.PromptTokens != 50 || usage.CompletionTokens != 30 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	var completion int64
+	if err := db.QueryRow(`SELECT completion_tokens FROM proxy_usage WHERE stream = 1`).Scan(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion != 30 {
+		t.Errorf("unexpected recorded completion tokens %d", completion)
+	}
+}
+
+func TestAnthropicStreamErrorEvent(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_3\",\"usage\":{\"input_tokens\":5}}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, anthropicModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"claude-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	s := readChatStream(t, w.Body)
+	if s.content != "Hi" || s.errMsg != "Overloaded" || !s.done {
+		t.Errorf("expected a single upstream error followed by [DONE], got %+v", s)
+	}
+}
+
+func TestAnthropicErrorResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens too large"}}`)
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, anthropicModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"claude-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Message != "max_tokens too large" || resp.Error.Type != "invalid_request_error" {
+		t.Errorf("unexpected error body %s", w.Body.String())
+	}
+
+	// 非 chat-completions 接口无法翻译
+	w = postJSON(h, "/v1/embeddings", `{"model":"claude-test","input":"hi"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unsupported path, got %d", w.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"backend/internal/model"
+	virtualKeyService "backend/internal/service/virtual_key"
+	"backend/pkg/config"
+	"backend/pkg/jwt"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const callerContextKey = "proxyCaller"
+
+// caller 发起代理请求的调用方, 用户 JWT 或 API key 二选一
+type caller struct {
+	userID     int64
+	username   string
+	department string
+	keyName    string
+	clientIP   string
+	// virtualKey 通过数据库虚拟 key 调用时不为空
+	virtualKey *model.VirtualKey
+	// allowedModels 为空表示不限制
+	allowedModels []string
+	// interactive 是否为可信调用方, 只有用户 JWT 及配置允许的 API key 是:
+	// 可申请 interactive 优先级, X-Prompt-Id / X-Proxy-Replay-Of 才会强制抓取
+	interactive bool
+}
+
+// subject 限流维度: API key > 用户 > 客户端 IP
+func (cl *caller) subject() string {
+	switch {
+	case cl.virtualKey != nil:
+		return "vkey:" + cl.virtualKey.ID
+	case cl.keyName != "":
+		return "key:" + cl.keyName
+	case cl.userID > 0:
+		return fmt.Sprintf("user:%d", cl.userID)
+	default:
+		return "ip:" + cl.clientIP
+	}
+}
+
+// priority 请求头只能在调用方被允许的范围内提升优先级, 降为 batch 总是允许
+func (cl *caller) priority(header string) priority {
+	if p := parsePriority(header); p == priorityInteractive && cl.interactive {
+		return p
+	}
+	return priorityBatch
+}
+
+// captureHeaders 可信调用方携带的 prompt id 和重放来源, 其他调用方的这两个头被忽略, 不能借此绕过采样
+func (cl *caller) captureHeaders(h http.Header) (promptID, replayOf string) {
+	if !cl.interactive {
+		return "", ""
+	}
+	return h.Get(promptHeader), h.Get(replayHeader)
+}
+
+func (cl *caller) allowModel(model string) bool {
+	if len(cl.allowedModels) == 0 {
+		return true
+	}
+	for _, m := range cl.allowedModels {
+		if strings.EqualFold(m, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyHash 配置中的 API key 以 sha256 为索引, 避免逐个比较明文
+func keyHash(key string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(key))
+}
+
+func buildKeyIndex(keys []config.ProxyKey) map[[sha256.Size]byte]config.ProxyKey {
+	index := make(map[[sha256.Size]byte]config.ProxyKey, len(keys))
+	for _, k := range keys {
+		if k.Key == "" {
+			continue
+		}
+		index[keyHash(k.Key)] = k
+	}
+	return index
+}
+
+// bearerToken 支持 Authorization: Bearer 及 x-api-key 两种传递方式
+func bearerToken(c *gin.Context) string {
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		return strings.TrimSpace(parts[1])
+	}
+	return strings.TrimSpace(c.GetHeader("X-Api-Key"))
+}
+
+// authMiddleware 校验用户 JWT(与主服务共用密钥)、配置签发的 API key 或数据库虚拟 key
+func (p *ProxyServer) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing api key"})
+			return
+		}
+
+		cl := &caller{clientIP: c.ClientIP()}
+
+		if k, ok := p.currentKeyIndex()[keyHash(token)]; ok {
+			cl.keyName = k.Name
+			cl.allowedModels = k.Models
+			cl.interactive = k.Interactive
+			c.Set(callerContextKey, cl)
+			c.Next()
+			return
+		}
+
+		if strings.HasPrefix(token, virtualKeyService.KeyPrefix) {
+			k, err := p.virtualKeyService.Resolve(c.Request.Context(), token)
+			if err != nil {
+				status := http.StatusUnauthorized
+				if errors.Is(err, virtualKeyService.ErrDatabaseErr) {
+					status = http.StatusInternalServerError
+				}
+				c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			// 虚拟 key 的用量同时计入所有者的用户及部门预算
+			owner, err := p.userService.Get(c.Request.Context(), k.OwnerID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key owner not found"})
+				return
+			}
+			cl.virtualKey = k
+			cl.keyName = k.Name
+			cl.allowedModels = k.Models()
+			cl.userID = owner.ID
+			cl.username = owner.Username
+			cl.department = owner.Department
+
+			c.Set(callerContextKey, cl)
+			c.Next()
+			return
+		}
+
+		claims, err := jwt.ValidateToken(token, p.cfg.Security.SecretKey)
+		if err != nil {
+			message := "invalid api key"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				message = "token has expired"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+			return
+		}
+
+		u, err := p.userService.Get(c.Request.Context(), claims.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		cl.userID = u.ID
+		cl.username = u.Username
+		cl.department = u.Department
+		// 登录用户来自页面调试和回放, 虚拟 key 一律按 batch 排队
+		cl.interactive = true
+
+		c.Set(callerContextKey, cl)
+		c.Next()
+	}
+}
+
+func callerFromContext(c *gin.Context) *caller {
+	if v, ok := c.Get(callerContextKey); ok {
+		if cl, ok := v.(*caller); ok {
+			return cl
+		}
+	}
+	return &caller{clientIP: c.ClientIP()}
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"backend/internal/model"
+	"net/http"
+	"testing"
+)
+
+func TestCallerPriority(t *testing.T) {
+	cases := []struct {
+		name   string
+		caller *caller
+		header string
+		want   priority
+	}{
+		{"user interactive", &caller{userID: 1, interactive: true}, "interactive", priorityInteractive},
+		{"user default", &caller{userID: 1, interactive: true}, "", priorityBatch},
+		{"virtual key", &caller{virtualKey: &model.VirtualKey{ID: "vk"}}, "interactive", priorityBatch},
+		{"config key", &caller{keyName: "team-a"}, "Interactive", priorityBatch},
+		{"trusted config key", &caller{keyName: "ui", interactive: true}, "interactive", priorityInteractive},
+		{"downgrade", &caller{userID: 1, interactive: true}, "batch", priorityBatch},
+	}
+	for _, tc := range cases {
+		if got := tc.caller.priority(tc.header); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCallerCaptureHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set(promptHeader, "p1")
+	h.Set(replayHeader, "c1")
+
+	if promptID, replayOf := (&caller{userID: 1, interactive: true}).captureHeaders(h); promptID != "p1" || replayOf != "c1" {
+		t.Errorf("trusted caller: got %q %q", promptID, replayOf)
+	}
+	for _, cl := range []*caller{
+		{virtualKey: &model.VirtualKey{ID: "vk"}},
+		{keyName: "team-a"},
+		{clientIP: "10.0.0.1"},
+	} {
+		if promptID, replayOf := cl.captureHeaders(h); promptID != "" || replayOf != "" {
+			t.Errorf("untrusted caller %+v: got %q %q", cl, promptID, replayOf)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"backend/internal/model"
+	"backend/pkg/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+)
+
+type tokenUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+func (u *tokenUsage) total() int64 {
+	if u.TotalTokens > 0 {
+		return u.TotalTokens
+	}
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// recordUsage 计算费用并落库, 返回本次请求的费用
+func (p *ProxyServer) recordUsage(cl *caller, modelName string, price config.ModelPrice, endpoint string, stream bool, statusCode int, usage *tokenUsage) float64 {
+	if usage == nil {
+		return 0
+	}
+	cost := p.usageService.Cost(price, usage.PromptTokens, usage.CompletionTokens)
+
+	// 请求可能已被客户端取消, 这里使用独立的 context
+	u := &model.ProxyUsage{
+		UserID:           cl.userID,
+		Username:         cl.username,
+		Department:       cl.department,
+		Model:            modelName,
+		Endpoint:         endpoint,
+		Stream:           stream,
+		StatusCode:       statusCode,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             cost,
+	}
+	if cl.virtualKey != nil {
+		u.KeyID = cl.virtualKey.ID
+	}
+	if err := p.usageService.Record(context.Background(), u); err != nil {
+		log.Printf("[ERROR] record usage failed: %s", err.Error())
+	}
+	return cost
+}
+
+// withStreamUsage 为 stream 请求补充 stream_options.include_usage, 已显式设置时保持不变
+func withStreamUsage(body []byte) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return body
+	}
+	if _, ok := m["stream_options"]; ok {
+		return body
+	}
+	m["stream_options"] = json.RawMessage(`{"include_usage":true}`)
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// usageRecorder 从响应体中提取 usage, stream 响应按行扫描 SSE data
+type usageRecorder struct {
+	pending []byte
+	usage   *tokenUsage
+}
+
+func (r *usageRecorder) Write(p []byte) {
+	r.pending = append(r.pending, p...)
+	for {
+		idx := bytes.IndexByte(r.pending, '\n')
+		if idx < 0 {
+			return
+		}
+		line := bytes.TrimSpace(r.pending[:idx])
+		r.pending = r.pending[idx+1:]
+
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		r.parse(bytes.TrimSpace(data))
+	}
+}
+
+func (r *usageRecorder) parse(body []byte) {
+	if !bytes.Contains(body, []byte(`"usage"`)) {
+		return
+	}
+	var payload struct {
+		Usage *tokenUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Usage != nil {
+		r.usage = payload.Usage
+	}
+}
@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 缓存状态响应头
+const (
+	cacheHeader = "X-Proxy-Cache"
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheBypass = "BYPASS"
+)
+
+type cacheEntry struct {
+	key       string
+	body      []byte
+	stream    bool
+	createdAt time.Time
+}
+
+// responseCache LRU 响应缓存, 按条数和总字节数限制容量
+type responseCache struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	maxEntries    int
+	maxBytes      int64
+	maxEntryBytes int64
+	entries       map[string]*list.Element
+	lru           *list.List
+	bytes         int64
+
+	hits   uint64
+	misses uint64
+}
+
+// CacheStats 响应缓存状态
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// newResponseCache 未开启缓存时返回 nil
+func newResponseCache(cfg config.Proxy) *responseCache {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+	c := &responseCache{
+		ttl:           cfg.Cache.TTL,
+		maxEntries:    cfg.Cache.MaxEntries,
+		maxBytes:      cfg.Cache.MaxBytes,
+		maxEntryBytes: cfg.Cache.MaxEntryBytes,
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+	}
+	// 兜底值
+	if c.ttl <= 0 {
+		c.ttl = time.Hour
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = 10000
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = 256 << 20
+	}
+	if c.maxEntryBytes <= 0 {
+		c.maxEntryBytes = 1 << 20
+	}
+	log.Printf(
+		"[init] response cache enabled ttl=%s maxEntries=%d maxBytes=%d",
+		c.ttl, c.maxEntries, c.maxBytes,
+	)
+	return c
+}
+
+func (c *responseCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Since(entry.createdAt) > c.ttl {
+		c.removeLocked(elem)
+		c.misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.hits++
+	return entry, true
+}
+
+// set 写入缓存, 超过单条上限的响应不缓存
+func (c *responseCache) set(key string, body []byte, stream bool) {
+	size := int64(len(body))
+	if size == 0 || size > c.maxEntryBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	entry := &cacheEntry{
+		key:       key,
+		body:      body,
+		stream:    stream,
+		createdAt: time.Now(),
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += size
+
+	for c.lru.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func (c *responseCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.body))
+}
+
+func (c *responseCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Entries: c.lru.Len(),
+		Bytes:   c.bytes,
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+// cacheBypassed X-Proxy-Cache: bypass 或 Cache-Control: no-cache/no-store 时跳过缓存
+func cacheBypassed(h http.Header) bool {
+	switch strings.ToLower(strings.TrimSpace(h.Get(cacheHeader))) {
+	case "bypass", "no-cache", "no-store":
+		return true
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return strings.Contains(cc, "no-cache") || strings.Contains(cc, "no-store")
+}
+
+// cacheKey 只缓存 temperature=0 且 n<=1 的 chat-completions 请求;
+// 请求体解析后重新序列化(key 有序), 去掉不影响结果的字段, 与 path 一起计算哈希
+func cacheKey(path string, body []byte) (string, bool) {
+	if path != "/chat/completions" {
+		return "", false
+	}
+	// 数字统一解析为 float64, 0 与 0.0 视为相同
+	var m map[string]any
+	if err := json.Unmarshal(body, &m); err != nil {
+		return "", false
+	}
+	if t, ok := m["temperature"].(float64); !ok || t != 0 {
+		return "", false
+	}
+	if n, ok := m["n"].(float64); ok && n != 1 {
+		return "", false
+	}
+
+	stream, _ := m["stream"].(bool)
+	m["stream"] = stream
+	delete(m, "stream_options")
+	delete(m, "user")
+
+	normalized, err := json.Marshal(m)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(append([]byte(path+"\n"), normalized...))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// cacheBuffer 收集 stream 响应用于写入缓存, 超过上限后放弃
+type cacheBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (b *cacheBuffer) Write(p []byte) (int, error) {
+	if !b.overflow {
+		if int64(b.buf.Len()+len(p)) > b.limit {
+			b.overflow = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// replaySSE 按事件逐个写出缓存的 stream 响应
+func replaySSE(w http.ResponseWriter, body []byte) {
+	flusher, _ := w.(http.Flusher)
+	for len(body) > 0 {
+		idx := bytes.Index(body, []byte("\n\n"))
+		event := body
+		if idx >= 0 {
+			event = body[:idx+2]
+		}
+		body = body[len(event):]
+		if _, err := w.Write(event); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// serveCached 返回缓存的响应, 命中的请求不计费
+func (p *ProxyServer) serveCached(c *gin.Context, entry *cacheEntry) {
+	c.Header(cacheHeader, cacheHit)
+	c.Header("Age", strconv.FormatInt(int64(time.Since(entry.createdAt).Seconds()), 10))
+	c.Header("X-Proxy-Cost", fmt.Sprintf("%.6f", 0.0))
+	if !entry.stream {
+		c.Data(http.StatusOK, "application/json", entry.body)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	replaySSE(c.Writer, entry.body)
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"backend/internal/model"
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// promptHeader 页面调试时由后端带上, 用于按 prompt 浏览抓取记录
+	promptHeader = "X-Prompt-Id"
+	// replayHeader 重放请求携带的原始抓取记录 id
+	replayHeader = "X-Proxy-Replay-Of"
+)
+
+// captureSession 记录一次转发的请求/响应, 请求结束后落库
+type captureSession struct {
+	record   *model.ProxyCapture
+	headers  http.Header
+	start    time.Time
+	response *truncBuffer
+}
+
+// beginCapture 未开启抓取或未命中采样时返回 nil
+func (p *ProxyServer) beginCapture(c *gin.Context, cl *caller, path, modelName string, stream bool, body []byte) *captureSession {
+	if p.captureService == nil {
+		return nil
+	}
+	promptID, replayOf := cl.captureHeaders(c.Request.Header)
+	if !p.captureService.Sample(promptID, replayOf) {
+		return nil
+	}
+	record := &model.ProxyCapture{
+		UserID:      cl.userID,
+		Username:    cl.username,
+		PromptID:    promptID,
+		ReplayOf:    replayOf,
+		Model:       modelName,
+		Path:        path,
+		Stream:      stream,
+		RequestBody: string(body),
+	}
+	if cl.virtualKey != nil {
+		record.KeyID = cl.virtualKey.ID
+	}
+	return &captureSession{
+		record:   record,
+		headers:  c.Request.Header.Clone(),
+		start:    time.Now(),
+		response: &truncBuffer{limit: p.captureService.BodyLimit()},
+	}
+}
+
+// upstream 记录实际发往上游的地址和请求体(经过协议转换)
+func (s *captureSession) upstream(req *http.Request, endpoint string) {
+	s.record.Endpoint = endpoint
+	s.record.UpstreamURL = req.URL.String()
+	if req.GetBody == nil {
+		return
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(io.LimitReader(rc, int64(s.response.limit)+1))
+	s.record.UpstreamBody = string(b)
+}
+
+// finishCapture 异步落库, 不阻塞响应结束; 请求可能已被客户端取消, 使用独立的 context
+func (p *ProxyServer) finishCapture(s *captureSession, statusCode int) {
+	if s == nil {
+		return
+	}
+	s.record.StatusCode = statusCode
+	s.record.LatencyMs = time.Since(s.start).Milliseconds()
+	s.record.ResponseBody = s.response.buf.String()
+	go func() {
+		if err := p.captureService.Record(context.Background(), s.record, s.headers); err != nil {
+			log.Printf("[ERROR] record capture failed: %s", err.Error())
+		}
+	}()
+}
+
+// truncBuffer 只保留前 limit 字节, 多出的部分丢弃, 截断由 service 标记
+type truncBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *truncBuffer) Write(p []byte) (int, error) {
+	if room := b.limit + 1 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
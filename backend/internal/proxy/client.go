@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"crypto/sha256"
+	"log"
+	"net/http"
+	"sync"
+)
+
+type APIClient struct {
+	client  *http.Client
+	name    string
+	apiKey  string
+	apiBase string
+	adapter adapter
+	headers *headerPolicy
+}
+
+var rrCounter = make(map[string]uint64)
+var rrLock sync.Mutex
+
+func pickClient(model string, clients []*APIClient) *APIClient {
+	rrLock.Lock()
+	defer rrLock.Unlock()
+
+	idx := rrCounter[model] % uint64(len(clients))
+	rrCounter[model]++
+
+	return clients[idx]
+}
+
+func createModelClient(name string, apiBase string, apiKey string, adapter adapter, headers *headerPolicy, httpTransport *http.Transport) *APIClient {
+	// 不设置整体超时, 超时由每个请求的 context 控制(见 upstreamDeadline)
+	client := &http.Client{
+		Transport: httpTransport,
+		Timeout:   0,
+	}
+	return &APIClient{
+		client:  client,
+		name:    name,
+		apiKey:  apiKey,
+		apiBase: apiBase,
+		adapter: adapter,
+		headers: headers,
+	}
+}
+
+// modelRegistry 一份完整的模型 -> 客户端映射, 配置重载时整体替换
+type modelRegistry struct {
+	// cfg 生成该映射的配置, 超时、限流 tier、stream 空闲超时等设置随映射一起替换
+	cfg        config.Proxy
+	keyIndex   map[[sha256.Size]byte]config.ProxyKey
+	clients    map[string][]*APIClient
+	models     map[string]config.ProxyModel
+	transports []*http.Transport
+	guards     *guardrails
+	// inflight 正在使用该映射的请求数, 替换后等待其归零再释放连接
+	inflight sync.WaitGroup
+}
+
+func newModelRegistry(cfg config.Proxy) *modelRegistry {
+	reg := &modelRegistry{
+		cfg:      cfg,
+		keyIndex: buildKeyIndex(cfg.Auth.Keys),
+		clients:  make(map[string][]*APIClient, len(cfg.Models)),
+		models:   make(map[string]config.ProxyModel, len(cfg.Models)),
+		guards:   newGuardrails(cfg),
+	}
+	existClients := make(map[string]*APIClient)
+
+	for modelName, mc := range cfg.Models {
+		if len(mc.Endpoints) == 0 {
+			log.Printf("[warn] model=%s has no endpoints, skipped", modelName)
+			continue
+		}
+		log.Printf("[info] model=%s init", modelName)
+
+		clients := make([]*APIClient, 0, len(mc.Endpoints))
+
+		for idx, ep := range mc.Endpoints {
+			kind := ep.Type
+			if kind == "" {
+				kind = providerOpenAI
+			}
+			// 本地 Ollama 可以不配置 key
+			if ep.ApiBase == "" || (ep.ApiKey == "" && kind != providerOllama) {
+				log.Printf(
+					"[warn] model=%s endpoint[%d] invalid: name=%s base=%s",
+					modelName, idx, ep.Name, ep.ApiBase,
+				)
+				continue
+			}
+			ad, ok := adapterFor(kind)
+			if !ok {
+				log.Printf(
+					"[warn] model=%s endpoint[%d] unsupported type: name=%s type=%s",
+					modelName, idx, ep.Name, ep.Type,
+				)
+				continue
+			}
+
+			client, ok := existClients[ep.Name]
+			if !ok {
+				transport := &http.Transport{
+					MaxConnsPerHost:     cfg.HttpClient.MaxConnsPerHost,
+					MaxIdleConns:        cfg.HttpClient.MaxIdleConns,
+					MaxIdleConnsPerHost: cfg.HttpClient.MaxIdleConnsPerHost,
+					IdleConnTimeout:     cfg.HttpClient.IdleConnTimeout,
+				}
+				headers := newHeaderPolicy(cfg, ep.Name, kind, ep.Headers)
+				client = createModelClient(ep.Name, ep.ApiBase, ep.ApiKey, ad, headers, transport)
+				existClients[ep.Name] = client
+				reg.transports = append(reg.transports, transport)
+			}
+
+			clients = append(clients, client)
+
+			log.Printf(
+				"[init] model=%s endpoint[%d] type=%s base=%s",
+				modelName, idx, kind, ep.ApiBase,
+			)
+		}
+
+		if len(clients) > 0 {
+			reg.clients[modelName] = clients
+			reg.models[modelName] = mc
+		}
+	}
+
+	log.Printf(
+		"[init] modelClientMap initialized, models=%d",
+		len(reg.clients),
+	)
+	return reg
+}
+
+// drain 等待旧映射上的请求(包括 stream)全部结束后关闭空闲连接
+func (r *modelRegistry) drain() {
+	r.inflight.Wait()
+	for _, t := range r.transports {
+		t.CloseIdleConnections()
+	}
+	log.Printf("[proxy] previous model registry drained, models=%d", len(r.clients))
+}
@@ -0,0 +1,439 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// geminiAdapter Google Gemini generateContent API
+type geminiAdapter struct{}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int64    `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiResponse struct {
+	ResponseID string `json:"responseId"`
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		TotalTokenCount      int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (r *geminiResponse) usage() *tokenUsage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &tokenUsage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+func (geminiAdapter) newRequest(ctx context.Context, c *gin.Context, client *APIClient, path string, body []byte, stream bool) (*http.Request, error) {
+	if path != "/chat/completions" {
+		return nil, errUnsupportedPath
+	}
+	var in chatRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidRequest, err.Error())
+	}
+	out, err := toGeminiRequest(&in)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	target := client.apiBase + "/models/" + url.PathEscape(in.Model) + ":generateContent"
+	if stream {
+		target = client.apiBase + "/models/" + url.PathEscape(in.Model) + ":streamGenerateContent?alt=sse"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", client.apiKey)
+	return req, nil
+}
+
+func toGeminiRequest(in *chatRequest) (*geminiRequest, error) {
+	out := &geminiRequest{}
+	cfg := &geminiGenerationConfig{
+		MaxOutputTokens: in.maxTokens(),
+		Temperature:     in.Temperature,
+		TopP:            in.TopP,
+		StopSequences:   in.stopSequences(),
+	}
+	if cfg.MaxOutputTokens > 0 || cfg.Temperature != nil || cfg.TopP != nil || len(cfg.StopSequences) > 0 {
+		out.GenerationConfig = cfg
+	}
+
+	// functionResponse 需要函数名, 通过 tool_call_id 反查
+	toolNames := make(map[string]string)
+	var system []geminiPart
+	for i := range in.Messages {
+		m := &in.Messages[i]
+		switch m.Role {
+		case "system", "developer":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			system = append(system, geminiPart{Text: text})
+		case "user":
+			parts, err := geminiParts(m)
+			if err != nil {
+				return nil, err
+			}
+			out.appendContent("user", parts...)
+		case "assistant":
+			parts, err := geminiParts(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, tc := range m.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: tc.Function.Name, Args: args}})
+			}
+			out.appendContent("model", parts...)
+		case "tool":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			out.appendContent("user", geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     toolNames[m.ToolCallID],
+				Response: geminiToolResult(text),
+			}})
+		default:
+			return nil, fmt.Errorf("%w: unsupported role %q", errInvalidRequest, m.Role)
+		}
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if len(in.Tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(in.Tools))
+		for _, t := range in.Tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		out.Tools = []geminiTool{{FunctionDeclarations: decls}}
+		out.ToolConfig = geminiChoice(in.ToolChoice)
+	}
+	return out, nil
+}
+
+// appendContent 相邻同角色消息合并
+func (r *geminiRequest) appendContent(role string, parts ...geminiPart) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(r.Contents); n > 0 && r.Contents[n-1].Role == role {
+		r.Contents[n-1].Parts = append(r.Contents[n-1].Parts, parts...)
+		return
+	}
+	r.Contents = append(r.Contents, geminiContent{Role: role, Parts: parts})
+}
+
+func geminiParts(m *chatMessage) ([]geminiPart, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]geminiPart, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				res = append(res, geminiPart{Text: p.Text})
+			}
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			mimeType, data, ok := parseDataURL(p.ImageURL.URL)
+			if !ok {
+				return nil, fmt.Errorf("%w: gemini only supports base64 data URL images", errInvalidRequest)
+			}
+			res = append(res, geminiPart{InlineData: &geminiInlineData{MimeType: mimeType, Data: data}})
+		}
+	}
+	return res, nil
+}
+
+// geminiToolResult functionResponse.response 必须是对象, 非 JSON 对象的结果包装为 {"content": ...}
+func geminiToolResult(text string) json.RawMessage {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	b, _ := json.Marshal(map[string]string{"content": text})
+	return b
+}
+
+func geminiChoice(raw json.RawMessage) *geminiToolConfig {
+	cfg := &geminiToolConfig{}
+	switch mode, name := parseToolChoice(raw); mode {
+	case "auto":
+		cfg.FunctionCallingConfig.Mode = "AUTO"
+	case "none":
+		cfg.FunctionCallingConfig.Mode = "NONE"
+	case "required":
+		cfg.FunctionCallingConfig.Mode = "ANY"
+	case "function":
+		cfg.FunctionCallingConfig.Mode = "ANY"
+		cfg.FunctionCallingConfig.AllowedFunctionNames = []string{name}
+	default:
+		return nil
+	}
+	return cfg
+}
+
+func (geminiAdapter) convertResponse(body []byte, model string) []byte {
+	var in geminiResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return body
+	}
+	if in.Error != nil {
+		return chatError(in.Error.Message, in.Error.Status)
+	}
+
+	out := newCompletion(in.ResponseID, model, "chat.completion")
+	msg := &chatOutMessage{Role: "assistant"}
+	var text strings.Builder
+	finish := "stop"
+	if len(in.Candidates) > 0 {
+		cand := in.Candidates[0]
+		for _, p := range cand.Content.Parts {
+			if p.FunctionCall != nil {
+				msg.ToolCalls = append(msg.ToolCalls, geminiToolCall(p.FunctionCall, len(msg.ToolCalls)))
+				continue
+			}
+			text.WriteString(p.Text)
+		}
+		finish = geminiFinishReason(cand.FinishReason, len(msg.ToolCalls) > 0)
+	}
+	if text.Len() > 0 || len(msg.ToolCalls) == 0 {
+		content := text.String()
+		msg.Content = &content
+	}
+	out.Choices = append(out.Choices, chatChoice{Message: msg, FinishReason: &finish})
+	out.Usage = in.usage()
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// geminiToolCall Gemini 的函数调用没有 id, 按下标生成
+func geminiToolCall(fc *geminiFunctionCall, idx int) chatToolCall {
+	args := string(fc.Args)
+	if args == "" {
+		args = "{}"
+	}
+	return chatToolCall{
+		ID:       fmt.Sprintf("call_%d", idx),
+		Type:     "function",
+		Function: chatFunctionCall{Name: fc.Name, Arguments: args},
+	}
+}
+
+func geminiFinishReason(reason string, toolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func (geminiAdapter) streamConverter(w io.Writer, model string) io.Writer {
+	return &geminiStream{w: w, model: model}
+}
+
+// geminiStream 将 streamGenerateContent(alt=sse) 的增量响应转换为 OpenAI chunk
+type geminiStream struct {
+	w       io.Writer
+	model   string
+	decoder sseDecoder
+	chunk   *chatCompletion
+	tools   int
+	done    bool
+}
+
+func (s *geminiStream) Write(p []byte) (int, error) {
+	for _, ev := range s.decoder.feed(p) {
+		if err := s.handle(ev); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *geminiStream) handle(ev sseEvent) error {
+	if s.done {
+		return nil
+	}
+	var in geminiResponse
+	if err := json.Unmarshal(ev.data, &in); err != nil {
+		return nil
+	}
+	if in.Error != nil {
+		s.done = true
+		if _, err := fmt.Fprintf(s.w, "data: %s\n\n", chatError(in.Error.Message, in.Error.Status)); err != nil {
+			return err
+		}
+		return writeSSEDone(s.w)
+	}
+
+	if s.chunk == nil {
+		s.chunk = newCompletion(in.ResponseID, s.model, "chat.completion.chunk")
+		empty := ""
+		if err := s.emit(&chatOutMessage{Role: "assistant", Content: &empty}, nil); err != nil {
+			return err
+		}
+	}
+	if len(in.Candidates) == 0 {
+		return nil
+	}
+
+	cand := in.Candidates[0]
+	delta := &chatOutMessage{}
+	var text strings.Builder
+	for _, p := range cand.Content.Parts {
+		if p.FunctionCall != nil {
+			idx := s.tools
+			tc := geminiToolCall(p.FunctionCall, idx)
+			tc.Index = &idx
+			delta.ToolCalls = append(delta.ToolCalls, tc)
+			s.tools++
+			continue
+		}
+		text.WriteString(p.Text)
+	}
+	if text.Len() > 0 {
+		content := text.String()
+		delta.Content = &content
+	}
+	if delta.Content != nil || len(delta.ToolCalls) > 0 {
+		if err := s.emit(delta, nil); err != nil {
+			return err
+		}
+	}
+	if cand.FinishReason == "" {
+		return nil
+	}
+
+	// Gemini 没有结束事件, 以携带 finishReason 的响应作为结尾
+	s.done = true
+	finish := geminiFinishReason(cand.FinishReason, s.tools > 0)
+	if err := s.emit(&chatOutMessage{}, &finish); err != nil {
+		return err
+	}
+	if usage := in.usage(); usage != nil {
+		c := *s.chunk
+		c.Choices = make([]chatChoice, 0)
+		c.Usage = usage
+		if err := writeSSEData(s.w, c); err != nil {
+			return err
+		}
+	}
+	return writeSSEDone(s.w)
+}
+
+func (s *geminiStream) emit(delta *chatOutMessage, finish *string) error {
+	c := *s.chunk
+	c.Choices = []chatChoice{{Delta: delta, FinishReason: finish}}
+	return writeSSEData(s.w, c)
+}
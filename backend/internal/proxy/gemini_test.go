@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func geminiModels(apiBase string) map[string]config.ProxyModel {
+	return map[string]config.ProxyModel{
+		"gemini-test": {
+			Price: config.ModelPrice{Input: 1, Output: 2},
+			Endpoints: []config.ProxyEndpoint{{
+				Name:    "gemini",
+				Type:    providerGemini,
+				ApiBase: apiBase + "/v1beta",
+				ApiKey:  "gm-test",
+			}},
+		},
+	}
+}
+
+func TestGeminiChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-test:generateContent" {
+			t.Errorf("unexpected upstream path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "gm-test" {
+			t.Errorf("missing gemini api key: %v", r.Header)
+		}
+
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "be brief" {
+			t.Errorf("unexpected system instruction %+v", req.SystemInstruction)
+		}
+		// user, model(functionCall), user(functionResponse + text)
+		if len(req.Contents) != 3 || req.Contents[1].Role != "model" {
+			t.Fatalf("expected 3 alternating contents, got %+v", req.Contents)
+		}
+		if fc := req.Contents[1].Parts[0].FunctionCall; fc == nil || fc.Name != "get_weather" || string(fc.Args) != `{"city":"Paris"}` {
+			t.Errorf("unexpected function call %+v", req.Contents[1].Parts)
+		}
+		if fr := req.Contents[2].Parts[0].FunctionResponse; fr == nil || fr.Name != "get_weather" || string(fr.Response) != `{"content":"18C"}` {
+			t.Errorf("unexpected function response %+v", req.Contents[2].Parts)
+		}
+		if req.ToolConfig == nil || req.ToolConfig.FunctionCallingConfig.Mode != "ANY" {
+			t.Errorf("unexpected tool config %+v", req.ToolConfig)
+		}
+		if req.GenerationConfig == nil || len(req.GenerationConfig.StopSequences) != 1 {
+			t.Errorf("unexpected generation config %+v", req.GenerationConfig)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{
+			"responseId": "resp_1",
+			"candidates": [{
+				"content": {"role": "model", "parts": [
+					{"text": "Checking."},
+					{"functionCall": {"name": "get_weather", "args": {"city": "Rome"}}}
+				]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 100, "candidatesTokenCount": 20, "totalTokenCount": 120}
+		}`)
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, geminiModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{
+		"model": "gemini-test",
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "weather in Paris?"},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_0", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_0", "content": "18C"},
+			{"role": "user", "content": "and Rome?"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp chatCompletion
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "resp_1" || resp.Model != "gemini-test" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content == nil || *choice.Message.Content != "Checking." {
+		t.Errorf("unexpected content %s", w.Body.String())
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "call_0" ||
+		choice.Message.ToolCalls[0].Function.Arguments != `{"city": "Rome"}` {
+		t.Errorf("unexpected tool calls %+v", choice.Message.ToolCalls)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != "tool_calls" {
+		t.Errorf("unexpected finish reason %v", choice.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 100 || resp.Usage.CompletionTokens != 20 || resp.Usage.TotalTokens != 120 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	var prompt, completion int64
+	if err := db.QueryRow(`SELECT prompt_tokens, completion_tokens FROM proxy_usage`).Scan(&prompt, &completion); err != nil {
+		t.Fatal(err)
+	}
+	if prompt != 100 || completion != 20 {
+		t.Errorf("unexpected recorded usage %d/%d", prompt, completion)
+	}
+}
+
+func TestGeminiChatCompletionStream(t *testing.T) {
+	chunks := []string{
+		`{"responseId":"resp_2","candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		`{"responseId":"resp_2","candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}]}`,
+		`{"responseId":"resp_2","candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"MAX_TOKENS"}],` +
+			`"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":30,"totalTokenCount":80}}`,
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected upstream url %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, c := range chunks {
+			// 按半个事件写出, 验证跨读取边界的解析
+			ev := "data: " + c + "\r\n\r\n"
+			half := len(ev) / 2
+			fmt.Fprint(w, ev[:half])
+			flusher.Flush()
+			fmt.Fprint(w, ev[half:])
+			flusher.Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, geminiModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gemini-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	s := readChatStream(t, w.Body)
+	if s.content != "Hello" || s.finish != "length" || s.errMsg != "" || !s.done {
+		t.Errorf("unexpected stream %+v", s)
+	}
+	if s.usage == nil || s.usage.PromptTokens != 50 || s.usage.CompletionTokens != 30 {
+		t.Errorf("unexpected usage %+v", s.usage)
+	}
+
+	var completion int64
+	if err := db.QueryRow(`SELECT completion_tokens FROM proxy_usage WHERE stream = 1`).Scan(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion != 30 {
+		t.Errorf("unexpected recorded completion tokens %d", completion)
+	}
+}
+
+func TestGeminiErrorResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`)
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, geminiModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gemini-test","messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Message != "quota exceeded" || resp.Error.Type != "RESOURCE_EXHAUSTED" {
+		t.Errorf("unexpected error body %s", w.Body.String())
+	}
+}
+
+func TestGeminiStreamErrorEvent(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"responseId\":\"resp_3\",\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"error\":{\"code\":500,\"message\":\"internal error\",\"status\":\"INTERNAL\"}}\n\n")
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, geminiModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gemini-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	s := readChatStream(t, w.Body)
+	if s.content != "Hi" || s.errMsg != "internal error" || !s.done {
+		t.Errorf("expected a single upstream error followed by [DONE], got %+v", s)
+	}
+}
@@ -0,0 +1,606 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	guardRegex   = "regex"
+	guardKeyword = "keyword"
+	guardLength  = "length"
+	guardWebhook = "webhook"
+
+	guardModeBlock  = "block"
+	guardModeRedact = "redact"
+	guardModeLog    = "log"
+
+	stageRequest  = "request"
+	stageResponse = "response"
+
+	redactedText = "[REDACTED]"
+
+	defaultGuardWebhookTimeout = 5 * time.Second
+	defaultGuardMaxStreamBytes = 1 << 20
+
+	// guardStreamLimit stream 超出缓存上限时拦截使用的检查名
+	guardStreamLimit = "max_stream_bytes"
+)
+
+// guardVerdict 单个检查的结果, texts 为脱敏后的文本, 无法脱敏时为 nil
+type guardVerdict struct {
+	flagged bool
+	reason  string
+	texts   []string
+}
+
+type guard interface {
+	check(ctx context.Context, stage, model string, texts []string) (guardVerdict, error)
+}
+
+// guardViolation 内容被拦截, 作为错误返回给调用方
+type guardViolation struct {
+	guard  string
+	stage  string
+	reason string
+}
+
+func (v *guardViolation) Error() string {
+	return fmt.Sprintf("%s blocked by guardrail %s: %s", v.stage, v.guard, v.reason)
+}
+
+// patternGuard regex / keyword 检查, 命中的片段替换为 [REDACTED]
+type patternGuard struct {
+	kind     string
+	patterns []*regexp.Regexp
+}
+
+func (g patternGuard) check(_ context.Context, _, _ string, texts []string) (guardVerdict, error) {
+	v := guardVerdict{texts: make([]string, len(texts))}
+	for i, text := range texts {
+		for idx, re := range g.patterns {
+			if !re.MatchString(text) {
+				continue
+			}
+			// 原因只给出规则序号, 避免敏感内容出现在日志和错误信息中
+			if !v.flagged {
+				v.reason = fmt.Sprintf("%s #%d matched", g.kind, idx+1)
+			}
+			v.flagged = true
+			text = re.ReplaceAllLiteralString(text, redactedText)
+		}
+		v.texts[i] = text
+	}
+	return v, nil
+}
+
+// lengthGuard 所有文本合计的字符数上限, 超出时无法脱敏
+type lengthGuard struct {
+	max int
+}
+
+func (g lengthGuard) check(_ context.Context, _, _ string, texts []string) (guardVerdict, error) {
+	total := 0
+	for _, text := range texts {
+		total += utf8.RuneCountInString(text)
+	}
+	if total <= g.max {
+		return guardVerdict{}, nil
+	}
+	return guardVerdict{flagged: true, reason: fmt.Sprintf("content length %d exceeds %d", total, g.max)}, nil
+}
+
+// webhookGuard 调用外部服务检查, 请求体 {guard, stage, model, texts}, 响应体 {flagged, reason, texts}
+type webhookGuard struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+type webhookGuardRequest struct {
+	Guard string   `json:"guard"`
+	Stage string   `json:"stage"`
+	Model string   `json:"model"`
+	Texts []string `json:"texts"`
+}
+
+type webhookGuardResponse struct {
+	Flagged bool     `json:"flagged"`
+	Reason  string   `json:"reason"`
+	Texts   []string `json:"texts"`
+}
+
+func (g webhookGuard) check(ctx context.Context, stage, model string, texts []string) (guardVerdict, error) {
+	body, err := json.Marshal(webhookGuardRequest{Guard: g.name, Stage: stage, Model: model, Texts: texts})
+	if err != nil {
+		return guardVerdict{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return guardVerdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range g.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return guardVerdict{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return guardVerdict{}, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	var result webhookGuardResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return guardVerdict{}, fmt.Errorf("decode webhook response: %w", err)
+	}
+	v := guardVerdict{flagged: result.Flagged, reason: result.Reason}
+	if v.reason == "" {
+		v.reason = "flagged by webhook"
+	}
+	// 返回的文本数量不一致时视为无法脱敏
+	if len(result.Texts) == len(texts) {
+		v.texts = result.Texts
+	}
+	return v, nil
+}
+
+type namedGuard struct {
+	name     string
+	stage    string
+	guard    guard
+	failOpen bool
+}
+
+// guardrails 按模型对请求/响应中的文本执行检查, 随模型映射一起热加载
+type guardrails struct {
+	guards   map[string]*namedGuard
+	policies map[string]config.GuardrailPolicy
+	// maxStreamBytes 响应检查时 stream 最多缓存的字节数
+	maxStreamBytes int64
+}
+
+func newGuardrails(cfg config.Proxy) *guardrails {
+	if len(cfg.Guardrails.Models) == 0 {
+		return nil
+	}
+
+	g := &guardrails{
+		guards:   make(map[string]*namedGuard, len(cfg.Guardrails.Guards)),
+		policies: make(map[string]config.GuardrailPolicy, len(cfg.Guardrails.Models)),
+
+		maxStreamBytes: cfg.Guardrails.MaxStreamBytes,
+	}
+	if g.maxStreamBytes <= 0 {
+		g.maxStreamBytes = defaultGuardMaxStreamBytes
+	}
+	for _, gc := range cfg.Guardrails.Guards {
+		ng, err := buildGuard(gc)
+		if err != nil {
+			log.Printf("[warn] guardrail %s invalid, skipped: %s", gc.Name, err.Error())
+			continue
+		}
+		g.guards[gc.Name] = ng
+	}
+	for model, policy := range cfg.Guardrails.Models {
+		switch policy.Mode {
+		case "":
+			policy.Mode = guardModeBlock
+		case guardModeBlock, guardModeRedact, guardModeLog:
+		default:
+			log.Printf("[warn] guardrail mode %q for model=%s unknown, use block", policy.Mode, model)
+			policy.Mode = guardModeBlock
+		}
+		for _, name := range policy.Guards {
+			if _, ok := g.guards[name]; !ok {
+				log.Printf("[warn] guardrail %s for model=%s not defined", name, model)
+			}
+		}
+		g.policies[strings.ToLower(model)] = policy
+	}
+	return g
+}
+
+func buildGuard(gc config.GuardrailGuard) (*namedGuard, error) {
+	if gc.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+	if gc.Stage != "" && gc.Stage != stageRequest && gc.Stage != stageResponse {
+		return nil, fmt.Errorf("unknown stage %q", gc.Stage)
+	}
+
+	ng := &namedGuard{name: gc.Name, stage: gc.Stage, failOpen: gc.FailOpen}
+	switch gc.Type {
+	case guardRegex:
+		patterns := make([]*regexp.Regexp, 0, len(gc.Patterns))
+		for _, expr := range gc.Patterns {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, re)
+		}
+		ng.guard = patternGuard{kind: "pattern", patterns: patterns}
+	case guardKeyword:
+		patterns := make([]*regexp.Regexp, 0, len(gc.Keywords))
+		for _, kw := range gc.Keywords {
+			if kw == "" {
+				continue
+			}
+			patterns = append(patterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(kw)))
+		}
+		ng.guard = patternGuard{kind: "keyword", patterns: patterns}
+	case guardLength:
+		if gc.MaxLength <= 0 {
+			return nil, fmt.Errorf("max_length must be positive")
+		}
+		ng.guard = lengthGuard{max: gc.MaxLength}
+	case guardWebhook:
+		if gc.URL == "" {
+			return nil, fmt.Errorf("missing url")
+		}
+		timeout := gc.Timeout
+		if timeout <= 0 {
+			timeout = defaultGuardWebhookTimeout
+		}
+		ng.guard = webhookGuard{name: gc.Name, url: gc.URL, headers: gc.Headers, client: &http.Client{Timeout: timeout}}
+	default:
+		return nil, fmt.Errorf("unknown type %q", gc.Type)
+	}
+	return ng, nil
+}
+
+func (g *guardrails) policyFor(model string) (config.GuardrailPolicy, bool) {
+	if g == nil {
+		return config.GuardrailPolicy{}, false
+	}
+	if policy, ok := g.policies[strings.ToLower(model)]; ok {
+		return policy, len(policy.Guards) > 0
+	}
+	policy, ok := g.policies["*"]
+	return policy, ok && len(policy.Guards) > 0
+}
+
+// active 该模型是否配置了检查
+func (g *guardrails) active(model string) bool {
+	_, ok := g.policyFor(model)
+	return ok
+}
+
+// activeFor 该模型是否配置了适用于 stage 的检查
+func (g *guardrails) activeFor(model, stage string) bool {
+	policy, ok := g.policyFor(model)
+	if !ok {
+		return false
+	}
+	for _, name := range policy.Guards {
+		if ng := g.guards[name]; ng != nil && (ng.stage == "" || ng.stage == stage) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 依次执行模型配置的检查, 返回处理后的文本; 拦截时返回 *guardViolation
+func (g *guardrails) apply(ctx context.Context, stage, model string, texts []string) ([]string, error) {
+	policy, ok := g.policyFor(model)
+	if !ok || len(texts) == 0 {
+		return texts, nil
+	}
+
+	for _, name := range policy.Guards {
+		ng := g.guards[name]
+		if ng == nil || (ng.stage != "" && ng.stage != stage) {
+			continue
+		}
+		v, err := ng.guard.check(ctx, stage, model, texts)
+		if err != nil {
+			log.Printf("[GUARD] guard=%s stage=%s model=%s check failed: %s", name, stage, model, err.Error())
+			if ng.failOpen {
+				continue
+			}
+			v = guardVerdict{flagged: true, reason: "guard unavailable"}
+		}
+		if !v.flagged {
+			continue
+		}
+
+		log.Printf("[GUARD] guard=%s stage=%s model=%s mode=%s: %s", name, stage, model, policy.Mode, v.reason)
+		switch {
+		case policy.Mode == guardModeLog:
+		case policy.Mode == guardModeRedact && v.texts != nil:
+			texts = v.texts
+		default:
+			return nil, &guardViolation{guard: name, stage: stage, reason: v.reason}
+		}
+	}
+	return texts, nil
+}
+
+// guardJSON 检查 JSON 请求/响应体中的文本, 脱敏后重新编码; 非 JSON 或没有文本时原样返回
+func (g *guardrails) guardJSON(ctx context.Context, stage, model string, body []byte) ([]byte, error) {
+	if !g.active(model) {
+		return body, nil
+	}
+
+	doc, ok := decodeJSON(body)
+	if !ok {
+		return body, nil
+	}
+
+	var slots []textSlot
+	if stage == stageRequest {
+		slots = requestTexts(doc)
+	} else {
+		slots = responseTexts(doc)
+	}
+	if len(slots) == 0 {
+		return body, nil
+	}
+
+	texts := make([]string, len(slots))
+	for i, slot := range slots {
+		texts[i] = slot.value
+	}
+	out, err := g.apply(ctx, stage, model, texts)
+	if err != nil {
+		return body, err
+	}
+
+	changed := false
+	for i, slot := range slots {
+		if out[i] != texts[i] {
+			slot.set(out[i])
+			changed = true
+		}
+	}
+	if !changed {
+		return body, nil
+	}
+	return encodeJSON(doc, body)
+}
+
+func decodeJSON(body []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	// 保留数字原样, 避免重新编码后 seed 等大整数丢失精度
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// encodeJSON 重新编码改写后的文档, 失败时返回原始内容
+func encodeJSON(doc map[string]any, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return body, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// textSlot JSON 中的一段文本及其回写方法
+type textSlot struct {
+	value string
+	set   func(string)
+}
+
+// fieldTexts 收集 m[key] 中的文本: 字符串、字符串数组, 或 content parts 中的 text
+func fieldTexts(m map[string]any, key string) []textSlot {
+	switch v := m[key].(type) {
+	case string:
+		return []textSlot{{value: v, set: func(s string) { m[key] = s }}}
+	case []any:
+		var slots []textSlot
+		for i, item := range v {
+			switch it := item.(type) {
+			case string:
+				slots = append(slots, textSlot{value: it, set: func(s string) { v[i] = s }})
+			case map[string]any:
+				if text, ok := it["text"].(string); ok {
+					slots = append(slots, textSlot{value: text, set: func(s string) { it["text"] = s }})
+				}
+			}
+		}
+		return slots
+	}
+	return nil
+}
+
+// toolCallTexts tool_calls[].function.arguments, stream 中同一 tool call 的参数分散在多个 delta, 按 index 区分
+func toolCallTexts(msg map[string]any, each func(index string, slot textSlot)) {
+	calls, _ := msg["tool_calls"].([]any)
+	for i, item := range calls {
+		call, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		fn, ok := call["function"].(map[string]any)
+		if !ok {
+			continue
+		}
+		index := fmt.Sprint(i)
+		if v, ok := call["index"]; ok {
+			index = fmt.Sprint(v)
+		}
+		for _, slot := range fieldTexts(fn, "arguments") {
+			each(index, slot)
+		}
+	}
+}
+
+func appendToolCallTexts(slots []textSlot, msg map[string]any) []textSlot {
+	toolCallTexts(msg, func(_ string, slot textSlot) {
+		slots = append(slots, slot)
+	})
+	return slots
+}
+
+// requestTexts chat 的 messages[].content 和 tool call 参数, completions 的 prompt, embeddings 等的 input
+func requestTexts(doc map[string]any) []textSlot {
+	var slots []textSlot
+	if messages, ok := doc["messages"].([]any); ok {
+		for _, item := range messages {
+			if msg, ok := item.(map[string]any); ok {
+				slots = append(slots, fieldTexts(msg, "content")...)
+				slots = appendToolCallTexts(slots, msg)
+			}
+		}
+	}
+	slots = append(slots, fieldTexts(doc, "prompt")...)
+	slots = append(slots, fieldTexts(doc, "input")...)
+	return slots
+}
+
+// responseTexts choices[].message / choices[].delta 的 content 和 tool call 参数, choices[].text
+func responseTexts(doc map[string]any) []textSlot {
+	var slots []textSlot
+	choices, _ := doc["choices"].([]any)
+	for _, item := range choices {
+		choice, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"message", "delta"} {
+			if msg, ok := choice[key].(map[string]any); ok {
+				slots = append(slots, fieldTexts(msg, "content")...)
+				slots = appendToolCallTexts(slots, msg)
+			}
+		}
+		slots = append(slots, fieldTexts(choice, "text")...)
+	}
+	return slots
+}
+
+// streamGuard 缓存 stream 的全部事件, 上游结束后按 choice 拼出完整文本(含 tool call 参数)统一检查:
+// 跨多个 delta 的敏感内容同样能命中, webhook 检查也会执行; 通过或脱敏后再写给客户端
+type streamGuard struct {
+	ctx    context.Context
+	guards *guardrails
+	model  string
+	events []sseEvent
+	size   int64
+}
+
+// hold 缓存一个事件; 超过 maxStreamBytes 时 block/redact 模式返回 *guardViolation (fail closed),
+// log 模式返回 false, 调用方写出已缓存的事件, 剩余部分不再检查
+func (sg *streamGuard) hold(ev sseEvent) (bool, error) {
+	sg.events = append(sg.events, ev)
+	sg.size += int64(len(ev.data))
+	if sg.size <= sg.guards.maxStreamBytes {
+		return true, nil
+	}
+
+	policy, _ := sg.guards.policyFor(sg.model)
+	log.Printf("[GUARD] stream model=%s mode=%s exceeds %d bytes buffered for response checks", sg.model, policy.Mode, sg.guards.maxStreamBytes)
+	if policy.Mode == guardModeLog {
+		return false, nil
+	}
+	return false, &guardViolation{
+		guard:  guardStreamLimit,
+		stage:  stageResponse,
+		reason: fmt.Sprintf("response exceeds %d bytes", sg.guards.maxStreamBytes),
+	}
+}
+
+// streamPiece 完整文本在某个事件中的一段
+type streamPiece struct {
+	event int
+	slot  textSlot
+}
+
+// release 检查缓存的事件, 返回可以写出的事件; 拦截时返回 *guardViolation
+// 脱敏时整段文本写入第一个片段, 其余片段置空
+func (sg *streamGuard) release() ([]sseEvent, error) {
+	docs := make([]map[string]any, len(sg.events))
+	pieces := make(map[string][]streamPiece)
+	var keys []string
+	add := func(key string, event int, slot textSlot) {
+		if _, ok := pieces[key]; !ok {
+			keys = append(keys, key)
+		}
+		pieces[key] = append(pieces[key], streamPiece{event: event, slot: slot})
+	}
+
+	for i, ev := range sg.events {
+		doc, ok := decodeJSON(ev.data)
+		if !ok {
+			continue
+		}
+		docs[i] = doc
+		choices, _ := doc["choices"].([]any)
+		for _, item := range choices {
+			choice, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			index := fmt.Sprint(choice["index"])
+			if delta, ok := choice["delta"].(map[string]any); ok {
+				for _, slot := range fieldTexts(delta, "content") {
+					add(index+"/content", i, slot)
+				}
+				toolCallTexts(delta, func(call string, slot textSlot) {
+					add(index+"/tool/"+call, i, slot)
+				})
+			}
+			for _, slot := range fieldTexts(choice, "text") {
+				add(index+"/text", i, slot)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return sg.events, nil
+	}
+
+	texts := make([]string, len(keys))
+	for i, key := range keys {
+		var b strings.Builder
+		for _, piece := range pieces[key] {
+			b.WriteString(piece.slot.value)
+		}
+		texts[i] = b.String()
+	}
+	out, err := sg.guards.apply(sg.ctx, stageResponse, sg.model, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[int]bool)
+	for i, key := range keys {
+		if out[i] == texts[i] {
+			continue
+		}
+		for j, piece := range pieces[key] {
+			if j == 0 {
+				piece.slot.set(out[i])
+			} else {
+				piece.slot.set("")
+			}
+			changed[piece.event] = true
+		}
+	}
+	for i := range changed {
+		data, err := encodeJSON(docs[i], sg.events[i].data)
+		if err != nil {
+			return nil, err
+		}
+		sg.events[i].data = data
+	}
+	return sg.events, nil
+}
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// chatStream 从 OpenAI SSE 响应中拼出的内容
+type chatStream struct {
+	content string
+	args    string
+	finish  string
+	errMsg  string
+	usage   *tokenUsage
+	done    bool
+	chunks  int
+}
+
+func readChatStream(t *testing.T, body io.Reader) chatStream {
+	t.Helper()
+	var s chatStream
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			s.done = true
+			continue
+		}
+		var errChunk struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &errChunk); err == nil && errChunk.Error != nil {
+			if s.errMsg != "" {
+				t.Errorf("more than one error event: %q, %q", s.errMsg, errChunk.Error.Message)
+			}
+			s.errMsg = errChunk.Error.Message
+			continue
+		}
+		var chunk chatCompletion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		s.chunks++
+		if chunk.Usage != nil {
+			s.usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content != nil {
+				s.content += *c.Delta.Content
+			}
+			for _, tc := range c.Delta.ToolCalls {
+				s.args += tc.Function.Arguments
+			}
+			if c.FinishReason != nil {
+				s.finish = *c.FinishReason
+			}
+		}
+	}
+	return s
+}
+
+// openAIStream 按 delta 逐个写出 OpenAI 格式的 stream, 敏感内容拆在多个 delta 中
+func openAIStream(w http.ResponseWriter) {
+	deltas := []string{
+		`{"content":"card 4111 "}`,
+		`{"content":"1111 1111 "}`,
+		`{"content":"1111 ok"}`,
+		`{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"pay","arguments":"{\"card\":\"4111 1111"}}]}`,
+		`{"tool_calls":[{"index":0,"function":{"arguments":" 1111 1111\"}"}}]}`,
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	for _, d := range deltas {
+		fmt.Fprintf(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", d)
+		flusher.Flush()
+	}
+	fmt.Fprint(w, "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":9,\"total_tokens\":14}}\n\n")
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+func guardedConfig(apiBase, mode string, guards ...config.GuardrailGuard) *config.Config {
+	cfg := &config.Config{}
+	cfg.Proxy.Models = map[string]config.ProxyModel{
+		"gpt-test": {Endpoints: []config.ProxyEndpoint{{Name: "openai", ApiBase: apiBase + "/v1", ApiKey: "sk-test"}}},
+	}
+	names := make([]string, 0, len(guards))
+	for _, g := range guards {
+		names = append(names, g.Name)
+	}
+	cfg.Proxy.Guardrails.Guards = guards
+	cfg.Proxy.Guardrails.Models = map[string]config.GuardrailPolicy{"gpt-test": {Mode: mode, Guards: names}}
+	return cfg
+}
+
+var cardGuard = config.GuardrailGuard{
+	Name:     "card",
+	Type:     guardRegex,
+	Stage:    stageResponse,
+	Patterns: []string{`\d{4} \d{4} \d{4} \d{4}`},
+}
+
+func TestGuardrailStreamRedactAcrossDeltas(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAIStream(w)
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxyWithConfig(t, guardedConfig(upstream.URL, guardModeRedact, cardGuard))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gpt-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	s := readChatStream(t, w.Body)
+	if s.content != "card [REDACTED] ok" || s.args != `{"card":"[REDACTED]"}` || s.errMsg != "" || !s.done {
+		t.Errorf("unexpected stream: %+v", s)
+	}
+	if strings.Contains(w.Body.String(), "4111") {
+		t.Errorf("card number leaked: %s", w.Body.String())
+	}
+
+	var completion int64
+	if err := db.QueryRow(`SELECT completion_tokens FROM proxy_usage WHERE stream = 1`).Scan(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion != 9 {
+		t.Errorf("unexpected recorded completion tokens %d", completion)
+	}
+}
+
+func TestGuardrailStreamBlock(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAIStream(w)
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxyWithConfig(t, guardedConfig(upstream.URL, guardModeBlock, cardGuard))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gpt-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	s := readChatStream(t, w.Body)
+	if s.chunks != 0 || !strings.Contains(s.errMsg, "blocked by guardrail card") || !s.done {
+		t.Errorf("expected blocked stream without content, got %+v", s)
+	}
+}
+
+func TestGuardrailStreamLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAIStream(w)
+	}))
+	defer upstream.Close()
+
+	// 超出缓存上限: block/redact 模式拦截, log 模式放弃检查直接透传
+	for _, mode := range []string{guardModeBlock, guardModeRedact, guardModeLog} {
+		cfg := guardedConfig(upstream.URL, mode, cardGuard)
+		cfg.Proxy.Guardrails.MaxStreamBytes = 200
+		h, _ := newTestProxyWithConfig(t, cfg)
+		w := postJSON(h, "/v1/chat/completions", `{"model":"gpt-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+		s := readChatStream(t, w.Body)
+		if mode == guardModeLog {
+			if s.content != "card 4111 1111 1111 1111 ok" || s.errMsg != "" || !s.done {
+				t.Errorf("mode=%s: expected pass-through stream, got %+v", mode, s)
+			}
+			continue
+		}
+		if s.chunks != 0 || !strings.Contains(s.errMsg, "blocked by guardrail "+guardStreamLimit) || !s.done {
+			t.Errorf("mode=%s: expected stream blocked by size limit, got %+v", mode, s)
+		}
+	}
+}
+
+func TestGuardrailStreamWebhook(t *testing.T) {
+	var calls atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req webhookGuardRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		flagged := false
+		for _, text := range req.Texts {
+			flagged = flagged || strings.Contains(text, "4111 1111 1111 1111")
+		}
+		json.NewEncoder(w).Encode(webhookGuardResponse{Flagged: flagged, Reason: "pan"})
+	}))
+	defer webhook.Close()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAIStream(w)
+	}))
+	defer upstream.Close()
+
+	cfg := guardedConfig(upstream.URL, guardModeBlock, config.GuardrailGuard{
+		Name: "compliance", Type: guardWebhook, Stage: stageResponse, URL: webhook.URL,
+	})
+	h, _ := newTestProxyWithConfig(t, cfg)
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gpt-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	s := readChatStream(t, w.Body)
+	if calls.Load() != 1 || s.chunks != 0 || !strings.Contains(s.errMsg, "compliance") {
+		t.Errorf("expected webhook to block the stream, calls=%d stream=%+v", calls.Load(), s)
+	}
+}
+
+func TestGuardrailToolCallArguments(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"c2","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":null,
+			"tool_calls":[{"id":"call_1","type":"function","function":{"name":"pay","arguments":"{\"card\":\"4111 1111 1111 1111\"}"}}]},
+			"finish_reason":"tool_calls"}]}`)
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxyWithConfig(t, guardedConfig(upstream.URL, guardModeRedact, cardGuard))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"gpt-test","messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "4111") || !strings.Contains(w.Body.String(), redactedText) {
+		t.Errorf("expected redacted tool call arguments, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,423 @@
+package proxy
+
+import (
+	usageService "backend/internal/service/usage"
+	virtualKeyService "backend/internal/service/virtual_key"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+func (p *ProxyServer) openAIProxyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Param("path")
+		log.Printf("[PROXY] sub path = %s", path)
+		caller := callerFromContext(c)
+		reg := p.acquireRegistry()
+		defer reg.inflight.Done()
+
+		// 只转发显式定义的接口, 各路由可在配置中单独开关
+		rt := matchRoute(path)
+		if rt == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unsupported path: " + path})
+			return
+		}
+		if _, ok := rt.bodies[c.Request.Method]; !ok {
+			c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method not allowed: " + c.Request.Method + " " + path})
+			return
+		}
+		policy := reg.cfg.Routes[rt.name]
+		if !rt.enabled(policy) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "route disabled: " + rt.name})
+			return
+		}
+
+		// 特殊处理models路由, 返回本地代理模型
+		if rt.name == "models" {
+			now := time.Now().Unix()
+
+			// /models/{id} 返回单个模型, 未配置或调用方无权使用时与 OpenAI 一样返回 404
+			if id, ok := strings.CutPrefix(path, rt.path+"/"); ok {
+				if _, exists := reg.clients[id]; !exists || !caller.allowModel(id) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "unknown model: " + id})
+					return
+				}
+				c.JSON(http.StatusOK, OpenAIModel{ID: id, Object: "model", Created: now, OwnedBy: "proxy"})
+				return
+			}
+
+			data := make([]OpenAIModel, 0, len(reg.clients))
+			for model := range reg.clients {
+				if !caller.allowModel(model) {
+					continue
+				}
+				data = append(data, OpenAIModel{
+					ID:      model,
+					Object:  "model",
+					Created: now,
+					OwnedBy: "proxy",
+				})
+			}
+
+			c.JSON(200, OpenAIModelList{
+				Object: "list",
+				Data:   data,
+			})
+			return
+		}
+
+		// 读取 body
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 按路由的请求体格式解析model, stream
+		payload, err := rt.parse(c.Request.Method, c.GetHeader("Content-Type"), bodyBytes, policy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			log.Printf("[ERROR] route=%s: %s", rt.name, err.Error())
+			return
+		}
+
+		// 请求结束时记录指标, 未知模型归为 unknown, 避免任意模型名撑爆标签
+		endpoint := ""
+		defer func() {
+			model := payload.Model
+			if _, ok := reg.clients[model]; !ok {
+				model = "unknown"
+			}
+			p.metrics.observeRequest(model, endpoint, payload.Stream, c.Writer.Status(), time.Since(start))
+		}()
+
+		if !caller.allowModel(payload.Model) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "model not allowed for this api key: " + payload.Model,
+			})
+			return
+		}
+
+		// 内容检查, 脱敏后的请求体用于后续缓存、抓取和转发
+		if payload.kind == bodyJSON {
+			guarded, err := reg.guards.guardJSON(c.Request.Context(), stageRequest, payload.Model, bodyBytes)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			bodyBytes = guarded
+		}
+
+		// 响应缓存, 命中时不访问上游、不计费也不占用限流额度
+		var cacheKeyHash string
+		if p.cache != nil {
+			if cacheBypassed(c.Request.Header) {
+				c.Header(cacheHeader, cacheBypass)
+			} else if key, ok := cacheKey(path, bodyBytes); ok {
+				if entry, hit := p.cache.get(key); hit {
+					endpoint = endpointCache
+					p.serveCached(c, entry)
+					return
+				}
+				cacheKeyHash = key
+				c.Header(cacheHeader, cacheMiss)
+			}
+		}
+
+		// 预算检查
+		if err := p.usageService.CheckBudget(c.Request.Context(), caller.userID, caller.username, caller.department); err != nil {
+			var budgetErr *usageService.BudgetError
+			if errors.As(err, &budgetErr) {
+				c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+				log.Printf("[PROXY] %s", err.Error())
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 虚拟 key 累计花费上限
+		if caller.virtualKey != nil {
+			if err := p.virtualKeyService.CheckSpend(c.Request.Context(), caller.virtualKey); err != nil {
+				var limitErr *virtualKeyService.SpendLimitError
+				if errors.As(err, &limitErr) {
+					c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+					log.Printf("[PROXY] %s", err.Error())
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// 按调用方+模型限流
+		var decision *rateDecision
+		subject := caller.subject()
+		estTokens := estimateTokens(payload.estimateBody(bodyBytes), max(payload.MaxTokens, payload.MaxCompletionTokens))
+		if reg.cfg.RateLimits.Enabled {
+			if tier, ok := p.limiter.tier(reg.cfg, caller); ok {
+				decision = p.limiter.allow(subject, payload.Model, tier, estTokens)
+				writeRateLimitHeaders(c, decision)
+				if !decision.allowed {
+					c.JSON(http.StatusTooManyRequests, gin.H{
+						"error": fmt.Sprintf("rate limit exceeded for %s on model %s, retry after %s", subject, payload.Model, formatReset(decision.retryAfter)),
+					})
+					return
+				}
+			}
+		}
+
+		// 并发控制, 打满时按优先级排队
+		pool := p.poolFor(rt, payload.Stream)
+		prio := caller.priority(c.GetHeader("X-Proxy-Priority"))
+		maxWait, _ := time.ParseDuration(c.GetHeader("X-Proxy-Queue-Timeout"))
+		queueStart := time.Now()
+		if err := pool.acquire(c.Request.Context(), prio, maxWait); err != nil {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			log.Printf("[PROXY] pool=%s priority=%s: %s", pool.name, prio, err.Error())
+			return
+		}
+		defer pool.release()
+		if wait := time.Since(queueStart); wait > 10*time.Millisecond {
+			log.Printf("[PROXY] pool=%s priority=%s queued %s", pool.name, prio, wait)
+		}
+
+		clients, ok := reg.clients[payload.Model]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "unknown model: " + payload.Model,
+			})
+			log.Printf("[ERROR] unknown model: %s", payload.Model)
+			return
+		}
+		// 轮询获取客户端, 文件等有状态接口固定使用第一个上游
+		client := clients[0]
+		if !rt.modelless {
+			client = pickClient(payload.Model, clients)
+		}
+		endpoint = client.name
+		log.Printf(
+			"[PROXY] client: %s base: %s; model name = %s; stream = %v",
+			client.name,
+			client.apiBase,
+			payload.Model,
+			payload.Stream,
+		)
+
+		// 上游请求与客户端连接绑定, 客户端断开或超时时取消, 及时释放并发额度
+		deadline := newUpstreamDeadline(c.Request.Context(), timeoutsFor(reg.cfg, payload.Model))
+		defer deadline.stop()
+
+		// 按上游协议构造转发请求
+		req, err := client.adapter.newRequest(deadline.ctx, c, client, path, bodyBytes, payload.Stream)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errUnsupportedPath) || errors.Is(err, errInvalidRequest) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			log.Printf("[ERROR] %s", err.Error())
+			return
+		}
+		client.headers.forwardRequest(req.Header, c.Request.Header)
+
+		// 按采样记录请求/响应, 响应结束后落库
+		session := p.beginCapture(c, caller, path, payload.Model, payload.Stream, bodyBytes)
+		statusCode := http.StatusBadGateway
+		if session != nil {
+			session.upstream(req, client.name)
+			defer func() { p.finishCapture(session, statusCode) }()
+		}
+
+		// 转发
+		resp, err := client.client.Do(req)
+		if err != nil {
+			status, msg := http.StatusBadGateway, err.Error()
+			if timeoutErr := deadline.timeout(); timeoutErr != nil {
+				status, msg = http.StatusGatewayTimeout, timeoutErr.Error()
+			} else if c.Request.Context().Err() != nil {
+				log.Printf("[PROXY] client disconnected before upstream responded, model=%s endpoint=%s", payload.Model, client.name)
+			}
+			statusCode = status
+			if session != nil {
+				session.response.Write([]byte(msg))
+			}
+			c.JSON(status, gin.H{"error": msg})
+			log.Printf("[ERROR] model=%s endpoint=%s: %s", payload.Model, client.name, err.Error())
+			return
+		}
+		defer resp.Body.Close()
+		statusCode = resp.StatusCode
+
+		// 按规则复制响应头, 限流头以代理自身为准
+		client.headers.forwardResponse(c.Writer.Header(), resp.Header)
+		if decision != nil {
+			writeRateLimitHeaders(c, decision)
+		}
+
+		recorder := &usageRecorder{}
+		// 记录用量, 并用实际 token 修正限流预估
+		settle := func(stream bool) float64 {
+			if decision != nil && recorder.usage != nil {
+				p.limiter.adjust(subject, payload.Model, recorder.usage.total()-estTokens)
+			}
+			p.metrics.observeTokens(payload.Model, client.name, recorder.usage)
+			return p.recordUsage(caller, payload.Model, reg.models[payload.Model].Price, client.name, stream, resp.StatusCode, recorder.usage)
+		}
+
+		// 上游返回错误时不会产生 SSE, 按普通响应转换
+		if payload.Stream && resp.StatusCode < http.StatusBadRequest {
+			flusher, ok := c.Writer.(http.Flusher)
+			if !ok {
+				c.JSON(500, gin.H{"error": "stream not supported"})
+				return
+			}
+
+			// SSE 响应头必须在 WriteHeader 之前设置
+			header := c.Writer.Header()
+			header.Del("Content-Length")
+			header.Set("Content-Type", "text/event-stream")
+			header.Set("Cache-Control", "no-cache")
+			header.Set("Connection", "keep-alive")
+			header.Set("X-Accel-Buffering", "no")
+			c.Writer.WriteHeader(resp.StatusCode)
+			flusher.Flush()
+
+			defer settle(true)
+
+			sw := &streamWriter{
+				w:        c.Writer,
+				flusher:  flusher,
+				recorder: recorder,
+				firstWrite: func() {
+					p.metrics.observeTTFT(payload.Model, client.name, time.Since(start))
+				},
+			}
+			if cacheKeyHash != "" {
+				sw.capture = &cacheBuffer{limit: p.cache.maxEntryBytes}
+			}
+			if session != nil {
+				sw.tap = session.response
+			}
+			// 配置了响应检查的模型缓存整个 stream, 按完整文本检查后再写出, 避免敏感内容拆在多个 delta 中漏检
+			if reg.guards.activeFor(payload.Model, stageResponse) {
+				sw.guard = &streamGuard{ctx: c.Request.Context(), guards: reg.guards, model: payload.Model}
+			}
+
+			// 两次读取之间超过 idle 没有数据时取消上游请求
+			idle := reg.streamIdleTimeout()
+			timer := time.AfterFunc(idle, func() {
+				deadline.cancel(errStreamIdle)
+			})
+			defer timer.Stop()
+
+			out := client.adapter.streamConverter(sw, payload.Model)
+			buf := make([]byte, 4096)
+			for {
+				n, err := resp.Body.Read(buf)
+				timer.Reset(idle)
+				if n > 0 {
+					if _, werr := out.Write(buf[:n]); werr != nil {
+						var violation *guardViolation
+						if errors.As(werr, &violation) {
+							deadline.cancel(werr)
+							sw.fail(violation.Error())
+							return
+						}
+						// client 断开, 取消上游请求
+						deadline.cancel(werr)
+						log.Printf("[PROXY] client disconnected: %s", werr.Error())
+						return
+					}
+				}
+				if err == nil {
+					continue
+				}
+				switch {
+				case c.Request.Context().Err() != nil:
+					log.Printf("[PROXY] client disconnected, upstream stream canceled")
+				case errors.Is(err, io.EOF) && sw.done:
+					// 只缓存以 [DONE] 正常结束的完整 stream
+					if sw.capture != nil && !sw.capture.overflow {
+						p.cache.set(cacheKeyHash, sw.capture.buf.Bytes(), true)
+					}
+				case deadline.timeout() != nil:
+					timeoutErr := deadline.timeout()
+					if errors.Is(timeoutErr, errStreamIdle) {
+						sw.fail(fmt.Sprintf("upstream stream idle for more than %s", idle))
+					} else {
+						sw.fail(timeoutErr.Error())
+					}
+					log.Printf("[ERROR] %s, model=%s endpoint=%s", timeoutErr.Error(), payload.Model, client.name)
+				case errors.Is(err, io.EOF):
+					sw.fail("upstream closed the stream before completion")
+					log.Printf("[ERROR] upstream stream truncated, model=%s endpoint=%s", payload.Model, client.name)
+				default:
+					sw.fail("upstream stream error: " + err.Error())
+					log.Printf("[ERROR] upstream stream error: %s", err.Error())
+				}
+				return
+			}
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			status, msg := http.StatusBadGateway, err.Error()
+			if timeoutErr := deadline.timeout(); timeoutErr != nil {
+				status, msg = http.StatusGatewayTimeout, timeoutErr.Error()
+			}
+			statusCode = status
+			c.Writer.Header().Del("Content-Length")
+			c.JSON(status, gin.H{"error": msg})
+			log.Printf("[ERROR] model=%s endpoint=%s: %s", payload.Model, client.name, err.Error())
+			return
+		}
+		respBody = client.adapter.convertResponse(respBody, payload.Model)
+		recorder.parse(respBody)
+		// 内容检查, 拦截时上游已产生费用, 照常计费
+		if resp.StatusCode < http.StatusBadRequest {
+			guarded, err := reg.guards.guardJSON(c.Request.Context(), stageResponse, payload.Model, respBody)
+			if err != nil {
+				statusCode = http.StatusBadGateway
+				if session != nil {
+					session.response.Write([]byte(err.Error()))
+				}
+				settle(false)
+				c.Writer.Header().Del("Content-Length")
+				c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+				return
+			}
+			respBody = guarded
+		}
+		if session != nil {
+			session.response.Write(respBody)
+		}
+		if cacheKeyHash != "" && resp.StatusCode == http.StatusOK {
+			p.cache.set(cacheKeyHash, respBody, false)
+		}
+		cost := settle(payload.Stream)
+		c.Writer.Header().Del("Content-Length")
+		c.Writer.Header().Set("X-Proxy-Cost", fmt.Sprintf("%.6f", cost))
+		c.Writer.WriteHeader(resp.StatusCode)
+		c.Writer.Write(respBody)
+	}
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"net/http"
+	"strings"
+)
+
+// hopByHopHeaders RFC 7230 6.1 规定的逐跳头, 两个方向都不转发
+var hopByHopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// strippedRequestHeaders 调用方凭证以及由代理/transport 自行处理的请求头, 不受 allow 影响
+var strippedRequestHeaders = []string{
+	"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie",
+	"Host", "Content-Length", "Accept-Encoding",
+}
+
+// 内置 allow 列表, 配置中的规则在此基础上追加
+var (
+	defaultRequestAllow = []string{
+		"Content-Type", "Accept", "User-Agent", "X-Request-Id", "Idempotency-Key",
+		"OpenAI-Beta", "X-Stainless-*",
+	}
+	defaultResponseAllow = []string{
+		"Content-Type", "Retry-After", "X-Request-Id", "Request-Id",
+		"OpenAI-Processing-Ms", "OpenAI-Version",
+	}
+)
+
+type headerFilter struct {
+	allow []string
+	deny  []string
+}
+
+func newHeaderFilter(defaults []string, policies ...config.HeaderPolicy) headerFilter {
+	f := headerFilter{allow: lowerAll(defaults)}
+	for _, p := range policies {
+		f.allow = append(f.allow, lowerAll(p.Allow)...)
+		f.deny = append(f.deny, lowerAll(p.Deny)...)
+	}
+	return f
+}
+
+func (f headerFilter) allowed(name string) bool {
+	name = strings.ToLower(name)
+	return matchHeader(f.allow, name) && !matchHeader(f.deny, name)
+}
+
+func matchHeader(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// headerPolicy 一个上游服务的请求/响应头转发规则及附加请求头
+type headerPolicy struct {
+	request  headerFilter
+	response headerFilter
+	extra    map[string]string
+}
+
+// newHeaderPolicy 全局规则 + 协议类型规则 + 服务名规则依次叠加
+func newHeaderPolicy(cfg config.Proxy, name, kind string, extra map[string]string) *headerPolicy {
+	rules := []config.HeaderRules{cfg.Headers.HeaderRules}
+	for _, key := range []string{kind, name} {
+		if r, ok := cfg.Headers.Providers[strings.ToLower(key)]; ok {
+			rules = append(rules, r)
+		}
+	}
+	var req, resp []config.HeaderPolicy
+	for _, r := range rules {
+		req = append(req, r.Request)
+		resp = append(resp, r.Response)
+	}
+	return &headerPolicy{
+		request:  newHeaderFilter(defaultRequestAllow, req...),
+		response: newHeaderFilter(defaultResponseAllow, resp...),
+		extra:    extra,
+	}
+}
+
+// forwardRequest 将调用方请求头按规则复制到上游请求, adapter 已设置的头不覆盖, 最后注入附加请求头
+func (hp *headerPolicy) forwardRequest(dst, src http.Header) {
+	src = src.Clone()
+	removeHopByHop(src)
+	for _, k := range strippedRequestHeaders {
+		src.Del(k)
+	}
+	for k, v := range src {
+		if _, exists := dst[k]; exists || !hp.request.allowed(k) {
+			continue
+		}
+		dst[k] = v
+	}
+	for k, v := range hp.extra {
+		dst.Set(k, v)
+	}
+	removeHopByHop(dst)
+}
+
+// forwardResponse 将上游响应头按规则复制给调用方
+func (hp *headerPolicy) forwardResponse(dst, src http.Header) {
+	src = src.Clone()
+	removeHopByHop(src)
+	for k, v := range src {
+		if hp.response.allowed(k) {
+			dst[k] = v
+		}
+	}
+}
+
+// removeHopByHop 删除逐跳头以及 Connection 中列出的头
+func removeHopByHop(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, k := range hopByHopHeaders {
+		h.Del(k)
+	}
+}
+
+func lowerAll(list []string) []string {
+	res := make([]string, 0, len(list))
+	for _, s := range list {
+		res = append(res, strings.ToLower(strings.TrimSpace(s)))
+	}
+	return res
+}
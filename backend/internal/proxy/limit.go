@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("too many requests, queue is full")
+	ErrQueueTimeout = errors.New("too many requests, queue wait timed out")
+)
+
+// priority 排队优先级, 数值越小越先出队
+type priority int
+
+const (
+	priorityInteractive priority = iota
+	priorityBatch
+	priorityCount
+)
+
+func (p priority) String() string {
+	if p == priorityInteractive {
+		return "interactive"
+	}
+	return "batch"
+}
+
+// parsePriority 解析 X-Proxy-Priority, 默认 batch
+func parsePriority(v string) priority {
+	if strings.EqualFold(strings.TrimSpace(v), "interactive") {
+		return priorityInteractive
+	}
+	return priorityBatch
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// concurrencyPool 并发信号量, 打满后按优先级排队等待
+type concurrencyPool struct {
+	mu       sync.Mutex
+	name     string
+	capacity int
+	inUse    int
+	queue    bool
+	maxQueue int
+	timeout  time.Duration
+	waiters  [priorityCount]*list.List
+
+	// 累计指标
+	queuedTotal   uint64
+	rejectedTotal uint64
+	timeoutTotal  uint64
+}
+
+// PoolStats 并发池及排队队列状态
+type PoolStats struct {
+	Name          string         `json:"name"`
+	Capacity      int            `json:"capacity"`
+	InUse         int            `json:"inUse"`
+	MaxQueue      int            `json:"maxQueue"`
+	Queued        map[string]int `json:"queued"`
+	QueuedTotal   uint64         `json:"queuedTotal"`
+	RejectedTotal uint64         `json:"rejectedTotal"`
+	TimeoutTotal  uint64         `json:"timeoutTotal"`
+}
+
+func newConcurrencyPool(name string, capacity int, cfg config.Proxy) *concurrencyPool {
+	p := &concurrencyPool{
+		name:     name,
+		capacity: capacity,
+		queue:    cfg.Limits.Queue.Enabled,
+		maxQueue: cfg.Limits.Queue.MaxSize,
+		timeout:  cfg.Limits.Queue.Timeout,
+	}
+	// 兜底值
+	if p.maxQueue <= 0 {
+		p.maxQueue = capacity
+	}
+	if p.timeout <= 0 {
+		p.timeout = 30 * time.Second
+	}
+	for i := range p.waiters {
+		p.waiters[i] = list.New()
+	}
+	return p
+}
+
+func (p *concurrencyPool) queuedLocked() int {
+	n := 0
+	for _, l := range p.waiters {
+		n += l.Len()
+	}
+	return n
+}
+
+// acquire 获取并发槽位; 队列开启时最多等待 min(maxWait, 配置超时), maxWait<=0 表示使用配置超时
+func (p *concurrencyPool) acquire(ctx context.Context, prio priority, maxWait time.Duration) error {
+	p.mu.Lock()
+	if p.inUse < p.capacity && p.queuedLocked() == 0 {
+		p.inUse++
+		p.mu.Unlock()
+		return nil
+	}
+	if !p.queue || p.queuedLocked() >= p.maxQueue {
+		p.rejectedTotal++
+		p.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	elem := p.waiters[prio].PushBack(w)
+	p.queuedTotal++
+	p.mu.Unlock()
+
+	wait := p.timeout
+	if maxWait > 0 && maxWait < wait {
+		wait = maxWait
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 超时与出队同时发生时, 槽位已经转交给当前请求
+	if w.granted {
+		return nil
+	}
+	p.waiters[prio].Remove(elem)
+	p.timeoutTotal++
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrQueueTimeout
+}
+
+// release 释放槽位, 有排队请求时直接转交给优先级最高的等待者
+func (p *concurrencyPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, l := range p.waiters {
+		if front := l.Front(); front != nil {
+			w := l.Remove(front).(*waiter)
+			w.granted = true
+			close(w.ready)
+			return
+		}
+	}
+	p.inUse--
+}
+
+func (p *concurrencyPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queued := make(map[string]int, priorityCount)
+	for i, l := range p.waiters {
+		queued[priority(i).String()] = l.Len()
+	}
+	return PoolStats{
+		Name:          p.name,
+		Capacity:      p.capacity,
+		InUse:         p.inUse,
+		MaxQueue:      p.maxQueue,
+		Queued:        queued,
+		QueuedTotal:   p.queuedTotal,
+		RejectedTotal: p.rejectedTotal,
+		TimeoutTotal:  p.timeoutTotal,
+	}
+}
+
+func initPools(cfg config.Proxy) (nonStreamPool, streamPool *concurrencyPool) {
+	nonStream := cfg.Limits.NonStreamConcurrency
+	stream := cfg.Limits.StreamConcurrency
+
+	// 兜底值，防止配置写 0 把服务搞挂
+	if nonStream <= 0 {
+		nonStream = 300
+	}
+	if stream <= 0 {
+		stream = 50
+	}
+
+	nonStreamPool = newConcurrencyPool("non_stream", nonStream, cfg)
+	streamPool = newConcurrencyPool("stream", stream, cfg)
+
+	log.Printf(
+		"[init] concurrency pools initialized nonStream=%d stream=%d queue=%v",
+		nonStream, stream, cfg.Limits.Queue.Enabled,
+	)
+	return
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+const metricsNamespace = "prompt_proxy"
+
+// endpointCache 缓存命中的请求不经过上游, endpoint 标签记为 cache
+const endpointCache = "cache"
+
+// proxyMetrics 代理的 Prometheus 指标, 使用独立的 registry 避免与其他组件冲突
+type proxyMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	ttft     *prometheus.HistogramVec
+	tokens   *prometheus.CounterVec
+}
+
+func newProxyMetrics(p *ProxyServer) *proxyMetrics {
+	m := &proxyMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Proxied requests by model, upstream endpoint and response status code.",
+		}, []string{"model", "endpoint", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time from receiving a request to finishing its response.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 60, 120, 300},
+		}, []string{"model", "endpoint", "stream"}),
+		ttft: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "stream_time_to_first_token_seconds",
+			Help:      "Time from receiving a stream request to writing its first event.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30},
+		}, []string{"model", "endpoint"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tokens_total",
+			Help:      "Tokens reported by upstream usage, by type (prompt or completion).",
+		}, []string{"model", "endpoint", "type"}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.latency, m.ttft, m.tokens,
+		&poolCollector{p: p},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+func (m *proxyMetrics) handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// observeRequest 请求结束时调用, 未开启指标时 m 为 nil
+func (m *proxyMetrics) observeRequest(model, endpoint string, stream bool, code int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(model, endpoint, strconv.Itoa(code)).Inc()
+	m.latency.WithLabelValues(model, endpoint, strconv.FormatBool(stream)).Observe(elapsed.Seconds())
+}
+
+func (m *proxyMetrics) observeTTFT(model, endpoint string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.ttft.WithLabelValues(model, endpoint).Observe(elapsed.Seconds())
+}
+
+func (m *proxyMetrics) observeTokens(model, endpoint string, usage *tokenUsage) {
+	if m == nil || usage == nil {
+		return
+	}
+	m.tokens.WithLabelValues(model, endpoint, "prompt").Add(float64(usage.PromptTokens))
+	m.tokens.WithLabelValues(model, endpoint, "completion").Add(float64(usage.CompletionTokens))
+}
+
+// poolCollector 采集时读取并发池与响应缓存的实时状态
+type poolCollector struct {
+	p *ProxyServer
+}
+
+var (
+	poolInUseDesc = prometheus.NewDesc(metricsNamespace+"_pool_in_flight",
+		"Requests currently holding a concurrency slot.", []string{"pool"}, nil)
+	poolCapacityDesc = prometheus.NewDesc(metricsNamespace+"_pool_capacity",
+		"Concurrency slots of the pool.", []string{"pool"}, nil)
+	poolQueuedDesc = prometheus.NewDesc(metricsNamespace+"_pool_queued",
+		"Requests waiting for a slot, by priority.", []string{"pool", "priority"}, nil)
+	poolRejectedDesc = prometheus.NewDesc(metricsNamespace+"_pool_rejected_total",
+		"Requests rejected because the queue was full.", []string{"pool"}, nil)
+	poolTimeoutDesc = prometheus.NewDesc(metricsNamespace+"_pool_queue_timeouts_total",
+		"Requests that timed out while queued.", []string{"pool"}, nil)
+	cacheHitsDesc = prometheus.NewDesc(metricsNamespace+"_cache_hits_total",
+		"Response cache hits.", nil, nil)
+	cacheMissesDesc = prometheus.NewDesc(metricsNamespace+"_cache_misses_total",
+		"Response cache misses.", nil, nil)
+	cacheBytesDesc = prometheus.NewDesc(metricsNamespace+"_cache_bytes",
+		"Bytes held by the response cache.", nil, nil)
+)
+
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		poolInUseDesc, poolCapacityDesc, poolQueuedDesc, poolRejectedDesc, poolTimeoutDesc,
+		cacheHitsDesc, cacheMissesDesc, cacheBytesDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, pool := range pc.p.allPools() {
+		if pool == nil {
+			continue
+		}
+		s := pool.stats()
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(s.InUse), s.Name)
+		ch <- prometheus.MustNewConstMetric(poolCapacityDesc, prometheus.GaugeValue, float64(s.Capacity), s.Name)
+		for prio, n := range s.Queued {
+			ch <- prometheus.MustNewConstMetric(poolQueuedDesc, prometheus.GaugeValue, float64(n), s.Name, prio)
+		}
+		ch <- prometheus.MustNewConstMetric(poolRejectedDesc, prometheus.CounterValue, float64(s.RejectedTotal), s.Name)
+		ch <- prometheus.MustNewConstMetric(poolTimeoutDesc, prometheus.CounterValue, float64(s.TimeoutTotal), s.Name)
+	}
+	if pc.p.cache != nil {
+		s := pc.p.cache.stats()
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(s.Hits))
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(s.Misses))
+		ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(s.Bytes))
+	}
+}
@@ -0,0 +1,304 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ollamaAdapter Ollama 原生 /api/chat, stream 响应为逐行 JSON
+type ollamaAdapter struct{}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	// Ollama 默认 stream, 必须显式传递
+	Stream  bool           `json:"stream"`
+	Tools   []chatTool     `json:"tools,omitempty"`
+	Options *ollamaOptions `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int64    `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (r *ollamaResponse) usage() *tokenUsage {
+	return &tokenUsage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func (ollamaAdapter) newRequest(ctx context.Context, c *gin.Context, client *APIClient, path string, body []byte, stream bool) (*http.Request, error) {
+	if path != "/chat/completions" {
+		return nil, errUnsupportedPath
+	}
+	var in chatRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidRequest, err.Error())
+	}
+	out, err := toOllamaRequest(&in)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.apiBase+"/api/chat", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 本地 Ollama 不需要 key, 部署在鉴权网关后时才携带
+	if client.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+client.apiKey)
+	}
+	return req, nil
+}
+
+func toOllamaRequest(in *chatRequest) (*ollamaRequest, error) {
+	out := &ollamaRequest{
+		Model:  in.Model,
+		Stream: in.Stream,
+		Tools:  in.Tools,
+	}
+	opts := &ollamaOptions{
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+		NumPredict:  in.maxTokens(),
+		Stop:        in.stopSequences(),
+	}
+	if opts.Temperature != nil || opts.TopP != nil || opts.NumPredict > 0 || len(opts.Stop) > 0 {
+		out.Options = opts
+	}
+	if mode, _ := parseToolChoice(in.ToolChoice); mode == "none" {
+		out.Tools = nil
+	}
+
+	for i := range in.Messages {
+		m := &in.Messages[i]
+		role := m.Role
+		if role == "developer" {
+			role = "system"
+		}
+		msg := ollamaMessage{Role: role}
+
+		parts, err := m.parts()
+		if err != nil {
+			return nil, err
+		}
+		var text strings.Builder
+		for _, p := range parts {
+			switch p.Type {
+			case "text":
+				text.WriteString(p.Text)
+			case "image_url":
+				if p.ImageURL == nil {
+					continue
+				}
+				_, data, ok := parseDataURL(p.ImageURL.URL)
+				if !ok {
+					return nil, fmt.Errorf("%w: ollama only supports base64 data URL images", errInvalidRequest)
+				}
+				msg.Images = append(msg.Images, data)
+			}
+		}
+		msg.Content = text.String()
+
+		for _, tc := range m.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	return out, nil
+}
+
+func (ollamaAdapter) convertResponse(body []byte, model string) []byte {
+	var in ollamaResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return body
+	}
+	if in.Error != "" {
+		return chatError(in.Error, "api_error")
+	}
+
+	msg := &chatOutMessage{Role: "assistant"}
+	for i, tc := range in.Message.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, ollamaToolCallOut(tc, i))
+	}
+	if in.Message.Content != "" || len(msg.ToolCalls) == 0 {
+		content := in.Message.Content
+		msg.Content = &content
+	}
+
+	finish := ollamaFinishReason(in.DoneReason, len(msg.ToolCalls) > 0)
+	out := newCompletion("", model, "chat.completion")
+	out.Choices = append(out.Choices, chatChoice{Message: msg, FinishReason: &finish})
+	out.Usage = in.usage()
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// ollamaToolCallOut Ollama 的工具调用没有 id, 按下标生成
+func ollamaToolCallOut(tc ollamaToolCall, idx int) chatToolCall {
+	args := string(tc.Function.Arguments)
+	if args == "" {
+		args = "{}"
+	}
+	return chatToolCall{
+		ID:       fmt.Sprintf("call_%d", idx),
+		Type:     "function",
+		Function: chatFunctionCall{Name: tc.Function.Name, Arguments: args},
+	}
+}
+
+func ollamaFinishReason(reason string, toolCalls bool) string {
+	if reason == "length" {
+		return "length"
+	}
+	if toolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func (ollamaAdapter) streamConverter(w io.Writer, model string) io.Writer {
+	return &ollamaStream{w: w, model: model}
+}
+
+// ollamaStream 将逐行 JSON 转换为 OpenAI chunk
+type ollamaStream struct {
+	w       io.Writer
+	model   string
+	pending []byte
+	chunk   *chatCompletion
+	tools   int
+	done    bool
+}
+
+func (s *ollamaStream) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	for {
+		idx := bytes.IndexByte(s.pending, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		line := bytes.TrimSpace(s.pending[:idx])
+		s.pending = s.pending[idx+1:]
+		if len(line) == 0 {
+			continue
+		}
+		if err := s.handle(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (s *ollamaStream) handle(line []byte) error {
+	if s.done {
+		return nil
+	}
+	var in ollamaResponse
+	if err := json.Unmarshal(line, &in); err != nil {
+		return nil
+	}
+	if in.Error != "" {
+		s.done = true
+		if _, err := fmt.Fprintf(s.w, "data: %s\n\n", chatError(in.Error, "api_error")); err != nil {
+			return err
+		}
+		return writeSSEDone(s.w)
+	}
+
+	if s.chunk == nil {
+		s.chunk = newCompletion("", s.model, "chat.completion.chunk")
+		empty := ""
+		if err := s.emit(&chatOutMessage{Role: "assistant", Content: &empty}, nil); err != nil {
+			return err
+		}
+	}
+
+	delta := &chatOutMessage{}
+	if in.Message.Content != "" {
+		content := in.Message.Content
+		delta.Content = &content
+	}
+	for _, tc := range in.Message.ToolCalls {
+		idx := s.tools
+		call := ollamaToolCallOut(tc, idx)
+		call.Index = &idx
+		delta.ToolCalls = append(delta.ToolCalls, call)
+		s.tools++
+	}
+	if delta.Content != nil || len(delta.ToolCalls) > 0 {
+		if err := s.emit(delta, nil); err != nil {
+			return err
+		}
+	}
+	if !in.Done {
+		return nil
+	}
+
+	s.done = true
+	finish := ollamaFinishReason(in.DoneReason, s.tools > 0)
+	if err := s.emit(&chatOutMessage{}, &finish); err != nil {
+		return err
+	}
+	c := *s.chunk
+	c.Choices = make([]chatChoice, 0)
+	c.Usage = in.usage()
+	if err := writeSSEData(s.w, c); err != nil {
+		return err
+	}
+	return writeSSEDone(s.w)
+}
+
+func (s *ollamaStream) emit(delta *chatOutMessage, finish *string) error {
+	c := *s.chunk
+	c.Choices = []chatChoice{{Delta: delta, FinishReason: finish}}
+	return writeSSEData(s.w, c)
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ollamaModels 本地 Ollama 不配置 key
+func ollamaModels(apiBase string) map[string]config.ProxyModel {
+	return map[string]config.ProxyModel{
+		"llama-test": {
+			Endpoints: []config.ProxyEndpoint{{
+				Name:    "ollama",
+				Type:    providerOllama,
+				ApiBase: apiBase,
+			}},
+		},
+	}
+}
+
+func TestOllamaChatCompletion(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected upstream path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "llama-test" || req.Stream {
+			t.Errorf("unexpected model/stream: %q %v", req.Model, req.Stream)
+		}
+		if len(req.Messages) != 4 || req.Messages[0].Role != "system" {
+			t.Fatalf("unexpected messages %+v", req.Messages)
+		}
+		if tc := req.Messages[2].ToolCalls; len(tc) != 1 || string(tc[0].Function.Arguments) != `{"city":"Paris"}` {
+			t.Errorf("unexpected tool calls %+v", tc)
+		}
+		if req.Options == nil || req.Options.NumPredict != 64 || *req.Options.Temperature != 0.2 {
+			t.Errorf("unexpected options %+v", req.Options)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{
+			"model": "llama3",
+			"message": {"role": "assistant", "content": "", "tool_calls": [
+				{"function": {"name": "get_weather", "arguments": {"city": "Rome"}}}
+			]},
+			"done": true, "done_reason": "stop",
+			"prompt_eval_count": 40, "eval_count": 12
+		}`)
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, ollamaModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{
+		"model": "llama-test",
+		"max_tokens": 64,
+		"temperature": 0.2,
+		"messages": [
+			{"role": "developer", "content": "be brief"},
+			{"role": "user", "content": "weather in Paris?"},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_0", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_0", "content": "18C"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]
+	}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp chatCompletion
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "llama-test" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != nil {
+		t.Errorf("expected no content alongside tool calls, got %q", *choice.Message.Content)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "call_0" ||
+		choice.Message.ToolCalls[0].Function.Arguments != `{"city": "Rome"}` {
+		t.Errorf("unexpected tool calls %+v", choice.Message.ToolCalls)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != "tool_calls" {
+		t.Errorf("unexpected finish reason %v", choice.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 40 || resp.Usage.CompletionTokens != 12 || resp.Usage.TotalTokens != 52 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	var prompt, completion int64
+	if err := db.QueryRow(`SELECT prompt_tokens, completion_tokens FROM proxy_usage`).Scan(&prompt, &completion); err != nil {
+		t.Fatal(err)
+	}
+	if prompt != 40 || completion != 12 {
+		t.Errorf("unexpected recorded usage %d/%d", prompt, completion)
+	}
+}
+
+func TestOllamaChatCompletionStream(t *testing.T) {
+	lines := []string{
+		`{"model":"llama3","message":{"role":"assistant","content":"Hel"},"done":false}`,
+		`{"model":"llama3","message":{"role":"assistant","content":"lo"},"done":false}`,
+		`{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":50,"eval_count":30}`,
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Stream {
+			t.Errorf("expected stream request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher := w.(http.Flusher)
+		for _, line := range lines {
+			// 按半行写出, 验证跨读取边界的解析
+			half := len(line) / 2
+			fmt.Fprint(w, line[:half])
+			flusher.Flush()
+			fmt.Fprint(w, line[half:]+"\n")
+			flusher.Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	h, db := newTestProxy(t, ollamaModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"llama-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	s := readChatStream(t, w.Body)
+	if s.content != "Hello" || s.finish != "length" || s.errMsg != "" || !s.done {
+		t.Errorf("unexpected stream %+v", s)
+	}
+	if s.usage == nil || s.usage.PromptTokens != 50 || s.usage.CompletionTokens != 30 {
+		t.Errorf("unexpected usage %+v", s.usage)
+	}
+
+	var completion int64
+	if err := db.QueryRow(`SELECT completion_tokens FROM proxy_usage WHERE stream = 1`).Scan(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion != 30 {
+		t.Errorf("unexpected recorded completion tokens %d", completion)
+	}
+}
+
+func TestOllamaErrorResponse(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"model \"llama-test\" not found, try pulling it first"}`)
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, ollamaModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"llama-test","messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Message != `model "llama-test" not found, try pulling it first` || resp.Error.Type != "api_error" {
+		t.Errorf("unexpected error body %s", w.Body.String())
+	}
+}
+
+func TestOllamaStreamErrorLine(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"Hi"},"done":false}`+"\n")
+		fmt.Fprint(w, `{"error":"out of memory"}`+"\n")
+	}))
+	defer upstream.Close()
+
+	h, _ := newTestProxy(t, ollamaModels(upstream.URL))
+	w := postJSON(h, "/v1/chat/completions", `{"model":"llama-test","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	s := readChatStream(t, w.Body)
+	if s.content != "Hi" || s.errMsg != "out of memory" || !s.done {
+		t.Errorf("expected a single upstream error followed by [DONE], got %+v", s)
+	}
+}
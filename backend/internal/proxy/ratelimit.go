@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 超过该时长未使用的令牌桶会被清理
+const bucketIdleTTL = 10 * time.Minute
+
+// tokenBucket 容量为每分钟额度, 按秒匀速回填
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int64, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait 距离桶内余量达到 n 还需的时间
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// reset 距离桶被填满还需的时间
+func (b *tokenBucket) reset() time.Duration {
+	return time.Duration((b.capacity - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) remaining() int64 {
+	return int64(math.Max(0, math.Floor(b.tokens)))
+}
+
+type limiterEntry struct {
+	// tier 创建令牌桶时的额度, 热加载修改 tier 后按新额度重建
+	tier     config.RateLimitTier
+	requests *tokenBucket
+	tokens   *tokenBucket
+	lastUsed time.Time
+}
+
+// rateDecision 单次限流判定结果, 用于输出 x-ratelimit-* 响应头
+type rateDecision struct {
+	allowed    bool
+	retryAfter time.Duration
+	requests   *bucketState
+	tokens     *bucketState
+}
+
+type bucketState struct {
+	limit     int64
+	remaining int64
+	reset     time.Duration
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*limiterEntry
+	lastGC  time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		entries: make(map[string]*limiterEntry),
+		lastGC:  time.Now(),
+	}
+}
+
+// tier 按 key > 用户 > 默认 的顺序确定调用方所属 tier, cfg 为当前生效的代理配置
+func (l *rateLimiter) tier(cfg config.Proxy, cl *caller) (config.RateLimitTier, bool) {
+	rl := cfg.RateLimits
+	name := rl.DefaultTier
+	if t, ok := rl.Users[strings.ToLower(cl.username)]; ok && cl.userID > 0 {
+		name = t
+	}
+	if t, ok := rl.Keys[strings.ToLower(cl.keyName)]; ok && cl.keyName != "" {
+		name = t
+	}
+	tier, ok := rl.Tiers[strings.ToLower(name)]
+	return tier, ok
+}
+
+// allow 尝试为 subject+model 扣减 1 个请求及 estTokens 个 token, 不足时不扣减
+func (l *rateLimiter) allow(subject, model string, tier config.RateLimitTier, estTokens int64) *rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.gc(now)
+
+	key := subject + "|" + model
+	entry, ok := l.entries[key]
+	if !ok || entry.tier != tier {
+		entry = &limiterEntry{tier: tier}
+		if tier.RequestsPerMinute > 0 {
+			entry.requests = newTokenBucket(tier.RequestsPerMinute, now)
+		}
+		if tier.TokensPerMinute > 0 {
+			entry.tokens = newTokenBucket(tier.TokensPerMinute, now)
+		}
+		l.entries[key] = entry
+	}
+	entry.lastUsed = now
+
+	var wait time.Duration
+	if b := entry.requests; b != nil {
+		b.refill(now)
+		wait = max(wait, b.wait(1))
+	}
+	if b := entry.tokens; b != nil {
+		b.refill(now)
+		// 单次请求超过桶容量时按桶满处理, 避免永远无法通过
+		wait = max(wait, b.wait(math.Min(float64(estTokens), b.capacity)))
+	}
+
+	d := &rateDecision{allowed: wait == 0, retryAfter: wait}
+	if d.allowed {
+		if b := entry.requests; b != nil {
+			b.tokens--
+		}
+		if b := entry.tokens; b != nil {
+			b.tokens -= float64(estTokens)
+		}
+	}
+	if b := entry.requests; b != nil {
+		d.requests = &bucketState{limit: int64(b.capacity), remaining: b.remaining(), reset: b.reset()}
+	}
+	if b := entry.tokens; b != nil {
+		d.tokens = &bucketState{limit: int64(b.capacity), remaining: b.remaining(), reset: b.reset()}
+	}
+	return d
+}
+
+// adjust 请求结束后用实际 token 用量修正预估值, delta 为 实际-预估
+func (l *rateLimiter) adjust(subject, model string, delta int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[subject+"|"+model]
+	if !ok || entry.tokens == nil {
+		return
+	}
+	entry.tokens.refill(time.Now())
+	entry.tokens.tokens = math.Min(entry.tokens.capacity, entry.tokens.tokens-float64(delta))
+}
+
+func (l *rateLimiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < bucketIdleTTL {
+		return
+	}
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastUsed) > bucketIdleTTL {
+			delete(l.entries, key)
+		}
+	}
+	l.lastGC = now
+}
+
+// estimateTokens 粗略预估请求 token: 请求体按 4 字节/token 估算, 加上 max_tokens
+func estimateTokens(body []byte, maxTokens int64) int64 {
+	return int64(len(body))/4 + maxTokens
+}
+
+func writeRateLimitHeaders(c *gin.Context, d *rateDecision) {
+	h := c.Writer.Header()
+	if s := d.requests; s != nil {
+		h.Set("x-ratelimit-limit-requests", strconv.FormatInt(s.limit, 10))
+		h.Set("x-ratelimit-remaining-requests", strconv.FormatInt(s.remaining, 10))
+		h.Set("x-ratelimit-reset-requests", formatReset(s.reset))
+	}
+	if s := d.tokens; s != nil {
+		h.Set("x-ratelimit-limit-tokens", strconv.FormatInt(s.limit, 10))
+		h.Set("x-ratelimit-remaining-tokens", strconv.FormatInt(s.remaining, 10))
+		h.Set("x-ratelimit-reset-tokens", formatReset(s.reset))
+	}
+	if !d.allowed {
+		h.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(d.retryAfter.Seconds())), 10))
+	}
+}
+
+// formatReset 与 OpenAI 保持一致, 如 "1s", "6m0s", "250ms"
+func formatReset(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// bodyKind 路由的请求体格式
+type bodyKind int
+
+const (
+	bodyNone bodyKind = iota
+	bodyJSON
+	bodyMultipart
+)
+
+var (
+	errMissingModel    = errors.New("missing model in request body")
+	errInvalidBody     = errors.New("invalid request body")
+	errRouteNoUpstream = errors.New("route has no upstream model configured")
+)
+
+// proxyRoute 一个 OpenAI 接口的转发策略, name 相同的路由共用一份配置
+type proxyRoute struct {
+	name string
+	path string
+	// prefix 为 true 时匹配 path 及其子路径, 如 /files/{id}/content
+	prefix bool
+	// bodies 允许的请求方法及对应的请求体格式
+	bodies map[string]bodyKind
+	stream bool
+	// modelless 请求中没有 model, 按配置的模型选择上游, 且固定使用第一个上游(文件等资源只存在于上传时的上游)
+	modelless bool
+	// disabled 默认关闭, 需在配置中显式开启
+	disabled bool
+}
+
+var proxyRoutes = []*proxyRoute{
+	// 页面的模型列表接口(POST /api/v1/prompt/models)以 POST 转发到这里
+	{name: "models", path: "/models", prefix: true, bodies: map[string]bodyKind{http.MethodGet: bodyNone, http.MethodPost: bodyNone}},
+	{name: "chat", path: "/chat/completions", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}, stream: true},
+	{name: "completions", path: "/completions", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}, stream: true},
+	{name: "embeddings", path: "/embeddings", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}},
+	{name: "moderations", path: "/moderations", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}},
+	{name: "images", path: "/images/generations", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}},
+	{name: "images", path: "/images/edits", bodies: map[string]bodyKind{http.MethodPost: bodyMultipart}},
+	{name: "images", path: "/images/variations", bodies: map[string]bodyKind{http.MethodPost: bodyMultipart}},
+	{name: "audio", path: "/audio/speech", bodies: map[string]bodyKind{http.MethodPost: bodyJSON}},
+	{name: "audio", path: "/audio/transcriptions", bodies: map[string]bodyKind{http.MethodPost: bodyMultipart}},
+	{name: "audio", path: "/audio/translations", bodies: map[string]bodyKind{http.MethodPost: bodyMultipart}},
+	{
+		name: "files", path: "/files", prefix: true, modelless: true, disabled: true,
+		bodies: map[string]bodyKind{http.MethodGet: bodyNone, http.MethodPost: bodyMultipart, http.MethodDelete: bodyNone},
+	},
+	{
+		name: "batches", path: "/batches", prefix: true, modelless: true, disabled: true,
+		bodies: map[string]bodyKind{http.MethodGet: bodyNone, http.MethodPost: bodyJSON},
+	},
+}
+
+// matchRoute 未定义的接口返回 nil
+func matchRoute(path string) *proxyRoute {
+	for _, rt := range proxyRoutes {
+		if path == rt.path || (rt.prefix && strings.HasPrefix(path, rt.path+"/")) {
+			return rt
+		}
+	}
+	return nil
+}
+
+func (rt *proxyRoute) enabled(policy config.ProxyRoute) bool {
+	if policy.Enabled != nil {
+		return *policy.Enabled
+	}
+	return !rt.disabled
+}
+
+// requestPayload 从请求体中解析出的路由所需字段
+type requestPayload struct {
+	Model               string `json:"model"`
+	Stream              bool   `json:"stream"`
+	MaxTokens           int64  `json:"max_tokens"`
+	MaxCompletionTokens int64  `json:"max_completion_tokens"`
+	kind                bodyKind
+}
+
+// parse 按路由的请求体格式解析 model / stream
+func (rt *proxyRoute) parse(method, contentType string, body []byte, policy config.ProxyRoute) (*requestPayload, error) {
+	payload := &requestPayload{kind: rt.bodies[method]}
+	switch payload.kind {
+	case bodyJSON:
+		if err := json.Unmarshal(body, payload); err != nil {
+			return nil, errInvalidBody
+		}
+	case bodyMultipart:
+		if err := parseMultipartFields(contentType, body, payload); err != nil {
+			return nil, err
+		}
+	}
+	if !rt.stream {
+		payload.Stream = false
+	}
+
+	if rt.modelless {
+		if policy.Model == "" {
+			return nil, errRouteNoUpstream
+		}
+		payload.Model = policy.Model
+	}
+	if payload.Model == "" {
+		return nil, errMissingModel
+	}
+	return payload, nil
+}
+
+// estimateBody 只有 JSON 请求体按长度估算 token, 文件上传不计入
+func (r *requestPayload) estimateBody(body []byte) []byte {
+	if r.kind != bodyJSON {
+		return nil
+	}
+	return body
+}
+
+// parseMultipartFields 从 multipart 表单中读取 model / stream 字段, 文件内容跳过
+func parseMultipartFields(contentType string, body []byte, payload *requestPayload) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return errInvalidBody
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errInvalidBody
+		}
+		if part.FileName() != "" {
+			continue
+		}
+		switch part.FormName() {
+		case "model":
+			v, _ := io.ReadAll(io.LimitReader(part, 256))
+			payload.Model = strings.TrimSpace(string(v))
+		case "stream":
+			v, _ := io.ReadAll(io.LimitReader(part, 16))
+			payload.Stream = strings.TrimSpace(string(v)) == "true"
+		}
+	}
+}
+
+// routePools 为配置了 concurrency 的路由创建独立并发池
+func routePools(cfg config.Proxy) map[string]*concurrencyPool {
+	pools := make(map[string]*concurrencyPool)
+	for name, policy := range cfg.Routes {
+		if policy.Concurrency > 0 {
+			pools[name] = newConcurrencyPool("route_"+name, policy.Concurrency, cfg)
+		}
+	}
+	return pools
+}
+
+// poolFor 路由有独立并发池时使用独立池, 否则按是否 stream 选择共享池
+func (p *ProxyServer) poolFor(rt *proxyRoute, stream bool) *concurrencyPool {
+	if pool, ok := p.routePools[rt.name]; ok {
+		return pool
+	}
+	if stream {
+		return p.streamPool
+	}
+	return p.nonStreamPool
+}
+
+// allPools 共享池及各路由的独立池, 用于状态与指标
+func (p *ProxyServer) allPools() []*concurrencyPool {
+	names := make([]string, 0, len(p.routePools))
+	for name := range p.routePools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pools := []*concurrencyPool{p.nonStreamPool, p.streamPool}
+	for _, name := range names {
+		pools = append(pools, p.routePools[name])
+	}
+	return pools
+}
+
+func poolStats(pools []*concurrencyPool) []PoolStats {
+	stats := make([]PoolStats, 0, len(pools))
+	for _, pool := range pools {
+		stats = append(stats, pool.stats())
+	}
+	return stats
+}
@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	captureService "backend/internal/service/capture"
+	providerService "backend/internal/service/provider"
+	usageService "backend/internal/service/usage"
+	userService "backend/internal/service/user"
+	virtualKeyService "backend/internal/service/virtual_key"
+	"backend/pkg/config"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type ProxyServer struct {
+	srv *http.Server
+	cfg *config.Config
+	// registryMu 保护 registry 的替换, 读取方在读锁内登记 inflight
+	registryMu sync.RWMutex
+	registry   *modelRegistry
+	// proxyCfg 最近一次生效的代理配置, 数据库模型变更后基于它重建映射
+	proxyCfg          config.Proxy
+	providerService   *providerService.Service
+	userService       *userService.Service
+	usageService      *usageService.Service
+	virtualKeyService *virtualKeyService.Service
+	captureService    *captureService.Service
+	limiter           *rateLimiter
+	cache             *responseCache
+	metrics           *proxyMetrics
+	nonStreamPool     *concurrencyPool
+	streamPool        *concurrencyPool
+	// routePools 配置了独立并发的路由, key 为路由名
+	routePools map[string]*concurrencyPool
+	// storedOnly 数据库已有上游服务, 模型只从数据库加载
+	storedOnly atomic.Bool
+}
+
+func CreateProxyServer(
+	cfg *config.Config,
+	userService *userService.Service,
+	usageService *usageService.Service,
+	virtualKeyService *virtualKeyService.Service,
+	providerService *providerService.Service,
+	captureService *captureService.Service,
+) *ProxyServer {
+	p := &ProxyServer{
+		cfg:               cfg,
+		proxyCfg:          cfg.Proxy,
+		providerService:   providerService,
+		userService:       userService,
+		usageService:      usageService,
+		virtualKeyService: virtualKeyService,
+		captureService:    captureService,
+		limiter:           newRateLimiter(),
+		cache:             newResponseCache(cfg.Proxy),
+	}
+
+	// 配置文件中的模型仅用于初始化空数据库, 之后以数据库为准
+	if err := providerService.Bootstrap(context.Background(), cfg.Proxy.Models); err != nil {
+		log.Printf("[warn] import proxy models from config failed: %v", err)
+	}
+	p.registry = newModelRegistry(p.withStoredModels(cfg.Proxy))
+
+	p.nonStreamPool, p.streamPool = initPools(cfg.Proxy)
+	p.routePools = routePools(cfg.Proxy)
+	p.metrics = newProxyMetrics(p)
+
+	if cfg.Proxy.HotReload {
+		config.Watch(func(newCfg *config.Config) {
+			p.Reload(newCfg.Proxy)
+		})
+	}
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	// 运行状态与监控指标同样需要凭证, Prometheus 可使用 proxy.auth.keys 中签发的 key
+	auth := p.authMiddleware()
+	r.GET("/status", auth, p.statusHandler)
+	r.GET("/metrics", auth, p.metrics.handler())
+	r.Any("/v1/*path", auth, p.openAIProxyHandler())
+
+	addr := fmt.Sprintf("%s:%d", cfg.Proxy.Server.Host, cfg.Proxy.Server.Port)
+	p.srv = &http.Server{
+		Addr:         addr,
+		Handler:      r,
+		ReadTimeout:  cfg.Proxy.Server.ReadTimeout,
+		WriteTimeout: cfg.Proxy.Server.WriteTimeout,
+		IdleTimeout:  cfg.Proxy.Server.IdleTimeout,
+	}
+
+	return p
+}
+
+func (p *ProxyServer) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		log.Println("[proxy] shutting down...")
+		_ = p.srv.Shutdown(context.Background())
+	}()
+
+	log.Printf("[proxy] listen on %s", p.srv.Addr)
+
+	if err := p.srv.ListenAndServe(); err != nil &&
+		!errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[proxy] listen error: %v", err)
+	}
+}
+
+// statusHandler 返回并发池、排队队列及响应缓存状态
+func (p *ProxyServer) statusHandler(c *gin.Context) {
+	status := gin.H{
+		"pools": poolStats(p.allPools()),
+	}
+	if p.cache != nil {
+		status["cache"] = p.cache.stats()
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// acquireRegistry 获取当前模型映射, 使用完毕后必须调用 inflight.Done()
+func (p *ProxyServer) acquireRegistry() *modelRegistry {
+	p.registryMu.RLock()
+	defer p.registryMu.RUnlock()
+
+	reg := p.registry
+	reg.inflight.Add(1)
+	return reg
+}
+
+// currentKeyIndex 当前生效的 API key 索引, 随模型映射一起热加载
+func (p *ProxyServer) currentKeyIndex() map[[sha256.Size]byte]config.ProxyKey {
+	p.registryMu.RLock()
+	defer p.registryMu.RUnlock()
+	return p.registry.keyIndex
+}
+
+// Reload 按新配置重建模型映射并原子替换, 旧映射上的请求结束后再释放连接
+func (p *ProxyServer) Reload(cfg config.Proxy) []string {
+	reg := newModelRegistry(p.withStoredModels(cfg))
+
+	p.registryMu.Lock()
+	old := p.registry
+	p.registry = reg
+	prev := p.proxyCfg
+	p.proxyCfg = cfg
+	p.registryMu.Unlock()
+
+	if changed := restartOnlyChanges(prev, cfg); len(changed) > 0 {
+		log.Printf("[warn] proxy config changes to %v take effect after restart", changed)
+	}
+
+	go old.drain()
+
+	models := make([]string, 0, len(reg.clients))
+	for name := range reg.clients {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	log.Printf("[proxy] model registry reloaded, models=%v", models)
+	return models
+}
+
+// Refresh 数据库中的模型/上游服务变更后, 按当前配置重建模型映射
+func (p *ProxyServer) Refresh() []string {
+	p.registryMu.RLock()
+	cfg := p.proxyCfg
+	p.registryMu.RUnlock()
+	return p.Reload(cfg)
+}
+
+// restartOnlyChanges 返回热加载不会生效的配置项中发生变化的部分
+// 监听地址、并发池与排队、响应缓存只在启动时创建; 抓取与预算由主服务的配置决定
+func restartOnlyChanges(prev, next config.Proxy) []string {
+	routeConcurrency := func(cfg config.Proxy) map[string]int {
+		m := make(map[string]int, len(cfg.Routes))
+		for name, r := range cfg.Routes {
+			if r.Concurrency > 0 {
+				m[name] = r.Concurrency
+			}
+		}
+		return m
+	}
+	checks := []struct {
+		name       string
+		prev, next any
+	}{
+		{"server", prev.Server, next.Server},
+		{"limits", prev.Limits, next.Limits},
+		{"routes.*.concurrency", routeConcurrency(prev), routeConcurrency(next)},
+		{"cache", prev.Cache, next.Cache},
+		{"capture", prev.Capture, next.Capture},
+		{"budgets", prev.Budgets, next.Budgets},
+	}
+	var changed []string
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.prev, c.next) {
+			changed = append(changed, c.name)
+		}
+	}
+	return changed
+}
+
+// withStoredModels 数据库中有上游服务(已导入配置文件或由管理员创建)后只使用数据库中的模型,
+// 之后即使模型、上游服务被全部删除也不再回退到配置文件
+func (p *ProxyServer) withStoredModels(cfg config.Proxy) config.Proxy {
+	ctx := context.Background()
+	if !p.storedOnly.Load() {
+		providers, err := p.providerService.ListProviders(ctx)
+		if err != nil {
+			log.Printf("[warn] load proxy providers from db failed: %v", err)
+			return cfg
+		}
+		if len(providers) == 0 {
+			return cfg
+		}
+		p.storedOnly.Store(true)
+	}
+
+	stored, err := p.providerService.LoadModels(ctx)
+	if err != nil {
+		// 数据库暂时不可用时沿用当前映射中的模型
+		log.Printf("[warn] load proxy models from db failed: %v", err)
+		p.registryMu.RLock()
+		defer p.registryMu.RUnlock()
+		if p.registry != nil {
+			cfg.Models = p.registry.cfg.Models
+		}
+		return cfg
+	}
+	cfg.Models = stored
+	return cfg
+}
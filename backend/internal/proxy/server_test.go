@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	providerRepo "backend/internal/repository/provider"
+	providerService "backend/internal/service/provider"
+	"backend/pkg/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+func TestRefreshAfterDeletingBootstrappedModel(t *testing.T) {
+	db := sqlx.MustOpen("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	schema, err := os.ReadFile("../../scripts/sqlite.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Security.SecretKey = "test-secret"
+	cfg.Proxy.Models = anthropicModels("http://upstream.invalid")
+	providers := providerService.CreateProviderService(providerRepo.CreateProviderRepo(db), cfg, zap.NewNop())
+	if err := providers.Bootstrap(ctx, cfg.Proxy.Models); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &ProxyServer{cfg: cfg, proxyCfg: cfg.Proxy, providerService: providers}
+	p.registry = newModelRegistry(p.withStoredModels(cfg.Proxy))
+	if _, ok := p.registry.clients["claude-test"]; !ok {
+		t.Fatal("bootstrapped model is not served")
+	}
+
+	models, err := providers.ListModels(ctx)
+	if err != nil || len(models) != 1 {
+		t.Fatalf("unexpected stored models %v %v", models, err)
+	}
+	if err := providers.DeleteModel(ctx, models[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if served := p.Refresh(); len(served) != 0 {
+		t.Errorf("deleted model still served from config file: %v", served)
+	}
+
+	// 上游服务也被删除后同样不回退到配置文件
+	for _, pv := range models[0].Providers {
+		if err := providers.DeleteProvider(ctx, pv.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if served := p.Refresh(); len(served) != 0 {
+		t.Errorf("empty database fell back to config file: %v", served)
+	}
+}
+
+func TestRetrieveModel(t *testing.T) {
+	h, _ := newTestProxy(t, anthropicModels("http://upstream.invalid"))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/v1/models/claude-test")
+	var m OpenAIModel
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil || w.Code != http.StatusOK || m.ID != "claude-test" || m.Object != "model" {
+		t.Errorf("unexpected model response %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/v1/models/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown model, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = get("/v1/models")
+	var list OpenAIModelList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.Object != "list" || len(list.Data) != 1 {
+		t.Errorf("unexpected model list %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultStreamIdleTimeout 上游 stream 两次数据之间的最长间隔
+const defaultStreamIdleTimeout = 2 * time.Minute
+
+// sseEvent 一个完整的 SSE 事件
+type sseEvent struct {
+	event string
+	data  []byte
+}
+
+// encode 按 SSE 格式编码, 多行 data 拆成多个 data 行
+func (ev sseEvent) encode() []byte {
+	var b bytes.Buffer
+	if ev.event != "" {
+		b.WriteString("event: " + ev.event + "\n")
+	}
+	for _, line := range bytes.Split(ev.data, []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func (ev sseEvent) isDone() bool {
+	return bytes.Equal(bytes.TrimSpace(ev.data), []byte("[DONE]"))
+}
+
+// sseDecoder 增量解析 SSE, 数据可以在任意位置被截断
+type sseDecoder struct {
+	pending []byte
+	event   string
+	data    [][]byte
+}
+
+func (d *sseDecoder) feed(p []byte) []sseEvent {
+	d.pending = append(d.pending, p...)
+	var events []sseEvent
+	for {
+		idx := bytes.IndexByte(d.pending, '\n')
+		if idx < 0 {
+			return events
+		}
+		line := bytes.TrimSuffix(d.pending[:idx], []byte("\r"))
+		d.pending = d.pending[idx+1:]
+
+		switch {
+		case len(line) == 0:
+			if len(d.data) > 0 {
+				events = append(events, sseEvent{event: d.event, data: bytes.Join(d.data, []byte("\n"))})
+			}
+			d.event = ""
+			d.data = nil
+		case line[0] == ':':
+			// 注释/心跳
+		default:
+			field, value, _ := bytes.Cut(line, []byte(":"))
+			value = bytes.TrimPrefix(value, []byte(" "))
+			switch string(field) {
+			case "event":
+				d.event = string(value)
+			case "data":
+				d.data = append(d.data, append([]byte(nil), value...))
+			}
+		}
+	}
+}
+
+// writeSSEData 以 OpenAI SSE 格式写出一个 data 事件
+func writeSSEData(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+	return err
+}
+
+func writeSSEDone(w io.Writer) error {
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+// streamWriter 将转换后的 SSE 按完整事件写给客户端并逐个 flush, 同时提取 usage,
+// 需要缓存或抓取时保留一份副本
+type streamWriter struct {
+	w        io.Writer
+	flusher  http.Flusher
+	recorder *usageRecorder
+	capture  *cacheBuffer
+	tap      *truncBuffer
+	// firstWrite 写出第一个事件时调用一次, 用于统计首 token 耗时
+	firstWrite func()
+	// guard 不为空时先缓存事件, 收到 [DONE] 后统一检查再写出
+	guard   *streamGuard
+	decoder sseDecoder
+	// done 已写出 [DONE], 之后的事件全部丢弃
+	done bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	for _, ev := range s.decoder.feed(p) {
+		if s.guard != nil && !s.done {
+			if !ev.isDone() {
+				// 缓存期间照常记录 usage, stream 中断时也能计费
+				s.recorder.Write(ev.encode())
+				held, err := s.guard.hold(ev)
+				if err != nil {
+					return 0, err
+				}
+				if held {
+					continue
+				}
+				// log 模式超出缓存上限: 写出已缓存的事件, 之后直接透传
+				events := s.guard.events
+				s.guard = nil
+				for _, held := range events {
+					if err := s.emit(held); err != nil {
+						return 0, err
+					}
+				}
+				continue
+			}
+			events, err := s.guard.release()
+			if err != nil {
+				return 0, err
+			}
+			s.guard = nil
+			for _, held := range events {
+				if err := s.emit(held); err != nil {
+					return 0, err
+				}
+			}
+		}
+		if err := s.writeEvent(ev); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *streamWriter) writeEvent(ev sseEvent) error {
+	if s.done {
+		return nil
+	}
+	s.recorder.Write(ev.encode())
+	return s.emit(ev)
+}
+
+// emit 写出事件, 不记录 usage
+func (s *streamWriter) emit(ev sseEvent) error {
+	if s.firstWrite != nil {
+		s.firstWrite()
+		s.firstWrite = nil
+	}
+	b := ev.encode()
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	if s.capture != nil {
+		s.capture.Write(b)
+	}
+	if s.tap != nil {
+		s.tap.Write(b)
+	}
+	s.done = ev.isDone()
+	return nil
+}
+
+// fail 上游 stream 异常中断时补发错误事件和 [DONE], 保证客户端收到完整的 SSE
+func (s *streamWriter) fail(message string) {
+	if s.done {
+		return
+	}
+	if err := s.writeEvent(sseEvent{data: chatError(message, "upstream_error")}); err != nil {
+		return
+	}
+	_ = s.writeEvent(sseEvent{data: []byte("[DONE]")})
+}
+
+func (r *modelRegistry) streamIdleTimeout() time.Duration {
+	if r.cfg.Stream.IdleTimeout > 0 {
+		return r.cfg.Stream.IdleTimeout
+	}
+	return defaultStreamIdleTimeout
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"context"
+	"errors"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// 上游超时默认值, 非 stream 请求的首字节即完整响应, 因此 first_byte 较长
+const (
+	defaultConnectTimeout   = 10 * time.Second
+	defaultFirstByteTimeout = 5 * time.Minute
+	defaultTotalTimeout     = 15 * time.Minute
+)
+
+var (
+	errConnectTimeout   = errors.New("upstream connect timeout")
+	errFirstByteTimeout = errors.New("upstream first byte timeout")
+	errTotalTimeout     = errors.New("upstream total timeout")
+	errStreamIdle       = errors.New("upstream stream idle timeout")
+)
+
+// timeoutsFor 模型配置 > 全局配置 > 默认值, 逐项合并
+func timeoutsFor(cfg config.Proxy, model string) config.ProxyTimeouts {
+	t := config.ProxyTimeouts{
+		Connect:   defaultConnectTimeout,
+		FirstByte: defaultFirstByteTimeout,
+		Total:     defaultTotalTimeout,
+	}
+	merge := func(o config.ProxyTimeouts) {
+		if o.Connect > 0 {
+			t.Connect = o.Connect
+		}
+		if o.FirstByte > 0 {
+			t.FirstByte = o.FirstByte
+		}
+		if o.Total > 0 {
+			t.Total = o.Total
+		}
+	}
+	merge(cfg.Timeouts.ProxyTimeouts)
+	if o, ok := cfg.Timeouts.Models[strings.ToLower(model)]; ok {
+		merge(o)
+	}
+	return t
+}
+
+// upstreamDeadline 绑定在上游请求上的 context, 客户端断开或任一超时触发时取消上游请求
+type upstreamDeadline struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	timers []*time.Timer
+}
+
+func newUpstreamDeadline(parent context.Context, t config.ProxyTimeouts) *upstreamDeadline {
+	ctx, cancel := context.WithCancelCause(parent)
+	d := &upstreamDeadline{cancel: cancel}
+
+	d.after(t.Total, errTotalTimeout)
+	connect := d.after(t.Connect, errConnectTimeout)
+	firstByte := d.after(t.FirstByte, errFirstByteTimeout)
+
+	// 连接建立、收到首字节后停止对应的计时
+	d.ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			connect.Stop()
+		},
+		GotFirstResponseByte: func() {
+			firstByte.Stop()
+		},
+	})
+	return d
+}
+
+func (d *upstreamDeadline) after(timeout time.Duration, cause error) *time.Timer {
+	timer := time.AfterFunc(timeout, func() {
+		d.cancel(cause)
+	})
+	d.timers = append(d.timers, timer)
+	return timer
+}
+
+// timeout 请求因超时被取消时返回原因, 否则返回 nil
+func (d *upstreamDeadline) timeout() error {
+	cause := context.Cause(d.ctx)
+	for _, err := range []error{errConnectTimeout, errFirstByteTimeout, errTotalTimeout, errStreamIdle} {
+		if errors.Is(cause, err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *upstreamDeadline) stop() {
+	for _, t := range d.timers {
+		t.Stop()
+	}
+	d.cancel(context.Canceled)
+}
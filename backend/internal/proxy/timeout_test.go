@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"backend/pkg/config"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowUpstream 模拟 OpenAI 兼容上游: 先等待 headerDelay 再返回响应头,
+// stream 请求每隔 chunkDelay 写出一个事件; 上游请求被取消时通知 canceled
+type slowUpstream struct {
+	headerDelay time.Duration
+	chunkDelay  time.Duration
+	chunks      int
+	canceled    chan struct{}
+}
+
+func (u *slowUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	stream := strings.Contains(string(body), `"stream":true`)
+
+	if !u.sleep(r, u.headerDelay) {
+		return
+	}
+	if !stream {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	for i := 0; i < u.chunks; i++ {
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%d\"}}]}\n\n", i)
+		flusher.Flush()
+		if !u.sleep(r, u.chunkDelay) {
+			return
+		}
+	}
+	io.WriteString(w, "data: [DONE]\n\n")
+}
+
+func (u *slowUpstream) sleep(r *http.Request, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		if u.canceled != nil {
+			u.canceled <- struct{}{}
+		}
+		return false
+	}
+}
+
+func slowModels(apiBase string) map[string]config.ProxyModel {
+	endpoint := []config.ProxyEndpoint{{Name: "slow", ApiBase: apiBase, ApiKey: "sk-test"}}
+	return map[string]config.ProxyModel{
+		"fast-model": {Endpoints: endpoint},
+		"slow-model": {Endpoints: endpoint},
+	}
+}
+
+func TestUpstreamFirstByteTimeout(t *testing.T) {
+	upstream := &slowUpstream{headerDelay: 500 * time.Millisecond, canceled: make(chan struct{}, 1)}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Proxy.Models = slowModels(srv.URL)
+	cfg.Proxy.Timeouts.FirstByte = 100 * time.Millisecond
+	h, _ := newTestProxyWithConfig(t, cfg)
+
+	start := time.Now()
+	w := postJSON(h, "/v1/chat/completions", `{"model":"fast-model","messages":[]}`)
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), errFirstByteTimeout.Error()) {
+		t.Fatalf("expected 504 first byte timeout, got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("timeout took %s", elapsed)
+	}
+	select {
+	case <-upstream.canceled:
+	case <-time.After(time.Second):
+		t.Error("upstream request was not canceled")
+	}
+}
+
+func TestUpstreamPerModelTimeout(t *testing.T) {
+	srv := httptest.NewServer(&slowUpstream{headerDelay: 200 * time.Millisecond})
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Proxy.Models = slowModels(srv.URL)
+	cfg.Proxy.Timeouts.FirstByte = time.Second
+	// viper 会把 map key 转为小写, 这里模拟配置文件加载后的结果
+	cfg.Proxy.Timeouts.Models = map[string]config.ProxyTimeouts{
+		"slow-model": {FirstByte: 50 * time.Millisecond},
+	}
+	h, _ := newTestProxyWithConfig(t, cfg)
+
+	if w := postJSON(h, "/v1/chat/completions", `{"model":"fast-model","messages":[]}`); w.Code != http.StatusOK {
+		t.Errorf("fast-model: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(h, "/v1/chat/completions", `{"model":"slow-model","messages":[]}`); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("slow-model: expected 504, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpstreamTotalTimeoutStream(t *testing.T) {
+	upstream := &slowUpstream{chunkDelay: 100 * time.Millisecond, chunks: 20, canceled: make(chan struct{}, 1)}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Proxy.Models = slowModels(srv.URL)
+	cfg.Proxy.Timeouts.Total = 250 * time.Millisecond
+	h, _ := newTestProxyWithConfig(t, cfg)
+
+	w := postJSON(h, "/v1/chat/completions", `{"model":"fast-model","stream":true,"messages":[]}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected stream response %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"content":"0"`) {
+		t.Errorf("expected events before timeout, got %q", body)
+	}
+	if !strings.Contains(body, errTotalTimeout.Error()) || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("expected error event and [DONE] after timeout, got %q", body)
+	}
+	select {
+	case <-upstream.canceled:
+	case <-time.After(time.Second):
+		t.Error("upstream request was not canceled")
+	}
+}
+
+func TestClientDisconnectCancelsUpstream(t *testing.T) {
+	upstream := &slowUpstream{headerDelay: 5 * time.Second, canceled: make(chan struct{}, 1)}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	h, _ := newTestProxy(t, slowModels(srv.URL))
+	proxySrv := httptest.NewServer(h)
+	defer proxySrv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, proxySrv.URL+"/v1/chat/completions",
+		strings.NewReader(`{"model":"fast-model","messages":[]}`))
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("expected client timeout")
+	}
+
+	select {
+	case <-upstream.canceled:
+	case <-time.After(time.Second):
+		t.Error("upstream request was not canceled after client disconnect")
+	}
+}
+
+func TestTimeoutsFor(t *testing.T) {
+	var cfg config.Proxy
+	cfg.Timeouts.Connect = 3 * time.Second
+	cfg.Timeouts.Models = map[string]config.ProxyTimeouts{
+		"gpt-4o": {Total: time.Minute},
+	}
+
+	got := timeoutsFor(cfg, "GPT-4o")
+	want := config.ProxyTimeouts{Connect: 3 * time.Second, FirstByte: defaultFirstByteTimeout, Total: time.Minute}
+	if got != want {
+		t.Errorf("timeoutsFor = %+v, want %+v", got, want)
+	}
+}
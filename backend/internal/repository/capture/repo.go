@@ -0,0 +1,111 @@
+package capture
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// Filter 列表过滤条件, 零值表示不过滤
+type Filter struct {
+	UserID   int64
+	PromptID string
+	Model    string
+}
+
+type IRepo interface {
+	Create(ctx context.Context, c *model.ProxyCapture) error
+	GetByID(ctx context.Context, id string) (*model.ProxyCapture, error)
+	List(ctx context.Context, filter Filter, offset, limit int) ([]*model.ProxyCapture, error)
+	Count(ctx context.Context, filter Filter) (int64, error)
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateCaptureRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+// listColumns 列表不返回请求/响应体
+const listColumns = `
+	id, user_id, username, key_id, prompt_id, replay_of, model, endpoint, path,
+	stream, status_code, latency_ms, created_at
+`
+
+func (r *Repo) Create(ctx context.Context, c *model.ProxyCapture) error {
+	c.CreatedAt = time.Now()
+	query := `
+		INSERT INTO proxy_captures (
+			id, user_id, username, key_id, prompt_id, replay_of, model, endpoint, path,
+			stream, status_code, latency_ms, request_headers, request_body,
+			upstream_url, upstream_body, response_body, created_at
+		) VALUES (
+			:id, :user_id, :username, :key_id, :prompt_id, :replay_of, :model, :endpoint, :path,
+			:stream, :status_code, :latency_ms, :request_headers, :request_body,
+			:upstream_url, :upstream_body, :response_body, :created_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, c)
+	return err
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*model.ProxyCapture, error) {
+	query := `
+		SELECT ` + listColumns + `, request_headers, request_body, upstream_url, upstream_body, response_body
+		FROM proxy_captures WHERE id = ?
+	`
+	var c model.ProxyCapture
+	err := r.db.GetContext(ctx, &c, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &c, err
+}
+
+func (r *Repo) List(ctx context.Context, filter Filter, offset, limit int) ([]*model.ProxyCapture, error) {
+	where, args := filter.where()
+	query := `SELECT ` + listColumns + ` FROM proxy_captures` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.ProxyCapture, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) Count(ctx context.Context, filter Filter) (int64, error) {
+	where, args := filter.where()
+	query := `SELECT COUNT(1) FROM proxy_captures` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func (f Filter) where() (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.UserID > 0 {
+		conds = append(conds, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.PromptID != "" {
+		conds = append(conds, "prompt_id = ?")
+		args = append(args, f.PromptID)
+	}
+	if f.Model != "" {
+		conds = append(conds, "model = ?")
+		args = append(args, f.Model)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	where := " WHERE " + conds[0]
+	for _, c := range conds[1:] {
+		where += " AND " + c
+	}
+	return where, args
+}
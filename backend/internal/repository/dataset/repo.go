@@ -0,0 +1,362 @@
+package dataset
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRepo interface {
+	Create(ctx context.Context, d *model.Dataset) error
+	Update(ctx context.Context, d *model.Dataset) error
+	GetByID(ctx context.Context, id string) (*model.Dataset, error)
+	List(ctx context.Context, promptID string, offset, limit int) ([]*model.Dataset, error)
+	Count(ctx context.Context, promptID string) (int64, error)
+	DeleteByID(ctx context.Context, id string) error
+
+	CreateRow(ctx context.Context, row *model.DatasetRow) error
+	UpdateRow(ctx context.Context, row *model.DatasetRow) error
+	GetRow(ctx context.Context, id string) (*model.DatasetRow, error)
+	DeleteRow(ctx context.Context, row *model.DatasetRow) error
+	ListRows(ctx context.Context, datasetID string, version, offset, limit int) ([]*model.DatasetRow, error)
+	AllRows(ctx context.Context, datasetID string, version int) ([]*model.DatasetRow, error)
+	CountRows(ctx context.Context, datasetID string, version int) (int64, error)
+	ImportRows(ctx context.Context, datasetID string, rows []*model.DatasetRow, replace bool) error
+
+	CreateVersion(ctx context.Context, v *model.DatasetVersion) error
+	GetVersion(ctx context.Context, datasetID string, version int) (*model.DatasetVersion, error)
+	ListVersions(ctx context.Context, datasetID string) ([]*model.DatasetVersion, error)
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateDatasetRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const datasetColumns = `
+	id, prompt_id, name, description, version, row_count,
+	created_by, username, created_at, updated_at
+`
+
+const rowColumns = `
+	id, dataset_id, version, position, variables, expected, reference,
+	created_at, updated_at
+`
+
+const insertRow = `
+	INSERT INTO dataset_rows (
+		id, dataset_id, version, position, variables, expected, reference,
+		created_at, updated_at
+	) VALUES (
+		:id, :dataset_id, :version, :position, :variables, :expected, :reference,
+		:created_at, :updated_at
+	)
+`
+
+func (r *Repo) Create(ctx context.Context, d *model.Dataset) error {
+	now := time.Now()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+	query := `
+		INSERT INTO datasets (
+			id, prompt_id, name, description, version, row_count,
+			created_by, username, created_at, updated_at
+		) VALUES (
+			:id, :prompt_id, :name, :description, :version, :row_count,
+			:created_by, :username, :created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, d)
+	return err
+}
+
+func (r *Repo) Update(ctx context.Context, d *model.Dataset) error {
+	d.UpdatedAt = time.Now()
+	query := `
+		UPDATE datasets SET
+			name = :name,
+			description = :description,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, d)
+	return err
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*model.Dataset, error) {
+	query := `SELECT ` + datasetColumns + ` FROM datasets WHERE id = ?`
+	var d model.Dataset
+	err := r.db.GetContext(ctx, &d, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &d, err
+}
+
+// List promptID 为空时返回全部数据集
+func (r *Repo) List(ctx context.Context, promptID string, offset, limit int) ([]*model.Dataset, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT ` + datasetColumns + ` FROM datasets` + where + ` ORDER BY updated_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.Dataset, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) Count(ctx context.Context, promptID string) (int64, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT COUNT(1) FROM datasets` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func promptFilter(promptID string) (string, []any) {
+	if promptID == "" {
+		return "", nil
+	}
+	return " WHERE prompt_id = ?", []any{promptID}
+}
+
+// DeleteByID 删除数据集及其全部数据行和快照
+func (r *Repo) DeleteByID(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, query := range []string{
+		`DELETE FROM dataset_rows WHERE dataset_id = ?`,
+		`DELETE FROM dataset_versions WHERE dataset_id = ?`,
+		`DELETE FROM datasets WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateRow 追加到可编辑数据末尾
+func (r *Repo) CreateRow(ctx context.Context, row *model.DatasetRow) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	position, err := nextPosition(ctx, tx, row.DatasetID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	row.Version = model.DatasetDraft
+	row.Position = position
+	row.CreatedAt = now
+	row.UpdatedAt = now
+	if _, err := tx.NamedExecContext(ctx, insertRow, row); err != nil {
+		return err
+	}
+	if err := refreshRowCount(ctx, tx, row.DatasetID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) UpdateRow(ctx context.Context, row *model.DatasetRow) error {
+	row.UpdatedAt = time.Now()
+	query := `
+		UPDATE dataset_rows SET
+			variables = :variables,
+			expected = :expected,
+			reference = :reference,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, row)
+	return err
+}
+
+func (r *Repo) GetRow(ctx context.Context, id string) (*model.DatasetRow, error) {
+	query := `SELECT ` + rowColumns + ` FROM dataset_rows WHERE id = ?`
+	var row model.DatasetRow
+	err := r.db.GetContext(ctx, &row, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &row, err
+}
+
+func (r *Repo) DeleteRow(ctx context.Context, row *model.DatasetRow) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM dataset_rows WHERE id = ?`), row.ID); err != nil {
+		return err
+	}
+	if err := refreshRowCount(ctx, tx, row.DatasetID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) ListRows(ctx context.Context, datasetID string, version, offset, limit int) ([]*model.DatasetRow, error) {
+	query := `
+		SELECT ` + rowColumns + ` FROM dataset_rows
+		WHERE dataset_id = ? AND version = ?
+		ORDER BY position LIMIT ? OFFSET ?
+	`
+	list := make([]*model.DatasetRow, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), datasetID, version, limit, offset)
+	return list, err
+}
+
+// AllRows 返回指定版本的全部数据行, 用于导出和评测
+func (r *Repo) AllRows(ctx context.Context, datasetID string, version int) ([]*model.DatasetRow, error) {
+	query := `
+		SELECT ` + rowColumns + ` FROM dataset_rows
+		WHERE dataset_id = ? AND version = ?
+		ORDER BY position
+	`
+	list := make([]*model.DatasetRow, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), datasetID, version)
+	return list, err
+}
+
+func (r *Repo) CountRows(ctx context.Context, datasetID string, version int) (int64, error) {
+	query := `SELECT COUNT(1) FROM dataset_rows WHERE dataset_id = ? AND version = ?`
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), datasetID, version)
+	return count, err
+}
+
+// ImportRows 批量写入可编辑数据, replace 为 true 时先清空原有数据
+func (r *Repo) ImportRows(ctx context.Context, datasetID string, rows []*model.DatasetRow, replace bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if replace {
+		query := tx.Rebind(`DELETE FROM dataset_rows WHERE dataset_id = ? AND version = ?`)
+		if _, err := tx.ExecContext(ctx, query, datasetID, model.DatasetDraft); err != nil {
+			return err
+		}
+	}
+	position, err := nextPosition(ctx, tx, datasetID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i, row := range rows {
+		row.DatasetID = datasetID
+		row.Version = model.DatasetDraft
+		row.Position = position + i
+		row.CreatedAt = now
+		row.UpdatedAt = now
+		if _, err := tx.NamedExecContext(ctx, insertRow, row); err != nil {
+			return err
+		}
+	}
+	if err := refreshRowCount(ctx, tx, datasetID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateVersion 将当前可编辑数据复制为快照 v.Version, 并更新数据集的最新版本号
+func (r *Repo) CreateVersion(ctx context.Context, v *model.DatasetVersion) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rows []*model.DatasetRow
+	query := tx.Rebind(`SELECT ` + rowColumns + ` FROM dataset_rows WHERE dataset_id = ? AND version = ? ORDER BY position`)
+	if err := tx.SelectContext(ctx, &rows, query, v.DatasetID, model.DatasetDraft); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, row := range rows {
+		row.ID = uuid.New().String()
+		row.Version = v.Version
+		row.CreatedAt = now
+		row.UpdatedAt = now
+		if _, err := tx.NamedExecContext(ctx, insertRow, row); err != nil {
+			return err
+		}
+	}
+
+	v.RowCount = len(rows)
+	v.CreatedAt = now
+	insertVersion := `
+		INSERT INTO dataset_versions (
+			id, dataset_id, version, row_count, change_log, created_by, username, created_at
+		) VALUES (
+			:id, :dataset_id, :version, :row_count, :change_log, :created_by, :username, :created_at
+		)
+	`
+	if _, err := tx.NamedExecContext(ctx, insertVersion, v); err != nil {
+		return err
+	}
+	update := tx.Rebind(`UPDATE datasets SET version = ?, updated_at = ? WHERE id = ?`)
+	if _, err := tx.ExecContext(ctx, update, v.Version, now, v.DatasetID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) GetVersion(ctx context.Context, datasetID string, version int) (*model.DatasetVersion, error) {
+	query := `
+		SELECT id, dataset_id, version, row_count, change_log, created_by, username, created_at
+		FROM dataset_versions WHERE dataset_id = ? AND version = ?
+	`
+	var v model.DatasetVersion
+	err := r.db.GetContext(ctx, &v, r.db.Rebind(query), datasetID, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &v, err
+}
+
+func (r *Repo) ListVersions(ctx context.Context, datasetID string) ([]*model.DatasetVersion, error) {
+	query := `
+		SELECT id, dataset_id, version, row_count, change_log, created_by, username, created_at
+		FROM dataset_versions WHERE dataset_id = ?
+		ORDER BY version DESC
+	`
+	list := make([]*model.DatasetVersion, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), datasetID)
+	return list, err
+}
+
+func nextPosition(ctx context.Context, tx *sqlx.Tx, datasetID string) (int, error) {
+	query := tx.Rebind(`SELECT COALESCE(MAX(position), -1) + 1 FROM dataset_rows WHERE dataset_id = ? AND version = ?`)
+	var position int
+	err := tx.GetContext(ctx, &position, query, datasetID, model.DatasetDraft)
+	return position, err
+}
+
+// refreshRowCount 同步数据集的可编辑数据行数
+func refreshRowCount(ctx context.Context, tx *sqlx.Tx, datasetID string) error {
+	query := tx.Rebind(`
+		UPDATE datasets SET
+			row_count = (SELECT COUNT(1) FROM dataset_rows WHERE dataset_id = ? AND version = ?),
+			updated_at = ?
+		WHERE id = ?
+	`)
+	_, err := tx.ExecContext(ctx, query, datasetID, model.DatasetDraft, time.Now(), datasetID)
+	return err
+}
@@ -0,0 +1,460 @@
+package eval
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// RunFilter 任务列表过滤条件, 零值表示不过滤
+type RunFilter struct {
+	PromptID  string
+	VersionID string
+}
+
+func (f RunFilter) where() (string, []any) {
+	where, args := "", make([]any, 0, 2)
+	add := func(cond string, arg any) {
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+		args = append(args, arg)
+	}
+	if f.PromptID != "" {
+		add("prompt_id = ?", f.PromptID)
+	}
+	if f.VersionID != "" {
+		add("version_id = ?", f.VersionID)
+	}
+	return where, args
+}
+
+type IRepo interface {
+	CreateRun(ctx context.Context, run *model.EvalRun, results []*model.EvalResult) error
+	GetRun(ctx context.Context, id string) (*model.EvalRun, error)
+	ListRuns(ctx context.Context, filter RunFilter, offset, limit int) ([]*model.EvalRun, error)
+	CountRuns(ctx context.Context, filter RunFilter) (int64, error)
+	StartRun(ctx context.Context, id string) error
+	FinishRun(ctx context.Context, id, status, errMsg string) error
+	InterruptRuns(ctx context.Context, errMsg string) (int64, error)
+
+	PendingResults(ctx context.Context, runID string) ([]*model.EvalResult, error)
+	SaveResult(ctx context.Context, result *model.EvalResult) error
+	CancelPending(ctx context.Context, runID string) error
+	ListResults(ctx context.Context, runID, status string, offset, limit int) ([]*model.EvalResult, error)
+	CountResults(ctx context.Context, runID, status string) (int64, error)
+	AllResults(ctx context.Context, runID string) ([]*model.EvalResult, error)
+
+	CreateAssertionSet(ctx context.Context, set *model.EvalAssertionSet) error
+	UpdateAssertionSet(ctx context.Context, set *model.EvalAssertionSet) error
+	GetAssertionSet(ctx context.Context, id string) (*model.EvalAssertionSet, error)
+	ListAssertionSets(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalAssertionSet, error)
+	CountAssertionSets(ctx context.Context, promptID string) (int64, error)
+	DeleteAssertionSet(ctx context.Context, id string) error
+
+	CreateComparison(ctx context.Context, c *model.EvalComparison) error
+	GetComparison(ctx context.Context, id string) (*model.EvalComparison, error)
+	ListComparisons(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalComparison, error)
+	CountComparisons(ctx context.Context, promptID string) (int64, error)
+
+	CreateGate(ctx context.Context, gate *model.EvalGate) error
+	UpdateGate(ctx context.Context, gate *model.EvalGate) error
+	GetGateByPromptID(ctx context.Context, promptID string) (*model.EvalGate, error)
+	DeleteGateByPromptID(ctx context.Context, promptID string) error
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateEvalRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const runColumns = `
+	id, prompt_id, version_id, dataset_id, dataset_version, model, params, concurrency,
+	status, total, succeeded, failed, prompt_tokens, completion_tokens, cost, error,
+	user_id, username, started_at, finished_at, created_at, updated_at
+`
+
+const resultColumns = `
+	id, run_id, position, variables, expected, reference, input, output, status, error,
+	latency_ms, prompt_tokens, completion_tokens, cost, created_at, updated_at
+`
+
+const assertionSetColumns = `
+	id, prompt_id, name, description, assertions, created_by, username, created_at, updated_at
+`
+
+const comparisonColumns = `
+	id, prompt_id, base_version_id, candidate_version_id, base_run_id, candidate_run_id,
+	assertions, regression_threshold, user_id, username, created_at, updated_at
+`
+
+const gateColumns = `
+	id, prompt_id, model, params, cases, assertions, min_pass_rate, enabled,
+	created_by, username, created_at, updated_at
+`
+
+// CreateRun 写入任务及其全部待执行的行
+func (r *Repo) CreateRun(ctx context.Context, run *model.EvalRun, results []*model.EvalResult) error {
+	now := time.Now()
+	run.CreatedAt = now
+	run.UpdatedAt = now
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO eval_runs (` + runColumns + `) VALUES (
+			:id, :prompt_id, :version_id, :dataset_id, :dataset_version, :model, :params, :concurrency,
+			:status, :total, :succeeded, :failed, :prompt_tokens, :completion_tokens, :cost, :error,
+			:user_id, :username, :started_at, :finished_at, :created_at, :updated_at
+		)
+	`
+	if _, err := tx.NamedExecContext(ctx, query, run); err != nil {
+		return err
+	}
+
+	insert := `
+		INSERT INTO eval_results (` + resultColumns + `) VALUES (
+			:id, :run_id, :position, :variables, :expected, :reference, :input, :output, :status, :error,
+			:latency_ms, :prompt_tokens, :completion_tokens, :cost, :created_at, :updated_at
+		)
+	`
+	for _, result := range results {
+		result.CreatedAt = now
+		result.UpdatedAt = now
+		if _, err := tx.NamedExecContext(ctx, insert, result); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) GetRun(ctx context.Context, id string) (*model.EvalRun, error) {
+	query := `SELECT ` + runColumns + ` FROM eval_runs WHERE id = ?`
+	var run model.EvalRun
+	err := r.db.GetContext(ctx, &run, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &run, err
+}
+
+func (r *Repo) ListRuns(ctx context.Context, filter RunFilter, offset, limit int) ([]*model.EvalRun, error) {
+	where, args := filter.where()
+	query := `SELECT ` + runColumns + ` FROM eval_runs` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.EvalRun, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) CountRuns(ctx context.Context, filter RunFilter) (int64, error) {
+	where, args := filter.where()
+	query := `SELECT COUNT(1) FROM eval_runs` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func (r *Repo) StartRun(ctx context.Context, id string) error {
+	now := time.Now()
+	query := `UPDATE eval_runs SET status = ?, started_at = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), model.EvalRunRunning, now, now, id)
+	return err
+}
+
+func (r *Repo) FinishRun(ctx context.Context, id, status, errMsg string) error {
+	now := time.Now()
+	query := `UPDATE eval_runs SET status = ?, error = ?, finished_at = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), status, errMsg, now, now, id)
+	return err
+}
+
+// InterruptRuns 将未结束的任务标记为失败, 用于服务重启后清理
+func (r *Repo) InterruptRuns(ctx context.Context, errMsg string) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	cancelResults := tx.Rebind(`
+		UPDATE eval_results SET status = ?, updated_at = ?
+		WHERE status = ? AND run_id IN (SELECT id FROM eval_runs WHERE status IN (?, ?))
+	`)
+	if _, err := tx.ExecContext(ctx, cancelResults, model.EvalResultCanceled, now,
+		model.EvalResultPending, model.EvalRunPending, model.EvalRunRunning); err != nil {
+		return 0, err
+	}
+	failRuns := tx.Rebind(`
+		UPDATE eval_runs SET status = ?, error = ?, finished_at = ?, updated_at = ?
+		WHERE status IN (?, ?)
+	`)
+	res, err := tx.ExecContext(ctx, failRuns, model.EvalRunFailed, errMsg, now, now,
+		model.EvalRunPending, model.EvalRunRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+func (r *Repo) PendingResults(ctx context.Context, runID string) ([]*model.EvalResult, error) {
+	query := `SELECT ` + resultColumns + ` FROM eval_results WHERE run_id = ? AND status = ? ORDER BY position`
+	list := make([]*model.EvalResult, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), runID, model.EvalResultPending)
+	return list, err
+}
+
+// SaveResult 保存一行的执行结果, 并累加到任务的进度与用量
+func (r *Repo) SaveResult(ctx context.Context, result *model.EvalResult) error {
+	result.UpdatedAt = time.Now()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		UPDATE eval_results SET
+			input = :input,
+			output = :output,
+			status = :status,
+			error = :error,
+			latency_ms = :latency_ms,
+			prompt_tokens = :prompt_tokens,
+			completion_tokens = :completion_tokens,
+			cost = :cost,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	if _, err := tx.NamedExecContext(ctx, query, result); err != nil {
+		return err
+	}
+
+	succeeded, failed := 0, 0
+	if result.Status == model.EvalResultSucceeded {
+		succeeded = 1
+	} else {
+		failed = 1
+	}
+	progress := tx.Rebind(`
+		UPDATE eval_runs SET
+			succeeded = succeeded + ?,
+			failed = failed + ?,
+			prompt_tokens = prompt_tokens + ?,
+			completion_tokens = completion_tokens + ?,
+			cost = cost + ?,
+			updated_at = ?
+		WHERE id = ?
+	`)
+	if _, err := tx.ExecContext(ctx, progress, succeeded, failed, result.PromptTokens,
+		result.CompletionTokens, result.Cost, result.UpdatedAt, result.RunID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) CancelPending(ctx context.Context, runID string) error {
+	query := `UPDATE eval_results SET status = ?, updated_at = ? WHERE run_id = ? AND status = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), model.EvalResultCanceled, time.Now(), runID, model.EvalResultPending)
+	return err
+}
+
+func resultFilter(runID, status string) (string, []any) {
+	if status == "" {
+		return " WHERE run_id = ?", []any{runID}
+	}
+	return " WHERE run_id = ? AND status = ?", []any{runID, status}
+}
+
+// ListResults status 为空时返回全部行
+func (r *Repo) ListResults(ctx context.Context, runID, status string, offset, limit int) ([]*model.EvalResult, error) {
+	where, args := resultFilter(runID, status)
+	query := `SELECT ` + resultColumns + ` FROM eval_results` + where + ` ORDER BY position LIMIT ? OFFSET ?`
+	list := make([]*model.EvalResult, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) CountResults(ctx context.Context, runID, status string) (int64, error) {
+	where, args := resultFilter(runID, status)
+	query := `SELECT COUNT(1) FROM eval_results` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func (r *Repo) AllResults(ctx context.Context, runID string) ([]*model.EvalResult, error) {
+	query := `SELECT ` + resultColumns + ` FROM eval_results WHERE run_id = ? ORDER BY position`
+	list := make([]*model.EvalResult, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), runID)
+	return list, err
+}
+
+func (r *Repo) CreateAssertionSet(ctx context.Context, set *model.EvalAssertionSet) error {
+	now := time.Now()
+	set.CreatedAt = now
+	set.UpdatedAt = now
+	query := `
+		INSERT INTO eval_assertion_sets (` + assertionSetColumns + `) VALUES (
+			:id, :prompt_id, :name, :description, :assertions, :created_by, :username, :created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, set)
+	return err
+}
+
+func (r *Repo) UpdateAssertionSet(ctx context.Context, set *model.EvalAssertionSet) error {
+	set.UpdatedAt = time.Now()
+	query := `
+		UPDATE eval_assertion_sets SET
+			name = :name,
+			description = :description,
+			assertions = :assertions,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, set)
+	return err
+}
+
+func (r *Repo) GetAssertionSet(ctx context.Context, id string) (*model.EvalAssertionSet, error) {
+	query := `SELECT ` + assertionSetColumns + ` FROM eval_assertion_sets WHERE id = ?`
+	var set model.EvalAssertionSet
+	err := r.db.GetContext(ctx, &set, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &set, err
+}
+
+func promptFilter(promptID string) (string, []any) {
+	if promptID == "" {
+		return "", nil
+	}
+	return " WHERE prompt_id = ?", []any{promptID}
+}
+
+// ListAssertionSets promptID 为空时返回全部断言集
+func (r *Repo) ListAssertionSets(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalAssertionSet, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT ` + assertionSetColumns + ` FROM eval_assertion_sets` + where + ` ORDER BY updated_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.EvalAssertionSet, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) CountAssertionSets(ctx context.Context, promptID string) (int64, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT COUNT(1) FROM eval_assertion_sets` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func (r *Repo) DeleteAssertionSet(ctx context.Context, id string) error {
+	query := `DELETE FROM eval_assertion_sets WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), id)
+	return err
+}
+
+func (r *Repo) CreateComparison(ctx context.Context, c *model.EvalComparison) error {
+	now := time.Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	query := `
+		INSERT INTO eval_comparisons (` + comparisonColumns + `) VALUES (
+			:id, :prompt_id, :base_version_id, :candidate_version_id, :base_run_id, :candidate_run_id,
+			:assertions, :regression_threshold, :user_id, :username, :created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, c)
+	return err
+}
+
+func (r *Repo) GetComparison(ctx context.Context, id string) (*model.EvalComparison, error) {
+	query := `SELECT ` + comparisonColumns + ` FROM eval_comparisons WHERE id = ?`
+	var c model.EvalComparison
+	err := r.db.GetContext(ctx, &c, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &c, err
+}
+
+// ListComparisons promptID 为空时返回全部对比
+func (r *Repo) ListComparisons(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalComparison, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT ` + comparisonColumns + ` FROM eval_comparisons` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.EvalComparison, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) CountComparisons(ctx context.Context, promptID string) (int64, error) {
+	where, args := promptFilter(promptID)
+	query := `SELECT COUNT(1) FROM eval_comparisons` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+func (r *Repo) CreateGate(ctx context.Context, gate *model.EvalGate) error {
+	now := time.Now()
+	gate.CreatedAt = now
+	gate.UpdatedAt = now
+	query := `
+		INSERT INTO eval_gates (` + gateColumns + `) VALUES (
+			:id, :prompt_id, :model, :params, :cases, :assertions, :min_pass_rate, :enabled,
+			:created_by, :username, :created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, gate)
+	return err
+}
+
+func (r *Repo) UpdateGate(ctx context.Context, gate *model.EvalGate) error {
+	gate.UpdatedAt = time.Now()
+	query := `
+		UPDATE eval_gates SET
+			model = :model,
+			params = :params,
+			cases = :cases,
+			assertions = :assertions,
+			min_pass_rate = :min_pass_rate,
+			enabled = :enabled,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, gate)
+	return err
+}
+
+func (r *Repo) GetGateByPromptID(ctx context.Context, promptID string) (*model.EvalGate, error) {
+	query := `SELECT ` + gateColumns + ` FROM eval_gates WHERE prompt_id = ?`
+	var gate model.EvalGate
+	err := r.db.GetContext(ctx, &gate, r.db.Rebind(query), promptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &gate, err
+}
+
+func (r *Repo) DeleteGateByPromptID(ctx context.Context, promptID string) error {
+	query := `DELETE FROM eval_gates WHERE prompt_id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), promptID)
+	return err
+}
@@ -0,0 +1,71 @@
+package experiment
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRepo interface {
+	Create(ctx context.Context, e *model.PromptExperiment) error
+	Update(ctx context.Context, e *model.PromptExperiment) error
+	GetByPromptID(ctx context.Context, promptID string) (*model.PromptExperiment, error)
+	DeleteByPromptID(ctx context.Context, promptID string) error
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateExperimentRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const experimentColumns = `
+	id, prompt_id, variants, enabled, created_by, username, created_at, updated_at
+`
+
+func (r *Repo) Create(ctx context.Context, e *model.PromptExperiment) error {
+	now := time.Now()
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	query := `
+		INSERT INTO prompt_experiments (` + experimentColumns + `) VALUES (
+			:id, :prompt_id, :variants, :enabled, :created_by, :username, :created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, e)
+	return err
+}
+
+func (r *Repo) Update(ctx context.Context, e *model.PromptExperiment) error {
+	e.UpdatedAt = time.Now()
+	query := `
+		UPDATE prompt_experiments SET
+			variants = :variants,
+			enabled = :enabled,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, e)
+	return err
+}
+
+func (r *Repo) GetByPromptID(ctx context.Context, promptID string) (*model.PromptExperiment, error) {
+	query := `SELECT ` + experimentColumns + ` FROM prompt_experiments WHERE prompt_id = ?`
+	var e model.PromptExperiment
+	err := r.db.GetContext(ctx, &e, r.db.Rebind(query), promptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &e, err
+}
+
+func (r *Repo) DeleteByPromptID(ctx context.Context, promptID string) error {
+	query := `DELETE FROM prompt_experiments WHERE prompt_id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), promptID)
+	return err
+}
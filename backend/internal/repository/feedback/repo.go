@@ -0,0 +1,150 @@
+package feedback
+
+import (
+	"backend/internal/model"
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// Filter 反馈过滤条件, 零值表示不过滤
+type Filter struct {
+	PromptID  string
+	VersionID string
+	TraceID   string
+	Tag       string
+	From      time.Time
+	To        time.Time
+}
+
+func (f Filter) where() (string, []any) {
+	where, args := "", make([]any, 0, 6)
+	add := func(cond string, arg any) {
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+		args = append(args, arg)
+	}
+	if f.PromptID != "" {
+		add("prompt_id = ?", f.PromptID)
+	}
+	if f.VersionID != "" {
+		add("version_id = ?", f.VersionID)
+	}
+	if f.TraceID != "" {
+		add("trace_id = ?", f.TraceID)
+	}
+	if f.Tag != "" {
+		add("id IN (SELECT feedback_id FROM prompt_feedback_tags WHERE tag = ?)", f.Tag)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To)
+	}
+	return where, args
+}
+
+type IRepo interface {
+	Create(ctx context.Context, f *model.Feedback, tags []string) error
+	List(ctx context.Context, filter Filter, offset, limit int) ([]*model.Feedback, error)
+	Count(ctx context.Context, filter Filter) (int64, error)
+	Stats(ctx context.Context, filter Filter) ([]*model.FeedbackStats, error)
+	TagCounts(ctx context.Context, filter Filter) ([]*model.FeedbackTagCount, error)
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateFeedbackRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const feedbackColumns = `
+	id, prompt_id, version_id, trace_id, score, tags, comment, created_at, updated_at
+`
+
+func (r *Repo) Create(ctx context.Context, f *model.Feedback, tags []string) error {
+	now := time.Now()
+	f.CreatedAt = now
+	f.UpdatedAt = now
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO prompt_feedback (` + feedbackColumns + `) VALUES (
+			:id, :prompt_id, :version_id, :trace_id, :score, :tags, :comment, :created_at, :updated_at
+		)
+	`
+	if _, err := tx.NamedExecContext(ctx, query, f); err != nil {
+		return err
+	}
+
+	insert := tx.Rebind(`
+		INSERT INTO prompt_feedback_tags (feedback_id, prompt_id, version_id, tag, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, insert, f.ID, f.PromptID, f.VersionID, tag, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) List(ctx context.Context, filter Filter, offset, limit int) ([]*model.Feedback, error) {
+	where, args := filter.where()
+	query := `SELECT ` + feedbackColumns + ` FROM prompt_feedback` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	list := make([]*model.Feedback, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), append(args, limit, offset)...)
+	return list, err
+}
+
+func (r *Repo) Count(ctx context.Context, filter Filter) (int64, error) {
+	where, args := filter.where()
+	query := `SELECT COUNT(1) FROM prompt_feedback` + where
+	var count int64
+	err := r.db.GetContext(ctx, &count, r.db.Rebind(query), args...)
+	return count, err
+}
+
+// Stats 按版本聚合分数
+func (r *Repo) Stats(ctx context.Context, filter Filter) ([]*model.FeedbackStats, error) {
+	where, args := filter.where()
+	query := `
+		SELECT version_id,
+			COUNT(1) AS count,
+			AVG(score) AS avg_score,
+			MIN(score) AS min_score,
+			MAX(score) AS max_score,
+			SUM(CASE WHEN score > 0 THEN 1 ELSE 0 END) AS positive,
+			SUM(CASE WHEN score < 0 THEN 1 ELSE 0 END) AS negative
+		FROM prompt_feedback` + where + `
+		GROUP BY version_id
+	`
+	list := make([]*model.FeedbackStats, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), args...)
+	return list, err
+}
+
+// TagCounts 按版本统计标签次数, 只统计满足过滤条件的反馈
+func (r *Repo) TagCounts(ctx context.Context, filter Filter) ([]*model.FeedbackTagCount, error) {
+	where, args := filter.where()
+	query := `
+		SELECT version_id, tag, COUNT(1) AS count
+		FROM prompt_feedback_tags
+		WHERE feedback_id IN (SELECT id FROM prompt_feedback` + where + `)
+		GROUP BY version_id, tag
+	`
+	list := make([]*model.FeedbackTagCount, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), args...)
+	return list, err
+}
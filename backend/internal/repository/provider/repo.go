@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRepo interface {
+	CreateProvider(ctx context.Context, p *model.ProxyProvider) error
+	GetProvider(ctx context.Context, id string) (*model.ProxyProvider, error)
+	ListProviders(ctx context.Context) ([]*model.ProxyProvider, error)
+	UpdateProvider(ctx context.Context, p *model.ProxyProvider) error
+	DeleteProvider(ctx context.Context, id string) error
+	CountProviderRefs(ctx context.Context, providerID string) (int64, error)
+
+	CreateModel(ctx context.Context, m *model.ProxyModel, providerIDs []string) error
+	GetModel(ctx context.Context, id string) (*model.ProxyModel, error)
+	ListModels(ctx context.Context) ([]*model.ProxyModel, error)
+	UpdateModel(ctx context.Context, m *model.ProxyModel, providerIDs []string) error
+	DeleteModel(ctx context.Context, id string) error
+	ListEndpoints(ctx context.Context) ([]*model.ProxyModelEndpoint, error)
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateProviderRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) CreateProvider(ctx context.Context, p *model.ProxyProvider) error {
+	now := time.Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	query := `
+		INSERT INTO proxy_providers (id, name, type, api_base, api_key, headers, created_at, updated_at)
+		VALUES (:id, :name, :type, :api_base, :api_key, :headers, :created_at, :updated_at)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, p)
+	return err
+}
+
+func (r *Repo) GetProvider(ctx context.Context, id string) (*model.ProxyProvider, error) {
+	query := `SELECT id, name, type, api_base, api_key, headers, created_at, updated_at FROM proxy_providers WHERE id = ?`
+	var p model.ProxyProvider
+	err := r.db.GetContext(ctx, &p, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &p, err
+}
+
+func (r *Repo) ListProviders(ctx context.Context) ([]*model.ProxyProvider, error) {
+	query := `SELECT id, name, type, api_base, api_key, headers, created_at, updated_at FROM proxy_providers ORDER BY name`
+	list := make([]*model.ProxyProvider, 0)
+	err := r.db.SelectContext(ctx, &list, query)
+	return list, err
+}
+
+func (r *Repo) UpdateProvider(ctx context.Context, p *model.ProxyProvider) error {
+	p.UpdatedAt = time.Now()
+	query := `
+		UPDATE proxy_providers SET
+			name = :name,
+			type = :type,
+			api_base = :api_base,
+			api_key = :api_key,
+			headers = :headers,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	_, err := r.db.NamedExecContext(ctx, query, p)
+	return err
+}
+
+func (r *Repo) DeleteProvider(ctx context.Context, id string) error {
+	query := `DELETE FROM proxy_providers WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), id)
+	return err
+}
+
+// CountProviderRefs 统计引用该上游服务的模型数量
+func (r *Repo) CountProviderRefs(ctx context.Context, providerID string) (int64, error) {
+	query := `SELECT COUNT(*) FROM proxy_model_endpoints WHERE provider_id = ?`
+	var n int64
+	err := r.db.GetContext(ctx, &n, r.db.Rebind(query), providerID)
+	return n, err
+}
+
+func (r *Repo) CreateModel(ctx context.Context, m *model.ProxyModel, providerIDs []string) error {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO proxy_models (id, name, input_price, output_price, created_at, updated_at)
+		VALUES (:id, :name, :input_price, :output_price, :created_at, :updated_at)
+	`
+	if _, err := tx.NamedExecContext(ctx, query, m); err != nil {
+		return err
+	}
+	if err := r.insertEndpoints(ctx, tx, m.ID, providerIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) GetModel(ctx context.Context, id string) (*model.ProxyModel, error) {
+	query := `SELECT id, name, input_price, output_price, created_at, updated_at FROM proxy_models WHERE id = ?`
+	var m model.ProxyModel
+	err := r.db.GetContext(ctx, &m, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &m, err
+}
+
+func (r *Repo) ListModels(ctx context.Context) ([]*model.ProxyModel, error) {
+	query := `SELECT id, name, input_price, output_price, created_at, updated_at FROM proxy_models ORDER BY name`
+	list := make([]*model.ProxyModel, 0)
+	err := r.db.SelectContext(ctx, &list, query)
+	return list, err
+}
+
+// UpdateModel 更新模型并整体替换其上游映射
+func (r *Repo) UpdateModel(ctx context.Context, m *model.ProxyModel, providerIDs []string) error {
+	m.UpdatedAt = time.Now()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		UPDATE proxy_models SET
+			name = :name,
+			input_price = :input_price,
+			output_price = :output_price,
+			updated_at = :updated_at
+		WHERE id = :id
+	`
+	if _, err := tx.NamedExecContext(ctx, query, m); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM proxy_model_endpoints WHERE model_id = ?`), m.ID); err != nil {
+		return err
+	}
+	if err := r.insertEndpoints(ctx, tx, m.ID, providerIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) DeleteModel(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM proxy_model_endpoints WHERE model_id = ?`), id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM proxy_models WHERE id = ?`), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) ListEndpoints(ctx context.Context) ([]*model.ProxyModelEndpoint, error) {
+	query := `SELECT model_id, provider_id FROM proxy_model_endpoints ORDER BY model_id, position`
+	list := make([]*model.ProxyModelEndpoint, 0)
+	err := r.db.SelectContext(ctx, &list, query)
+	return list, err
+}
+
+// insertEndpoints 按顺序写入模型的上游映射, position 决定轮询顺序
+func (r *Repo) insertEndpoints(ctx context.Context, tx *sqlx.Tx, modelID string, providerIDs []string) error {
+	query := tx.Rebind(`INSERT INTO proxy_model_endpoints (model_id, provider_id, position) VALUES (?, ?, ?)`)
+	for i, providerID := range providerIDs {
+		if _, err := tx.ExecContext(ctx, query, modelID, providerID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"backend/internal/model"
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRepo interface {
+	Create(ctx context.Context, u *model.ProxyUsage) error
+	SumCostByUser(ctx context.Context, userID int64, since time.Time) (float64, error)
+	SumCostByDepartment(ctx context.Context, department string, since time.Time) (float64, error)
+	SumCostByKey(ctx context.Context, keyID string) (float64, error)
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateUsageRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) Create(ctx context.Context, u *model.ProxyUsage) error {
+	u.CreatedAt = time.Now()
+	query := `
+		INSERT INTO proxy_usage (
+			id, user_id, username, department, key_id, model, endpoint,
+			stream, status_code, prompt_tokens, completion_tokens,
+			cost, created_at
+		) VALUES (
+			:id, :user_id, :username, :department, :key_id, :model, :endpoint,
+			:stream, :status_code, :prompt_tokens, :completion_tokens,
+			:cost, :created_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, u)
+	return err
+}
+
+func (r *Repo) SumCostByUser(ctx context.Context, userID int64, since time.Time) (float64, error) {
+	const query = `
+		SELECT COALESCE(SUM(cost), 0)
+		FROM proxy_usage
+		WHERE user_id = ? AND created_at >= ?
+	`
+	var total float64
+	err := r.db.GetContext(ctx, &total, r.db.Rebind(query), userID, since)
+	return total, err
+}
+
+func (r *Repo) SumCostByDepartment(ctx context.Context, department string, since time.Time) (float64, error) {
+	const query = `
+		SELECT COALESCE(SUM(cost), 0)
+		FROM proxy_usage
+		WHERE department = ? AND created_at >= ?
+	`
+	var total float64
+	err := r.db.GetContext(ctx, &total, r.db.Rebind(query), department, since)
+	return total, err
+}
+
+func (r *Repo) SumCostByKey(ctx context.Context, keyID string) (float64, error) {
+	const query = `
+		SELECT COALESCE(SUM(cost), 0)
+		FROM proxy_usage
+		WHERE key_id = ?
+	`
+	var total float64
+	err := r.db.GetContext(ctx, &total, r.db.Rebind(query), keyID)
+	return total, err
+}
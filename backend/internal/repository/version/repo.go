@@ -15,6 +15,7 @@ type IRepo interface {
 	GetByID(ctx context.Context, id string) (*model.PromptVersion, error)
 	GetByPromptID(ctx context.Context, promptID string) ([]*model.PromptVersion, error)
 	GetLatestByPromptID(ctx context.Context, promptID string) (*model.PromptVersion, error)
+	GetPublishedIDs(ctx context.Context, promptID string) ([]string, error)
 	List(ctx context.Context, offset, limit int) ([]*model.PromptVersion, error)
 	Count(ctx context.Context) (int64, error)
 	DeleteByID(ctx context.Context, id string) error
@@ -93,6 +94,17 @@ func (r *Repo) GetByPromptID(ctx context.Context, promptID string) ([]*model.Pro
 	return list, err
 }
 
+// GetPublishedIDs 提示词下已发布版本的 ID, 不读取内容
+func (r *Repo) GetPublishedIDs(ctx context.Context, promptID string) ([]string, error) {
+	query := r.db.Rebind(`
+		SELECT id FROM prompt_version
+		WHERE prompt_id = ? AND is_publish = ?
+	`)
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, query, promptID, true)
+	return ids, err
+}
+
 func (r *Repo) GetLatestByPromptID(ctx context.Context, promptID string) (*model.PromptVersion, error) {
 	const query = `
 		SELECT id, prompt_id, version, content, variables,
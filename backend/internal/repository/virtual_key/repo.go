@@ -0,0 +1,102 @@
+package virtual_key
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRepo interface {
+	Create(ctx context.Context, k *model.VirtualKey) error
+	GetByID(ctx context.Context, id string) (*model.VirtualKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*model.VirtualKey, error)
+	ListByOwner(ctx context.Context, ownerID int64) ([]*model.VirtualKey, error)
+	UpdateHash(ctx context.Context, id, keyHash, keyPrefix string) error
+	Revoke(ctx context.Context, id string) error
+}
+
+type Repo struct {
+	db *sqlx.DB
+}
+
+func CreateVirtualKeyRepo(db *sqlx.DB) *Repo {
+	return &Repo{db: db}
+}
+
+const selectColumns = `
+	id, name, key_prefix, key_hash, owner_id, owner_name,
+	allowed_models, spend_limit, expires_at, is_revoked,
+	created_at, updated_at
+`
+
+func (r *Repo) Create(ctx context.Context, k *model.VirtualKey) error {
+	now := time.Now()
+	k.CreatedAt = now
+	k.UpdatedAt = now
+	query := `
+		INSERT INTO virtual_keys (
+			id, name, key_prefix, key_hash, owner_id, owner_name,
+			allowed_models, spend_limit, expires_at, is_revoked,
+			created_at, updated_at
+		) VALUES (
+			:id, :name, :key_prefix, :key_hash, :owner_id, :owner_name,
+			:allowed_models, :spend_limit, :expires_at, :is_revoked,
+			:created_at, :updated_at
+		)
+	`
+	_, err := r.db.NamedExecContext(ctx, query, k)
+	return err
+}
+
+func (r *Repo) GetByID(ctx context.Context, id string) (*model.VirtualKey, error) {
+	query := `SELECT ` + selectColumns + ` FROM virtual_keys WHERE id = ?`
+	var k model.VirtualKey
+	err := r.db.GetContext(ctx, &k, r.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &k, err
+}
+
+func (r *Repo) GetByHash(ctx context.Context, keyHash string) (*model.VirtualKey, error) {
+	query := `SELECT ` + selectColumns + ` FROM virtual_keys WHERE key_hash = ?`
+	var k model.VirtualKey
+	err := r.db.GetContext(ctx, &k, r.db.Rebind(query), keyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &k, err
+}
+
+func (r *Repo) ListByOwner(ctx context.Context, ownerID int64) ([]*model.VirtualKey, error) {
+	query := `SELECT ` + selectColumns + ` FROM virtual_keys WHERE owner_id = ? ORDER BY created_at DESC`
+	list := make([]*model.VirtualKey, 0)
+	err := r.db.SelectContext(ctx, &list, r.db.Rebind(query), ownerID)
+	return list, err
+}
+
+func (r *Repo) UpdateHash(ctx context.Context, id, keyHash, keyPrefix string) error {
+	const query = `
+		UPDATE virtual_keys SET
+			key_hash = ?,
+			key_prefix = ?,
+			updated_at = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), keyHash, keyPrefix, time.Now(), id)
+	return err
+}
+
+func (r *Repo) Revoke(ctx context.Context, id string) error {
+	const query = `
+		UPDATE virtual_keys SET
+			is_revoked = ?,
+			updated_at = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(query), true, time.Now(), id)
+	return err
+}
@@ -0,0 +1,195 @@
+package capture
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository/capture"
+	"backend/pkg/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+)
+
+var (
+	ErrCaptureNotFound = errors.New("capture not found")
+	ErrNotReplayable   = errors.New("captured request body is truncated or invalid, cannot replay")
+	ErrDatabaseErr     = errors.New("query error, please contact admin")
+)
+
+const (
+	defaultMaxBodyBytes = 256 << 10
+	redacted            = "[REDACTED]"
+	truncatedMark       = "...[truncated]"
+)
+
+// 内置脱敏规则, 配置中的规则在此基础上追加
+var (
+	defaultRedactHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+		"X-Api-Key", "Api-Key", "X-Goog-Api-Key",
+	}
+	defaultRedactPatterns = []string{
+		`sk-[A-Za-z0-9_\-]{8,}`,
+		`pm-[0-9a-f]{16,}`,
+		`AIza[0-9A-Za-z_\-]{20,}`,
+		`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`,
+	}
+)
+
+type IService interface {
+	Enabled() bool
+	Sample(promptID, replayOf string) bool
+	BodyLimit() int
+	Record(ctx context.Context, c *model.ProxyCapture, headers http.Header) error
+	List(ctx context.Context, filter capture.Filter, offset, limit int) ([]*model.ProxyCapture, int64, error)
+	GetByID(ctx context.Context, id string) (*model.ProxyCapture, error)
+	ReplayBody(c *model.ProxyCapture, modelName string) ([]byte, error)
+}
+
+type Service struct {
+	repo           *capture.Repo
+	cfg            *config.Config
+	logger         *zap.Logger
+	redactHeaders  []string
+	redactPatterns []*regexp.Regexp
+}
+
+func CreateCaptureService(repo *capture.Repo, cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:          repo,
+		cfg:           cfg,
+		logger:        logger,
+		redactHeaders: append(append([]string{}, defaultRedactHeaders...), cfg.Proxy.Capture.RedactHeaders...),
+	}
+	for _, expr := range append(append([]string{}, defaultRedactPatterns...), cfg.Proxy.Capture.RedactPatterns...) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			logger.Warn("invalid capture redact pattern, ignored", zap.String("pattern", expr), zap.Error(err))
+			continue
+		}
+		s.redactPatterns = append(s.redactPatterns, re)
+	}
+	return s
+}
+
+func (s *Service) Enabled() bool {
+	return s.cfg.Proxy.Capture.Enabled
+}
+
+// Sample 关联 prompt 的调试请求和重放请求总是记录, 其余按采样率记录, 采样率未配置时全部记录
+func (s *Service) Sample(promptID, replayOf string) bool {
+	if !s.Enabled() {
+		return false
+	}
+	if promptID != "" || replayOf != "" {
+		return true
+	}
+	rate := s.cfg.Proxy.Capture.SampleRate
+	switch {
+	case rate == nil || *rate >= 1:
+		return true
+	case *rate <= 0:
+		return false
+	}
+	return rand.Float64() < *rate
+}
+
+// BodyLimit 单个请求/响应体保存的最大字节数
+func (s *Service) BodyLimit() int {
+	if s.cfg.Proxy.Capture.MaxBodyBytes > 0 {
+		return s.cfg.Proxy.Capture.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// Record 脱敏、截断后落库
+func (s *Service) Record(ctx context.Context, c *model.ProxyCapture, headers http.Header) error {
+	c.ID = uuid.New().String()
+	c.RequestHeaders = s.redactHeaderJSON(headers)
+	c.UpstreamURL = s.redact(c.UpstreamURL)
+	c.RequestBody = s.clean(c.RequestBody)
+	c.UpstreamBody = s.clean(c.UpstreamBody)
+	c.ResponseBody = s.clean(c.ResponseBody)
+	if err := s.repo.Create(ctx, c); err != nil {
+		s.logger.Error("create capture failed", zap.Error(err))
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+func (s *Service) List(ctx context.Context, filter capture.Filter, offset, limit int) ([]*model.ProxyCapture, int64, error) {
+	list, err := s.repo.List(ctx, filter, offset, limit)
+	if err != nil {
+		s.logger.Error("list captures failed", zap.Error(err))
+		return nil, 0, ErrDatabaseErr
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error("count captures failed", zap.Error(err))
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, total, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id string) (*model.ProxyCapture, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("get capture failed", zap.String("id", id), zap.Error(err))
+		return nil, ErrDatabaseErr
+	}
+	if c == nil {
+		return nil, ErrCaptureNotFound
+	}
+	return c, nil
+}
+
+// ReplayBody 将抓取到的原始请求体中的 model 替换为目标模型, modelName 为空时沿用原模型
+func (s *Service) ReplayBody(c *model.ProxyCapture, modelName string) ([]byte, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(c.RequestBody), &body); err != nil {
+		return nil, ErrNotReplayable
+	}
+	if modelName == "" {
+		modelName = c.Model
+	}
+	raw, _ := json.Marshal(modelName)
+	body["model"] = raw
+	return json.Marshal(body)
+}
+
+func (s *Service) redactHeaderJSON(headers http.Header) string {
+	h := headers.Clone()
+	for _, name := range s.redactHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, redacted)
+		}
+	}
+	for k, values := range h {
+		for i, v := range values {
+			values[i] = s.redact(v)
+		}
+		h[k] = values
+	}
+	b, _ := json.Marshal(h)
+	return string(b)
+}
+
+// clean 先脱敏再截断, 避免截断处残留的半个 key 不再匹配脱敏规则
+func (s *Service) clean(body string) string {
+	body = s.redact(body)
+	if limit := s.BodyLimit(); len(body) > limit {
+		body = body[:limit] + truncatedMark
+	}
+	return body
+}
+
+func (s *Service) redact(v string) string {
+	for _, re := range s.redactPatterns {
+		v = re.ReplaceAllString(v, redacted)
+	}
+	return v
+}
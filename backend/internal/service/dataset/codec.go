@@ -0,0 +1,243 @@
+package dataset
+
+import (
+	"backend/internal/model"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+
+	// 导入导出时的保留列, 其余列均为变量
+	columnExpected  = "expected"
+	columnReference = "reference"
+	columnVariables = "variables"
+
+	maxImportRows    = 50000
+	maxJSONLLineSize = 4 << 20
+)
+
+// ImportError 导入文件格式错误, Line 从 1 开始
+type ImportError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("invalid import file at line %d: %s", e.Line, e.Reason)
+}
+
+// DetectFormat 未指定格式时按文件扩展名判断
+func DetectFormat(format, filename string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	return ""
+}
+
+// ParseRows 解析 csv / jsonl 文件, 评测任务上传的文件也按此格式
+func ParseRows(format string, r io.Reader) ([]*model.DatasetRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSONL:
+		return parseJSONL(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// parseCSV 第一行为表头, expected / reference 列为期望输出和参考资料, 其余列为变量
+func parseCSV(r io.Reader) ([]*model.DatasetRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &ImportError{Line: 1, Reason: "missing header"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if name == "" || seen[name] {
+			return nil, &ImportError{Line: 1, Reason: fmt.Sprintf("empty or duplicate column %q", name)}
+		}
+		seen[name] = true
+		header[i] = name
+	}
+
+	rows := make([]*model.DatasetRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(rows) >= maxImportRows {
+			return nil, ErrTooManyRows
+		}
+
+		row := &model.DatasetRow{}
+		vars := make(map[string]string)
+		for i, value := range record {
+			switch header[i] {
+			case columnExpected:
+				row.Expected = value
+			case columnReference:
+				row.Reference = value
+			default:
+				vars[header[i]] = value
+			}
+		}
+		row.Variables = encodeVariables(vars)
+		rows = append(rows, row)
+	}
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &ImportError{Line: parseErr.Line, Reason: parseErr.Err.Error()}
+	}
+	return &ImportError{Line: 1, Reason: err.Error()}
+}
+
+// parseJSONL 每行一个对象, 支持 {"variables": {...}, "expected": "", "reference": ""}
+// 或者扁平对象(除 expected / reference 外的字段均为变量); 非字符串的值按 JSON 编码保存
+func parseJSONL(r io.Reader) ([]*model.DatasetRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLineSize)
+
+	rows := make([]*model.DatasetRow, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, ErrTooManyRows
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(text, &obj); err != nil {
+			return nil, &ImportError{Line: line, Reason: "invalid json object"}
+		}
+		row := &model.DatasetRow{
+			Expected:  jsonString(obj[columnExpected]),
+			Reference: jsonString(obj[columnReference]),
+		}
+		delete(obj, columnExpected)
+		delete(obj, columnReference)
+
+		fields := obj
+		if nested, ok := obj[columnVariables]; ok && len(obj) == 1 {
+			fields = nil
+			if err := json.Unmarshal(nested, &fields); err != nil {
+				return nil, &ImportError{Line: line, Reason: "variables must be an object"}
+			}
+		}
+		vars := make(map[string]string, len(fields))
+		for k, v := range fields {
+			vars[k] = jsonString(v)
+		}
+		row.Variables = encodeVariables(vars)
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ImportError{Line: line + 1, Reason: err.Error()}
+	}
+	return rows, nil
+}
+
+// jsonString 字符串取原值, null 为空, 其余按 JSON 原文
+func jsonString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func encodeVariables(vars map[string]string) string {
+	if vars == nil {
+		return "{}"
+	}
+	b, _ := json.Marshal(vars)
+	return string(b)
+}
+
+// writeCSV 变量列按名称排序, 最后是 expected / reference
+func writeCSV(w io.Writer, rows []*model.DatasetRow) error {
+	names := make(map[string]bool)
+	vars := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		vars[i] = row.VariableMap()
+		for name := range vars[i] {
+			names[name] = true
+		}
+	}
+	columns := make([]string, 0, len(names)+2)
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, columns...), columnExpected, columnReference)); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		record := make([]string, 0, len(columns)+2)
+		for _, name := range columns {
+			record = append(record, vars[i][name])
+		}
+		if err := writer.Write(append(record, row.Expected, row.Reference)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type jsonlRow struct {
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+	Reference string            `json:"reference"`
+}
+
+func writeJSONL(w io.Writer, rows []*model.DatasetRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, row := range rows {
+		if err := enc.Encode(jsonlRow{Variables: row.VariableMap(), Expected: row.Expected, Reference: row.Reference}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package dataset
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/dataset"
+	"backend/internal/repository/prompt"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrDatasetNotFound   = errors.New("dataset not found")
+	ErrPromptNotFound    = errors.New("prompt not found")
+	ErrRowNotFound       = errors.New("dataset row not found")
+	ErrRowReadonly       = errors.New("rows of a dataset version are read-only")
+	ErrVersionNotFound   = errors.New("dataset version not found")
+	ErrEmptyDataset      = errors.New("dataset has no rows")
+	ErrUnsupportedFormat = errors.New("unsupported format, use csv or jsonl")
+	ErrTooManyRows       = errors.New("too many rows in import file")
+	ErrDatabaseErr       = errors.New("query error, please contact admin")
+)
+
+type IService interface {
+	Create(ctx context.Context, userID int64, username string, req dto.CreateDatasetDTO) (*model.Dataset, error)
+	Update(ctx context.Context, req dto.UpdateDatasetDTO) (*model.Dataset, error)
+	GetByID(ctx context.Context, id string) (*model.Dataset, error)
+	List(ctx context.Context, promptID string, offset, limit int) ([]*model.Dataset, int64, error)
+	Delete(ctx context.Context, id string) error
+
+	ListRows(ctx context.Context, id string, version, offset, limit int) ([]*model.DatasetRow, int64, error)
+	Rows(ctx context.Context, id string, version int) ([]*model.DatasetRow, error)
+	CreateRow(ctx context.Context, req dto.CreateDatasetRowDTO) (*model.DatasetRow, error)
+	UpdateRow(ctx context.Context, req dto.UpdateDatasetRowDTO) (*model.DatasetRow, error)
+	DeleteRow(ctx context.Context, id string) error
+	Import(ctx context.Context, id, format string, r io.Reader, replace bool) (int, error)
+	Export(ctx context.Context, id string, version int, format string) ([]byte, error)
+
+	CreateVersion(ctx context.Context, id string, userID int64, username string, req dto.CreateDatasetVersionDTO) (*model.DatasetVersion, error)
+	ListVersions(ctx context.Context, id string) ([]*model.DatasetVersion, error)
+}
+
+type Service struct {
+	repo       *dataset.Repo
+	promptRepo *prompt.Repo
+	logger     *zap.Logger
+}
+
+func CreateDatasetService(repo *dataset.Repo, promptRepo *prompt.Repo, logger *zap.Logger) *Service {
+	return &Service{
+		repo:       repo,
+		promptRepo: promptRepo,
+		logger:     logger,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, userID int64, username string, req dto.CreateDatasetDTO) (*model.Dataset, error) {
+	p, err := s.promptRepo.GetByID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+
+	d := &model.Dataset{
+		ID:          uuid.New().String(),
+		PromptID:    req.PromptID,
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   strconv.FormatInt(userID, 10),
+		Username:    username,
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return d, nil
+}
+
+func (s *Service) Update(ctx context.Context, req dto.UpdateDatasetDTO) (*model.Dataset, error) {
+	d, err := s.GetByID(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.Name = req.Name
+	d.Description = req.Description
+	if err := s.repo.Update(ctx, d); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return d, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id string) (*model.Dataset, error) {
+	d, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if d == nil {
+		return nil, ErrDatasetNotFound
+	}
+	return d, nil
+}
+
+func (s *Service) List(ctx context.Context, promptID string, offset, limit int) ([]*model.Dataset, int64, error) {
+	list, err := s.repo.List(ctx, promptID, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.Count(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteByID(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// checkVersion version 为 0 时是当前可编辑数据, 其余必须是已有快照
+func (s *Service) checkVersion(ctx context.Context, id string, version int) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if version == model.DatasetDraft {
+		return nil
+	}
+	v, err := s.repo.GetVersion(ctx, id, version)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if v == nil {
+		return ErrVersionNotFound
+	}
+	return nil
+}
+
+func (s *Service) ListRows(ctx context.Context, id string, version, offset, limit int) ([]*model.DatasetRow, int64, error) {
+	if err := s.checkVersion(ctx, id, version); err != nil {
+		return nil, 0, err
+	}
+	list, err := s.repo.ListRows(ctx, id, version, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.CountRows(ctx, id, version)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+// Rows 返回指定版本的全部数据行
+func (s *Service) Rows(ctx context.Context, id string, version int) ([]*model.DatasetRow, error) {
+	if err := s.checkVersion(ctx, id, version); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.AllRows(ctx, id, version)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return rows, nil
+}
+
+func (s *Service) CreateRow(ctx context.Context, req dto.CreateDatasetRowDTO) (*model.DatasetRow, error) {
+	if _, err := s.GetByID(ctx, req.DatasetID); err != nil {
+		return nil, err
+	}
+	row := &model.DatasetRow{
+		ID:        uuid.New().String(),
+		DatasetID: req.DatasetID,
+		Variables: encodeVariables(req.Variables),
+		Expected:  req.Expected,
+		Reference: req.Reference,
+	}
+	if err := s.repo.CreateRow(ctx, row); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return row, nil
+}
+
+// getDraftRow 只有当前可编辑数据可以修改
+func (s *Service) getDraftRow(ctx context.Context, id string) (*model.DatasetRow, error) {
+	row, err := s.repo.GetRow(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if row == nil {
+		return nil, ErrRowNotFound
+	}
+	if row.Version != model.DatasetDraft {
+		return nil, ErrRowReadonly
+	}
+	return row, nil
+}
+
+func (s *Service) UpdateRow(ctx context.Context, req dto.UpdateDatasetRowDTO) (*model.DatasetRow, error) {
+	row, err := s.getDraftRow(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	row.Variables = encodeVariables(req.Variables)
+	row.Expected = req.Expected
+	row.Reference = req.Reference
+	if err := s.repo.UpdateRow(ctx, row); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return row, nil
+}
+
+func (s *Service) DeleteRow(ctx context.Context, id string) error {
+	row, err := s.getDraftRow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRow(ctx, row); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// Import 导入到当前可编辑数据, 返回导入行数; 文件有误时不写入任何数据
+func (s *Service) Import(ctx context.Context, id, format string, r io.Reader, replace bool) (int, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return 0, err
+	}
+	rows, err := ParseRows(format, r)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		row.ID = uuid.New().String()
+	}
+	if err := s.repo.ImportRows(ctx, id, rows, replace); err != nil {
+		s.logger.Error(err.Error())
+		return 0, ErrDatabaseErr
+	}
+	return len(rows), nil
+}
+
+func (s *Service) Export(ctx context.Context, id string, version int, format string) ([]byte, error) {
+	if format != FormatCSV && format != FormatJSONL {
+		return nil, ErrUnsupportedFormat
+	}
+	rows, err := s.Rows(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if format == FormatCSV {
+		err = writeCSV(&buf, rows)
+	} else {
+		err = writeJSONL(&buf, rows)
+	}
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return buf.Bytes(), nil
+}
+
+// CreateVersion 将当前可编辑数据保存为新的只读快照, 版本号递增
+func (s *Service) CreateVersion(ctx context.Context, id string, userID int64, username string, req dto.CreateDatasetVersionDTO) (*model.DatasetVersion, error) {
+	d, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.RowCount == 0 {
+		return nil, ErrEmptyDataset
+	}
+
+	v := &model.DatasetVersion{
+		ID:        uuid.New().String(),
+		DatasetID: id,
+		Version:   d.Version + 1,
+		ChangeLog: req.ChangeLog,
+		CreatedBy: strconv.FormatInt(userID, 10),
+		Username:  username,
+	}
+	if err := s.repo.CreateVersion(ctx, v); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return v, nil
+}
+
+func (s *Service) ListVersions(ctx context.Context, id string) ([]*model.DatasetVersion, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListVersions(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return list, nil
+}
@@ -0,0 +1,150 @@
+package eval
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/service/eval/scorer"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"strconv"
+)
+
+// AssertionError 断言参数有误
+type AssertionError struct {
+	Err error
+}
+
+func (e *AssertionError) Error() string {
+	return "invalid assertions: " + e.Err.Error()
+}
+
+func newScorer(assertions []scorer.Assertion) (*scorer.Scorer, error) {
+	sc, err := scorer.New(assertions)
+	if err != nil {
+		return nil, &AssertionError{Err: err}
+	}
+	return sc, nil
+}
+
+// Score 对一批输出执行断言; 未直接提供断言时使用保存的断言集
+func (s *Service) Score(ctx context.Context, req dto.ScoreDTO) (*scorer.Report, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(req.Items) > s.maxRows {
+		return nil, ErrTooManyRows
+	}
+	assertions := req.Assertions
+	if len(assertions) == 0 {
+		if req.AssertionSetID == "" {
+			return nil, ErrNoAssertions
+		}
+		set, err := s.GetAssertionSet(ctx, req.AssertionSetID)
+		if err != nil {
+			return nil, err
+		}
+		if assertions, err = decodeAssertions(set); err != nil {
+			s.logger.Error(err.Error())
+			return nil, ErrDatabaseErr
+		}
+	}
+	sc, err := newScorer(assertions)
+	if err != nil {
+		return nil, err
+	}
+	return sc.ScoreAll(req.Items), nil
+}
+
+func decodeAssertions(set *model.EvalAssertionSet) ([]scorer.Assertion, error) {
+	var assertions []scorer.Assertion
+	err := json.Unmarshal([]byte(set.Assertions), &assertions)
+	return assertions, err
+}
+
+func (s *Service) CreateAssertionSet(ctx context.Context, userID int64, username string, req dto.CreateAssertionSetDTO) (*model.EvalAssertionSet, error) {
+	p, err := s.promptRepo.GetByID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+	if _, err := newScorer(req.Assertions); err != nil {
+		return nil, err
+	}
+	assertions, _ := json.Marshal(req.Assertions)
+
+	set := &model.EvalAssertionSet{
+		ID:          uuid.New().String(),
+		PromptID:    req.PromptID,
+		Name:        req.Name,
+		Description: req.Description,
+		Assertions:  string(assertions),
+		CreatedBy:   strconv.FormatInt(userID, 10),
+		Username:    username,
+	}
+	if err := s.repo.CreateAssertionSet(ctx, set); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return set, nil
+}
+
+func (s *Service) UpdateAssertionSet(ctx context.Context, req dto.UpdateAssertionSetDTO) (*model.EvalAssertionSet, error) {
+	set, err := s.GetAssertionSet(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := newScorer(req.Assertions); err != nil {
+		return nil, err
+	}
+	assertions, _ := json.Marshal(req.Assertions)
+
+	set.Name = req.Name
+	set.Description = req.Description
+	set.Assertions = string(assertions)
+	if err := s.repo.UpdateAssertionSet(ctx, set); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return set, nil
+}
+
+func (s *Service) GetAssertionSet(ctx context.Context, id string) (*model.EvalAssertionSet, error) {
+	set, err := s.repo.GetAssertionSet(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if set == nil {
+		return nil, ErrAssertionSetNotFound
+	}
+	return set, nil
+}
+
+func (s *Service) ListAssertionSets(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalAssertionSet, int64, error) {
+	list, err := s.repo.ListAssertionSets(ctx, promptID, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.CountAssertionSets(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+func (s *Service) DeleteAssertionSet(ctx context.Context, id string) error {
+	if _, err := s.GetAssertionSet(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAssertionSet(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"backend/pkg/config"
+	"backend/pkg/jwt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultRequestTimeout = 5 * time.Minute
+
+// chatMessage OpenAI chat 消息
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatResult 一次模型调用的输出与用量
+type chatResult struct {
+	Output           string
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+// proxyClient 经模型代理调用模型, 以任务创建者的身份鉴权, 复用代理的路由、计费和限流
+type proxyClient struct {
+	baseURL string
+	secret  string
+	timeout time.Duration
+	http    *http.Client
+}
+
+func newProxyClient(cfg *config.Config) *proxyClient {
+	timeout := cfg.Eval.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	return &proxyClient{
+		baseURL: fmt.Sprintf("http://%s:%v", cfg.Proxy.Server.Host, cfg.Proxy.Server.Port),
+		secret:  cfg.Security.SecretKey,
+		timeout: timeout,
+		http:    &http.Client{},
+	}
+}
+
+// chat 调用 /v1/chat/completions, params 中的字段(temperature 等)合并到请求体
+func (p *proxyClient) chat(ctx context.Context, userID int64, username, model string, messages []chatMessage, params map[string]any) (*chatResult, error) {
+	payload := make(map[string]any, len(params)+2)
+	for k, v := range params {
+		payload[k] = v
+	}
+	payload["model"] = model
+	payload["messages"] = messages
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次请求签发短期 token, 任务执行时间再长也不会过期
+	token, err := jwt.GenerateToken(userID, username, p.secret, p.timeout+time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	// 批量任务让位于交互请求
+	req.Header.Set("X-Proxy-Priority", "batch")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model returned status %d: %s", resp.StatusCode, errorMessage(respBody))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, fmt.Errorf("invalid model response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("model returned no choices")
+	}
+
+	cost, _ := strconv.ParseFloat(resp.Header.Get("X-Proxy-Cost"), 64)
+	return &chatResult{
+		Output:           completion.Choices[0].Message.Content,
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		Cost:             cost,
+	}, nil
+}
+
+// errorMessage 提取代理({"error": "..."})或上游({"error": {"message": "..."}})的错误信息
+func errorMessage(body []byte) string {
+	var v struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &v); err == nil && len(v.Error) > 0 {
+		var msg string
+		if json.Unmarshal(v.Error, &msg) == nil {
+			return msg
+		}
+		var obj struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(v.Error, &obj) == nil && obj.Message != "" {
+			return obj.Message
+		}
+	}
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return string(body)
+}
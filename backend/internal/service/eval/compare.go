@@ -0,0 +1,470 @@
+package eval
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/service/eval/scorer"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+)
+
+// 对比中每行的变化
+const (
+	ChangeRegression  = "regression"
+	ChangeImprovement = "improvement"
+	ChangeUnchanged   = "unchanged"
+)
+
+// CompareSide 一行在一个版本上的结果; 配置了断言时才有 Score / Pass
+type CompareSide struct {
+	Status           string   `json:"status"`
+	Input            string   `json:"input"`
+	Output           string   `json:"output"`
+	Error            string   `json:"error"`
+	Score            *float64 `json:"score"`
+	Pass             *bool    `json:"pass"`
+	Failures         []string `json:"failures,omitempty"`
+	LatencyMs        int64    `json:"latencyMs"`
+	PromptTokens     int64    `json:"promptTokens"`
+	CompletionTokens int64    `json:"completionTokens"`
+	Cost             float64  `json:"cost"`
+}
+
+// CompareRow 同一输入在两个版本上的结果, Delta 均为候选版本减基线版本
+type CompareRow struct {
+	Position       int               `json:"position"`
+	Variables      map[string]string `json:"variables"`
+	Expected       string            `json:"expected"`
+	Base           *CompareSide      `json:"base"`
+	Candidate      *CompareSide      `json:"candidate"`
+	ScoreDelta     *float64          `json:"scoreDelta"`
+	LatencyDeltaMs int64             `json:"latencyDeltaMs"`
+	TokenDelta     int64             `json:"tokenDelta"`
+	CostDelta      float64           `json:"costDelta"`
+	Change         string            `json:"change"`
+	Reasons        []string          `json:"reasons,omitempty"`
+}
+
+// CompareSummary 一个版本的汇总, PassRate / MeanScore 只统计成功的行
+type CompareSummary struct {
+	RunID            string   `json:"runId"`
+	VersionID        string   `json:"versionId"`
+	Version          string   `json:"version"`
+	Status           string   `json:"status"`
+	Total            int      `json:"total"`
+	Succeeded        int      `json:"succeeded"`
+	Failed           int      `json:"failed"`
+	Passed           int      `json:"passed"`
+	PassRate         *float64 `json:"passRate"`
+	MeanScore        *float64 `json:"meanScore"`
+	MeanLatencyMs    float64  `json:"meanLatencyMs"`
+	PromptTokens     int64    `json:"promptTokens"`
+	CompletionTokens int64    `json:"completionTokens"`
+	Cost             float64  `json:"cost"`
+}
+
+// CompareReport 对比报告; 两个任务都结束前只有进度, Rows 为空
+type CompareReport struct {
+	ID                  string          `json:"id"`
+	PromptID            string          `json:"promptId"`
+	Status              string          `json:"status"`
+	Progress            float64         `json:"progress"`
+	RegressionThreshold float64         `json:"regressionThreshold"`
+	Base                *CompareSummary `json:"base"`
+	Candidate           *CompareSummary `json:"candidate"`
+	ScoreDelta          *float64        `json:"scoreDelta"`
+	PassRateDelta       *float64        `json:"passRateDelta"`
+	MeanLatencyDeltaMs  float64         `json:"meanLatencyDeltaMs"`
+	TokenDelta          int64           `json:"tokenDelta"`
+	CostDelta           float64         `json:"costDelta"`
+	Regressions         int             `json:"regressions"`
+	Improvements        int             `json:"improvements"`
+	Unchanged           int             `json:"unchanged"`
+	Rows                []*CompareRow   `json:"rows"`
+	RowTotal            int             `json:"rowTotal"`
+}
+
+// CreateComparison 用两个版本对同一批输入各创建一个评测任务
+func (s *Service) CreateComparison(ctx context.Context, userID int64, username string, req dto.CreateEvalCompareDTO, file io.Reader, format string) (*model.EvalComparison, error) {
+	base, err := s.getVersion(ctx, req.BaseVersionID)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := s.getVersion(ctx, req.CandidateVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if base.PromptID != candidate.PromptID {
+		return nil, ErrVersionMismatch
+	}
+
+	assertions := req.Assertions
+	if len(assertions) == 0 && req.AssertionSetID != "" {
+		set, err := s.GetAssertionSet(ctx, req.AssertionSetID)
+		if err != nil {
+			return nil, err
+		}
+		if assertions, err = decodeAssertions(set); err != nil {
+			s.logger.Error(err.Error())
+			return nil, ErrDatabaseErr
+		}
+	}
+	if len(assertions) > 0 {
+		if _, err := newScorer(assertions); err != nil {
+			return nil, err
+		}
+	} else {
+		assertions = []scorer.Assertion{}
+	}
+	assertionsJSON, _ := json.Marshal(assertions)
+
+	runReq := dto.CreateEvalRunDTO{
+		Model:          req.Model,
+		DatasetID:      req.DatasetID,
+		DatasetVersion: req.DatasetVersion,
+		Concurrency:    req.Concurrency,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+	}
+	rows, err := s.loadRows(ctx, base, runReq, file, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > s.maxRows {
+		return nil, ErrTooManyRows
+	}
+
+	runReq.VersionID = base.ID
+	baseRun, err := s.startRun(ctx, userID, username, base, runReq, file != nil, rows)
+	if err != nil {
+		return nil, err
+	}
+	runReq.VersionID = candidate.ID
+	candidateRun, err := s.startRun(ctx, userID, username, candidate, runReq, file != nil, rows)
+	if err != nil {
+		_ = s.Cancel(ctx, baseRun.ID)
+		return nil, err
+	}
+
+	c := &model.EvalComparison{
+		ID:                  uuid.New().String(),
+		PromptID:            base.PromptID,
+		BaseVersionID:       base.ID,
+		CandidateVersionID:  candidate.ID,
+		BaseRunID:           baseRun.ID,
+		CandidateRunID:      candidateRun.ID,
+		Assertions:          string(assertionsJSON),
+		RegressionThreshold: req.RegressionThreshold,
+		UserID:              userID,
+		Username:            username,
+	}
+	if err := s.repo.CreateComparison(ctx, c); err != nil {
+		s.logger.Error(err.Error())
+		_ = s.Cancel(ctx, baseRun.ID)
+		_ = s.Cancel(ctx, candidateRun.ID)
+		return nil, ErrDatabaseErr
+	}
+	return c, nil
+}
+
+func (s *Service) GetComparison(ctx context.Context, id string) (*model.EvalComparison, error) {
+	c, err := s.repo.GetComparison(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if c == nil {
+		return nil, ErrComparisonNotFound
+	}
+	return c, nil
+}
+
+func (s *Service) ListComparisons(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalComparison, int64, error) {
+	list, err := s.repo.ListComparisons(ctx, promptID, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.CountComparisons(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+// CancelComparison 取消两个任务中尚未结束的任务
+func (s *Service) CancelComparison(ctx context.Context, id string) error {
+	c, err := s.GetComparison(ctx, id)
+	if err != nil {
+		return err
+	}
+	canceled := false
+	for _, runID := range []string{c.BaseRunID, c.CandidateRunID} {
+		switch err := s.Cancel(ctx, runID); err {
+		case nil:
+			canceled = true
+		case ErrRunFinished:
+		default:
+			return err
+		}
+	}
+	if !canceled {
+		return ErrRunFinished
+	}
+	return nil
+}
+
+// CompareReport 生成对比报告; change 不为空时只返回该类变化的行, 行按 offset / limit 分页
+func (s *Service) CompareReport(ctx context.Context, id, change string, offset, limit int) (*CompareReport, error) {
+	c, err := s.GetComparison(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	baseRun, err := s.GetRun(ctx, c.BaseRunID)
+	if err != nil {
+		return nil, err
+	}
+	candidateRun, err := s.GetRun(ctx, c.CandidateRunID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CompareReport{
+		ID:                  c.ID,
+		PromptID:            c.PromptID,
+		Status:              comparisonStatus(baseRun, candidateRun),
+		RegressionThreshold: c.RegressionThreshold,
+		Base:                s.summary(ctx, baseRun),
+		Candidate:           s.summary(ctx, candidateRun),
+		Rows:                make([]*CompareRow, 0),
+	}
+	if total := baseRun.Total + candidateRun.Total; total > 0 {
+		done := baseRun.Succeeded + baseRun.Failed + candidateRun.Succeeded + candidateRun.Failed
+		report.Progress = float64(done) / float64(total)
+	}
+	if !baseRun.Finished() || !candidateRun.Finished() {
+		return report, nil
+	}
+
+	var sc *scorer.Scorer
+	var assertions []scorer.Assertion
+	if err := json.Unmarshal([]byte(c.Assertions), &assertions); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if len(assertions) > 0 {
+		if sc, err = newScorer(assertions); err != nil {
+			return nil, err
+		}
+	}
+
+	baseResults, err := s.repo.AllResults(ctx, baseRun.ID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	candidateResults, err := s.repo.AllResults(ctx, candidateRun.ID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	byPosition := make(map[int]*model.EvalResult, len(candidateResults))
+	for _, r := range candidateResults {
+		byPosition[r.Position] = r
+	}
+
+	rows := make([]*CompareRow, 0, len(baseResults))
+	for _, b := range baseResults {
+		cand, ok := byPosition[b.Position]
+		if !ok {
+			continue
+		}
+		row := compareRow(b, cand, sc, c.RegressionThreshold)
+		rows = append(rows, row)
+		switch row.Change {
+		case ChangeRegression:
+			report.Regressions++
+		case ChangeImprovement:
+			report.Improvements++
+		default:
+			report.Unchanged++
+		}
+	}
+	sideSummary(report.Base, rows, func(r *CompareRow) *CompareSide { return r.Base }, sc != nil)
+	sideSummary(report.Candidate, rows, func(r *CompareRow) *CompareSide { return r.Candidate }, sc != nil)
+
+	if report.Base.MeanScore != nil && report.Candidate.MeanScore != nil {
+		d := *report.Candidate.MeanScore - *report.Base.MeanScore
+		report.ScoreDelta = &d
+	}
+	if report.Base.PassRate != nil && report.Candidate.PassRate != nil {
+		d := *report.Candidate.PassRate - *report.Base.PassRate
+		report.PassRateDelta = &d
+	}
+	report.MeanLatencyDeltaMs = report.Candidate.MeanLatencyMs - report.Base.MeanLatencyMs
+	report.TokenDelta = report.Candidate.PromptTokens + report.Candidate.CompletionTokens -
+		report.Base.PromptTokens - report.Base.CompletionTokens
+	report.CostDelta = report.Candidate.Cost - report.Base.Cost
+
+	filtered := rows
+	if change != "" {
+		filtered = make([]*CompareRow, 0)
+		for _, r := range rows {
+			if r.Change == change {
+				filtered = append(filtered, r)
+			}
+		}
+	}
+	report.RowTotal = len(filtered)
+	offset, limit = max(offset, 0), max(limit, 0)
+	if offset < len(filtered) {
+		report.Rows = filtered[offset : offset+min(limit, len(filtered)-offset)]
+	}
+	return report, nil
+}
+
+// comparisonStatus 两个任务都完成才算完成, 否则取未结束或失败的状态
+func comparisonStatus(base, candidate *model.EvalRun) string {
+	switch {
+	case base.Status == model.EvalRunPending && candidate.Status == model.EvalRunPending:
+		return model.EvalRunPending
+	case !base.Finished() || !candidate.Finished():
+		return model.EvalRunRunning
+	case base.Status == model.EvalRunFailed || candidate.Status == model.EvalRunFailed:
+		return model.EvalRunFailed
+	case base.Status == model.EvalRunCanceled || candidate.Status == model.EvalRunCanceled:
+		return model.EvalRunCanceled
+	}
+	return model.EvalRunCompleted
+}
+
+func (s *Service) summary(ctx context.Context, run *model.EvalRun) *CompareSummary {
+	res := &CompareSummary{
+		RunID:            run.ID,
+		VersionID:        run.VersionID,
+		Status:           run.Status,
+		Total:            run.Total,
+		Succeeded:        run.Succeeded,
+		Failed:           run.Failed,
+		PromptTokens:     run.PromptTokens,
+		CompletionTokens: run.CompletionTokens,
+		Cost:             run.Cost,
+	}
+	if v, err := s.versionRepo.GetByID(ctx, run.VersionID); err != nil {
+		s.logger.Error(err.Error())
+	} else if v != nil {
+		res.Version = v.Version
+	}
+	return res
+}
+
+// sideSummary 汇总一个版本在成功行上的得分、通过率和平均耗时
+func sideSummary(sum *CompareSummary, rows []*CompareRow, side func(*CompareRow) *CompareSide, scored bool) {
+	var succeeded int
+	var latency int64
+	var score float64
+	for _, r := range rows {
+		sd := side(r)
+		if sd.Status != model.EvalResultSucceeded {
+			continue
+		}
+		succeeded++
+		latency += sd.LatencyMs
+		if sd.Score != nil {
+			score += *sd.Score
+		}
+		if sd.Pass != nil && *sd.Pass {
+			sum.Passed++
+		}
+	}
+	if succeeded == 0 {
+		return
+	}
+	sum.MeanLatencyMs = float64(latency) / float64(succeeded)
+	if scored {
+		mean := score / float64(succeeded)
+		rate := float64(sum.Passed) / float64(succeeded)
+		sum.MeanScore = &mean
+		sum.PassRate = &rate
+	}
+}
+
+func compareSide(r *model.EvalResult, sc *scorer.Scorer) *CompareSide {
+	side := &CompareSide{
+		Status:           r.Status,
+		Input:            r.Input,
+		Output:           r.Output,
+		Error:            r.Error,
+		LatencyMs:        r.LatencyMs,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		Cost:             r.Cost,
+	}
+	if sc == nil || r.Status != model.EvalResultSucceeded {
+		return side
+	}
+	res := sc.Score(r.Output, r.Expected)
+	side.Score = &res.Score
+	side.Pass = &res.Pass
+	for _, a := range res.Assertions {
+		if !a.Pass {
+			side.Failures = append(side.Failures, a.Type+": "+a.Reason)
+		}
+	}
+	return side
+}
+
+// compareRow 候选版本失败、断言由通过变为不通过或得分下降超过阈值时为退化, 反之为改进
+func compareRow(base, candidate *model.EvalResult, sc *scorer.Scorer, threshold float64) *CompareRow {
+	row := &CompareRow{
+		Position:       base.Position,
+		Variables:      (&model.DatasetRow{Variables: base.Variables}).VariableMap(),
+		Expected:       base.Expected,
+		Base:           compareSide(base, sc),
+		Candidate:      compareSide(candidate, sc),
+		LatencyDeltaMs: candidate.LatencyMs - base.LatencyMs,
+		TokenDelta:     candidate.PromptTokens + candidate.CompletionTokens - base.PromptTokens - base.CompletionTokens,
+		CostDelta:      candidate.Cost - base.Cost,
+		Change:         ChangeUnchanged,
+	}
+
+	var worse, better []string
+	baseOK := base.Status == model.EvalResultSucceeded
+	candidateOK := candidate.Status == model.EvalResultSucceeded
+	switch {
+	case baseOK && !candidateOK:
+		worse = append(worse, "candidate "+candidate.Status+": "+candidate.Error)
+	case !baseOK && candidateOK:
+		better = append(better, "base "+base.Status+": "+base.Error)
+	}
+	if row.Base.Score != nil && row.Candidate.Score != nil {
+		d := *row.Candidate.Score - *row.Base.Score
+		row.ScoreDelta = &d
+		switch {
+		case *row.Base.Pass && !*row.Candidate.Pass:
+			worse = append(worse, "assertions no longer pass")
+		case !*row.Base.Pass && *row.Candidate.Pass:
+			better = append(better, "assertions now pass")
+		}
+		switch {
+		case d < -threshold:
+			worse = append(worse, fmt.Sprintf("score dropped by %.3f", -d))
+		case d > threshold:
+			better = append(better, fmt.Sprintf("score rose by %.3f", d))
+		}
+	}
+
+	switch {
+	case len(worse) > 0:
+		row.Change = ChangeRegression
+		row.Reasons = worse
+	case len(better) > 0:
+		row.Change = ChangeImprovement
+		row.Reasons = better
+	}
+	return row
+}
@@ -0,0 +1,277 @@
+package eval
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/service/eval/scorer"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxGateCases 门禁在发布时同步执行, 用例数需要控制在较小范围
+	maxGateCases = 50
+	// defaultGateTimeout 门禁整体超时, 避免上游缓慢时发布请求一直挂起
+	defaultGateTimeout = 2 * time.Minute
+)
+
+// GateCaseResult 一个门禁用例的执行结果
+type GateCaseResult struct {
+	Position  int               `json:"position"`
+	Variables map[string]string `json:"variables"`
+	Expected  string            `json:"expected"`
+	Input     string            `json:"input"`
+	Output    string            `json:"output"`
+	Error     string            `json:"error,omitempty"`
+	Pass      bool              `json:"pass"`
+	Score     float64           `json:"score"`
+	Failures  []string          `json:"failures,omitempty"`
+	LatencyMs int64             `json:"latencyMs"`
+}
+
+// GateReport 一次门禁检查的结果, Pass 为通过率不低于 MinPassRate
+type GateReport struct {
+	GateID           string            `json:"gateId"`
+	PromptID         string            `json:"promptId"`
+	VersionID        string            `json:"versionId"`
+	Model            string            `json:"model"`
+	Pass             bool              `json:"pass"`
+	MinPassRate      float64           `json:"minPassRate"`
+	PassRate         float64           `json:"passRate"`
+	Total            int               `json:"total"`
+	Passed           int               `json:"passed"`
+	PromptTokens     int64             `json:"promptTokens"`
+	CompletionTokens int64             `json:"completionTokens"`
+	Cost             float64           `json:"cost"`
+	Cases            []*GateCaseResult `json:"cases"`
+}
+
+// SaveGate 创建或覆盖提示词的发布门禁
+func (s *Service) SaveGate(ctx context.Context, userID int64, username string, req dto.SaveGateDTO) (*model.EvalGate, error) {
+	p, err := s.promptRepo.GetByID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+	if len(req.Cases) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(req.Cases) > maxGateCases {
+		return nil, ErrTooManyCases
+	}
+	minPassRate := 1.0
+	if req.MinPassRate != nil {
+		minPassRate = *req.MinPassRate
+	}
+	if minPassRate < 0 || minPassRate > 1 {
+		return nil, ErrInvalidPassRate
+	}
+	assertions := req.Assertions
+	if len(assertions) == 0 {
+		assertions = []scorer.Assertion{{Type: scorer.TypeEquals}}
+	}
+	if _, err := newScorer(assertions); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]any)
+	if req.Temperature != nil {
+		params["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		params["max_tokens"] = req.MaxTokens
+	}
+	paramsJSON, _ := json.Marshal(params)
+	casesJSON, _ := json.Marshal(req.Cases)
+	assertionsJSON, _ := json.Marshal(assertions)
+
+	gate, err := s.repo.GetGateByPromptID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	create := gate == nil
+	if create {
+		gate = &model.EvalGate{
+			ID:        uuid.New().String(),
+			PromptID:  req.PromptID,
+			CreatedBy: strconv.FormatInt(userID, 10),
+			Username:  username,
+		}
+	}
+	gate.Model = req.Model
+	gate.Params = string(paramsJSON)
+	gate.Cases = string(casesJSON)
+	gate.Assertions = string(assertionsJSON)
+	gate.MinPassRate = minPassRate
+	gate.Enabled = req.Enabled == nil || *req.Enabled
+
+	if create {
+		err = s.repo.CreateGate(ctx, gate)
+	} else {
+		err = s.repo.UpdateGate(ctx, gate)
+	}
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return gate, nil
+}
+
+func (s *Service) GetGate(ctx context.Context, promptID string) (*model.EvalGate, error) {
+	gate, err := s.repo.GetGateByPromptID(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if gate == nil {
+		return nil, ErrGateNotFound
+	}
+	return gate, nil
+}
+
+func (s *Service) DeleteGate(ctx context.Context, promptID string) error {
+	if _, err := s.GetGate(ctx, promptID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteGateByPromptID(ctx, promptID); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// CheckGate 对已有版本试运行门禁, 不影响发布状态
+func (s *Service) CheckGate(ctx context.Context, userID int64, username string, req dto.CheckGateDTO) (*GateReport, error) {
+	v, err := s.getVersion(ctx, req.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	gate, err := s.GetGate(ctx, v.PromptID)
+	if err != nil {
+		return nil, err
+	}
+	return s.runGate(ctx, gate, v, userID, username)
+}
+
+// CheckPublish 发布前执行提示词的门禁; 没有门禁或门禁已停用时返回 nil
+func (s *Service) CheckPublish(ctx context.Context, v *model.PromptVersion, userID int64, username string) (*GateReport, error) {
+	gate, err := s.repo.GetGateByPromptID(ctx, v.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if gate == nil || !gate.Enabled {
+		return nil, nil
+	}
+	return s.runGate(ctx, gate, v, userID, username)
+}
+
+// runGate 经模型代理逐个执行用例并用门禁的断言判断是否通过, 超过 gateTimeout 时返回 ErrGateTimeout
+func (s *Service) runGate(ctx context.Context, gate *model.EvalGate, v *model.PromptVersion, userID int64, username string) (*GateReport, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, s.gateTimeout, ErrGateTimeout)
+	defer cancel()
+
+	var cases []dto.GateCaseDTO
+	var assertions []scorer.Assertion
+	params := make(map[string]any)
+	for _, field := range []struct {
+		raw string
+		dst any
+	}{{gate.Cases, &cases}, {gate.Assertions, &assertions}, {gate.Params, &params}} {
+		if err := json.Unmarshal([]byte(field.raw), field.dst); err != nil {
+			s.logger.Error(err.Error())
+			return nil, ErrDatabaseErr
+		}
+	}
+	sc, err := newScorer(assertions)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GateReport{
+		GateID:      gate.ID,
+		PromptID:    gate.PromptID,
+		VersionID:   v.ID,
+		Model:       gate.Model,
+		MinPassRate: gate.MinPassRate,
+		Total:       len(cases),
+		Cases:       make([]*GateCaseResult, len(cases)),
+	}
+	sem := make(chan struct{}, s.concurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res := &GateCaseResult{Position: i + 1, Variables: c.Variables, Expected: c.Expected}
+			report.Cases[i] = res
+
+			input, err := render(v.Content, c.Variables)
+			if err != nil {
+				res.Error = err.Error()
+				return
+			}
+			res.Input = input
+			start := time.Now()
+			out, err := s.client.chat(ctx, userID, username, gate.Model,
+				[]chatMessage{{Role: "user", Content: input}}, params)
+			res.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				res.Error = err.Error()
+				return
+			}
+			mu.Lock()
+			report.PromptTokens += out.PromptTokens
+			report.CompletionTokens += out.CompletionTokens
+			report.Cost += out.Cost
+			mu.Unlock()
+
+			res.Output = out.Output
+			scored := sc.Score(out.Output, c.Expected)
+			res.Pass = scored.Pass
+			res.Score = scored.Score
+			for _, a := range scored.Assertions {
+				if !a.Pass {
+					res.Failures = append(res.Failures, a.Type+": "+a.Reason)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		if cause := context.Cause(ctx); cause == ErrGateTimeout {
+			return nil, cause
+		}
+		return nil, ctx.Err()
+	}
+
+	for _, res := range report.Cases {
+		if res.Pass {
+			report.Passed++
+		}
+	}
+	if report.Total > 0 {
+		report.PassRate = float64(report.Passed) / float64(report.Total)
+	}
+	report.Pass = report.PassRate >= report.MinPassRate
+	return report, nil
+}
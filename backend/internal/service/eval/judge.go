@@ -0,0 +1,339 @@
+package eval
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinScore = 1
+	defaultMaxScore = 10
+	maxJudgeRetries = 5
+)
+
+// judgeFormat 要求模型按 {"score", "rationale"} 结构返回
+var judgeFormat = map[string]any{
+	"type": "json_schema",
+	"json_schema": map[string]any{
+		"name":   "judgement",
+		"strict": true,
+		"schema": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"score":     map[string]any{"type": "number"},
+				"rationale": map[string]any{"type": "string"},
+			},
+			"required":             []string{"score", "rationale"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+// Judgement 一条输出的评审结果, 评审失败时 Score 为空, Error 为原因
+type Judgement struct {
+	Score            *float64 `json:"score"`
+	Rationale        string   `json:"rationale"`
+	Pass             *bool    `json:"pass,omitempty"`
+	Attempts         int      `json:"attempts"`
+	Error            string   `json:"error,omitempty"`
+	LatencyMs        int64    `json:"latencyMs"`
+	PromptTokens     int64    `json:"promptTokens"`
+	CompletionTokens int64    `json:"completionTokens"`
+	Cost             float64  `json:"cost"`
+}
+
+// JudgeReport 一批输出的评审结果; MeanScore / PassRate 只统计评审成功的条目
+type JudgeReport struct {
+	RubricPath       string       `json:"rubricPath"`
+	RubricVersionID  string       `json:"rubricVersionId"`
+	RubricVersion    string       `json:"rubricVersion"`
+	Model            string       `json:"model"`
+	MinScore         float64      `json:"minScore"`
+	MaxScore         float64      `json:"maxScore"`
+	Total            int          `json:"total"`
+	Judged           int          `json:"judged"`
+	Failed           int          `json:"failed"`
+	MeanScore        float64      `json:"meanScore"`
+	Passed           int          `json:"passed"`
+	PassRate         *float64     `json:"passRate,omitempty"`
+	PromptTokens     int64        `json:"promptTokens"`
+	CompletionTokens int64        `json:"completionTokens"`
+	Cost             float64      `json:"cost"`
+	Items            []*Judgement `json:"items"`
+}
+
+// judgeScale 一次评审的分数范围与通过线
+type judgeScale struct {
+	min, max float64
+	pass     *float64
+}
+
+// Judge 用 rubricPath 对应的提示词作为评分标准, 经模型代理逐条评审输出
+func (s *Service) Judge(ctx context.Context, userID int64, username string, req dto.JudgeDTO) (*JudgeReport, error) {
+	if len(req.Items) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(req.Items) > s.maxJudgeItems {
+		return nil, ErrTooManyRows
+	}
+	scale := judgeScale{min: defaultMinScore, max: defaultMaxScore, pass: req.PassScore}
+	if req.MinScore != nil {
+		scale.min = *req.MinScore
+	}
+	if req.MaxScore != nil {
+		scale.max = *req.MaxScore
+	}
+	if scale.min >= scale.max {
+		return nil, ErrInvalidScale
+	}
+
+	rubric, err := s.rubric(ctx, req.RubricPath, req.RubricVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := s.judgeRetries
+	if req.Retries != nil {
+		retries = min(max(*req.Retries, 0), maxJudgeRetries)
+	}
+	params := map[string]any{"temperature": 0.0}
+	if req.Temperature != nil {
+		params["temperature"] = *req.Temperature
+	}
+	if req.StructuredOutput == nil || *req.StructuredOutput {
+		params["response_format"] = judgeFormat
+	}
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = s.concurrency
+	}
+	concurrency = min(concurrency, s.maxConcurrency)
+
+	report := &JudgeReport{
+		RubricPath:      req.RubricPath,
+		RubricVersionID: rubric.ID,
+		RubricVersion:   rubric.Version,
+		Model:           req.Model,
+		MinScore:        scale.min,
+		MaxScore:        scale.max,
+		Total:           len(req.Items),
+		Items:           make([]*Judgement, len(req.Items)),
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range req.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			report.Items[i] = s.judge(ctx, userID, username, req.Model, rubric.Content, item, scale, params, retries)
+		}()
+	}
+	wg.Wait()
+
+	var sum float64
+	for _, j := range report.Items {
+		report.PromptTokens += j.PromptTokens
+		report.CompletionTokens += j.CompletionTokens
+		report.Cost += j.Cost
+		if j.Score == nil {
+			report.Failed++
+			continue
+		}
+		report.Judged++
+		sum += *j.Score
+		if j.Pass != nil && *j.Pass {
+			report.Passed++
+		}
+	}
+	if report.Judged > 0 {
+		report.MeanScore = sum / float64(report.Judged)
+		if scale.pass != nil {
+			rate := float64(report.Passed) / float64(report.Judged)
+			report.PassRate = &rate
+		}
+	}
+	return report, nil
+}
+
+// rubric version 为空时取提示词的已发布版本
+func (s *Service) rubric(ctx context.Context, path, version string) (*model.PromptVersion, error) {
+	p, err := s.promptRepo.GetByPath(ctx, path)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrRubricNotFound
+	}
+
+	if version == "" {
+		if !p.IsPublish || p.LatestVersion == "" {
+			return nil, ErrRubricNotPublished
+		}
+		v, err := s.versionRepo.GetByID(ctx, p.LatestVersion)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return nil, ErrDatabaseErr
+		}
+		if v == nil {
+			return nil, ErrRubricNotPublished
+		}
+		return v, nil
+	}
+
+	versions, err := s.versionRepo.GetByPromptID(ctx, p.ID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+// judge 评审一条输出; 回复无法解析时把原因反馈给模型重试
+func (s *Service) judge(ctx context.Context, userID int64, username, judgeModel, rubric string, item dto.JudgeItemDTO, scale judgeScale, params map[string]any, retries int) *Judgement {
+	res := &Judgement{}
+	prompt, err := judgePrompt(rubric, item)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	messages := []chatMessage{
+		{Role: "system", Content: judgeInstruction(scale)},
+		{Role: "user", Content: prompt},
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		res.Error = ctx.Err().Error()
+		return res
+	}
+
+	start := time.Now()
+	defer func() { res.LatencyMs = time.Since(start).Milliseconds() }()
+	for attempt := 0; attempt <= retries; attempt++ {
+		res.Attempts++
+		out, err := s.client.chat(ctx, userID, username, judgeModel, messages, params)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.PromptTokens += out.PromptTokens
+		res.CompletionTokens += out.CompletionTokens
+		res.Cost += out.Cost
+
+		score, rationale, err := parseJudgement(out.Output, scale)
+		if err == nil {
+			res.Score = &score
+			res.Rationale = rationale
+			res.Error = ""
+			if scale.pass != nil {
+				pass := score >= *scale.pass
+				res.Pass = &pass
+			}
+			return res
+		}
+		res.Error = "unparseable judgement: " + err.Error()
+		messages = append(messages,
+			chatMessage{Role: "assistant", Content: out.Output},
+			chatMessage{Role: "user", Content: fmt.Sprintf("Your reply could not be used: %s. Reply again with only the JSON object.", err)},
+		)
+	}
+	return res
+}
+
+func judgeInstruction(scale judgeScale) string {
+	return fmt.Sprintf("You are an impartial evaluator. Grade the candidate output strictly according to the rubric. "+
+		`Reply with only a JSON object of the form {"score": <number from %s to %s>, "rationale": "<brief explanation>"} and nothing else.`,
+		formatScore(scale.min), formatScore(scale.max))
+}
+
+// judgePrompt 渲染评分标准, 可用变量为 input / output / expected / reference 及条目自带的变量;
+// 评分标准中没有 {{output}} 时把待评内容附在后面
+func judgePrompt(rubric string, item dto.JudgeItemDTO) (string, error) {
+	vars := make(map[string]string, len(item.Variables)+4)
+	for k, v := range item.Variables {
+		vars[k] = v
+	}
+	vars["input"] = item.Input
+	vars["output"] = item.Output
+	vars["expected"] = item.Expected
+	vars["reference"] = item.Reference
+
+	content, err := render(rubric, vars)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(rubric, -1) {
+		if m[1] == "output" {
+			return content, nil
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(content)
+	for _, section := range []struct{ title, value string }{
+		{"Input", item.Input},
+		{"Candidate output", item.Output},
+		{"Expected output", item.Expected},
+		{"Reference", item.Reference},
+	} {
+		if section.value == "" && section.title != "Candidate output" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n## %s\n%s", section.title, section.value)
+	}
+	return b.String(), nil
+}
+
+// parseJudgement 解析 {"score", "rationale"}, 容忍代码块包裹和前后多余文字
+func parseJudgement(reply string, scale judgeScale) (float64, string, error) {
+	text := strings.TrimSpace(reply)
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return 0, "", errors.New("no JSON object found")
+	}
+	var v struct {
+		Score     json.RawMessage `json:"score"`
+		Rationale string          `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &v); err != nil {
+		return 0, "", fmt.Errorf("invalid JSON: %v", err)
+	}
+	if len(v.Score) == 0 {
+		return 0, "", errors.New("missing score")
+	}
+	raw := strings.Trim(string(v.Score), `"`)
+	score, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	// ParseFloat 接受 NaN / Inf, NaN 能通过范围检查且无法编码为 JSON, 同样按解析失败处理
+	if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+		return 0, "", fmt.Errorf("score %s is not a number", v.Score)
+	}
+	if score < scale.min || score > scale.max {
+		return 0, "", fmt.Errorf("score %s is outside %s to %s", formatScore(score), formatScore(scale.min), formatScore(scale.max))
+	}
+	return score, v.Rationale, nil
+}
+
+func formatScore(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
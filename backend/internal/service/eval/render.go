@@ -0,0 +1,34 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder 提示词中的变量占位符 {{name}}, 名称两侧允许空格
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// render 用变量取值替换提示词中的占位符, 缺少变量时返回错误
+func render(content string, vars map[string]string) (string, error) {
+	missing := make(map[string]bool)
+	out := placeholder.ReplaceAllStringFunc(content, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing[name] = true
+			return m
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("missing variables: %s", strings.Join(names, ", "))
+	}
+	return out, nil
+}
@@ -0,0 +1,306 @@
+package scorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// lookup 按 a.b.0.c 形式的路径取字段
+func lookup(doc any, path string) (any, bool) {
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// equalJSON 比较两个 json.Unmarshal 得到的值, 数字按数值比较
+func equalJSON(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalJSON(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// similarity 基于编辑距离的相似度, 1 表示完全相同
+func similarity(a, b string) float64 {
+	n := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein([]rune(a), []rune(b)))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// schema JSON Schema 的常用子集: type / enum / const / properties / required /
+// additionalProperties / items / minItems / maxItems / minLength / maxLength /
+// pattern / minimum / maximum
+type schema struct {
+	Types                []string
+	Enum                 []any
+	Const                any
+	HasConst             bool
+	Properties           map[string]*schema
+	Required             []string
+	AdditionalProperties *bool
+	Items                *schema
+	MinItems, MaxItems   *int
+	MinLength, MaxLength *int
+	Pattern              *regexp.Regexp
+	Minimum, Maximum     *float64
+}
+
+func parseSchema(raw json.RawMessage) (*schema, error) {
+	var v struct {
+		Type                 json.RawMessage            `json:"type"`
+		Enum                 []any                      `json:"enum"`
+		Const                json.RawMessage            `json:"const"`
+		Properties           map[string]json.RawMessage `json:"properties"`
+		Required             []string                   `json:"required"`
+		AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+		Items                json.RawMessage            `json:"items"`
+		MinItems             *int                       `json:"minItems"`
+		MaxItems             *int                       `json:"maxItems"`
+		MinLength            *int                       `json:"minLength"`
+		MaxLength            *int                       `json:"maxLength"`
+		Pattern              string                     `json:"pattern"`
+		Minimum              *float64                   `json:"minimum"`
+		Maximum              *float64                   `json:"maximum"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	s := &schema{
+		Enum:      v.Enum,
+		Required:  v.Required,
+		MinItems:  v.MinItems,
+		MaxItems:  v.MaxItems,
+		MinLength: v.MinLength,
+		MaxLength: v.MaxLength,
+		Minimum:   v.Minimum,
+		Maximum:   v.Maximum,
+	}
+
+	if len(v.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(v.Type, &one); err == nil {
+			s.Types = []string{one}
+		} else if err := json.Unmarshal(v.Type, &s.Types); err != nil {
+			return nil, fmt.Errorf("type must be a string or an array of strings")
+		}
+		for _, t := range s.Types {
+			switch t {
+			case "object", "array", "string", "number", "integer", "boolean", "null":
+			default:
+				return nil, fmt.Errorf("unknown type %q", t)
+			}
+		}
+	}
+	if len(v.Const) > 0 {
+		if err := json.Unmarshal(v.Const, &s.Const); err != nil {
+			return nil, err
+		}
+		s.HasConst = true
+	}
+	if len(v.Properties) > 0 {
+		s.Properties = make(map[string]*schema, len(v.Properties))
+		for name, p := range v.Properties {
+			ps, err := parseSchema(p)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %v", name, err)
+			}
+			s.Properties[name] = ps
+		}
+	}
+	if len(v.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(v.AdditionalProperties, &allowed); err != nil {
+			return nil, fmt.Errorf("additionalProperties must be a boolean")
+		}
+		s.AdditionalProperties = &allowed
+	}
+	if len(v.Items) > 0 {
+		items, err := parseSchema(v.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+		s.Items = items
+	}
+	if v.Pattern != "" {
+		re, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %v", err)
+		}
+		s.Pattern = re
+	}
+	return s, nil
+}
+
+// validate 返回第一个不满足的约束, path 为出错位置
+func (s *schema) validate(v any, path string) error {
+	if len(s.Types) > 0 && !s.matchType(v) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(s.Types, " or "), typeOf(v))
+	}
+	if s.HasConst && !equalJSON(v, s.Const) {
+		return fmt.Errorf("%s: value does not equal const", path)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equalJSON(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of enum", path)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, field := range val {
+			if ps, ok := s.Properties[name]; ok {
+				if err := ps.validate(field, path+"."+name); err != nil {
+					return err
+				}
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, *s.MinItems, len(val))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, *s.MaxItems, len(val))
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: expected at least %d characters, got %d", path, *s.MinLength, n)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: expected at most %d characters, got %d", path, *s.MaxLength, n)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(val) {
+			return fmt.Errorf("%s: does not match pattern /%s/", path, s.Pattern.String())
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, val, *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, val, *s.Maximum)
+		}
+	}
+	return nil
+}
+
+func (s *schema) matchType(v any) bool {
+	for _, t := range s.Types {
+		switch t {
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		default:
+			if typeOf(v) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
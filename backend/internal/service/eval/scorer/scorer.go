@@ -0,0 +1,348 @@
+package scorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 断言类型
+const (
+	TypeEquals     = "equals"
+	TypeContains   = "contains"
+	TypeRegex      = "regex"
+	TypeJSONSchema = "json_schema"
+	TypeJSONField  = "json_field"
+	TypeLength     = "length"
+	TypeSimilarity = "similarity"
+)
+
+// defaultThreshold similarity 未指定阈值时的默认值
+const defaultThreshold = 0.8
+
+// maxSimilarityRunes similarity 输出与目标值各自的字符数上限, 编辑距离为 O(n·m), 超出时直接判定失败
+const maxSimilarityRunes = 2000
+
+// Assertion 对一条输出的检查; Value 为空时与 expected 比较
+type Assertion struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+	// Path json_field 的字段路径, 以 . 分隔, 数组用下标, 如 items.0.name
+	Path string `json:"path,omitempty"`
+	// Schema json_schema 的 JSON Schema, 为空时只检查输出是合法 JSON
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// Min / Max length 的字符数上下限
+	Min *int `json:"min,omitempty"`
+	Max *int `json:"max,omitempty"`
+	// Threshold similarity 的通过阈值, 0 ~ 1, 默认 0.8
+	Threshold  float64 `json:"threshold,omitempty"`
+	IgnoreCase bool    `json:"ignoreCase,omitempty"`
+	// Not 取反, 检查不满足时通过
+	Not bool `json:"not,omitempty"`
+	// Weight 计入总分的权重, 默认 1
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// AssertionResult 单条断言的结果, Score 为 0 ~ 1
+type AssertionResult struct {
+	Type   string  `json:"type"`
+	Pass   bool    `json:"pass"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+	// unscorable 断言无法执行 (如输入超长), not 时不取反
+	unscorable bool
+}
+
+// ItemResult 一条输出的结果, 全部断言通过才算通过, Score 为断言得分的加权平均
+type ItemResult struct {
+	Pass       bool               `json:"pass"`
+	Score      float64            `json:"score"`
+	Assertions []*AssertionResult `json:"assertions"`
+}
+
+// Item 待评分的输出
+type Item struct {
+	Output   string `json:"output"`
+	Expected string `json:"expected"`
+}
+
+// AssertionSummary 单条断言在全部输出上的通过率
+type AssertionSummary struct {
+	Type     string  `json:"type"`
+	Passed   int     `json:"passed"`
+	PassRate float64 `json:"passRate"`
+}
+
+// Report 一批输出的评分结果
+type Report struct {
+	Total      int                 `json:"total"`
+	Passed     int                 `json:"passed"`
+	PassRate   float64             `json:"passRate"`
+	MeanScore  float64             `json:"meanScore"`
+	Assertions []*AssertionSummary `json:"assertions"`
+	Items      []*ItemResult       `json:"items"`
+}
+
+// Scorer 预编译的一组断言, 可并发使用
+type Scorer struct {
+	assertions []Assertion
+	regexps    []*regexp.Regexp
+	schemas    []*schema
+}
+
+// New 校验并编译断言, 参数有误时返回错误
+func New(assertions []Assertion) (*Scorer, error) {
+	if len(assertions) == 0 {
+		return nil, fmt.Errorf("at least one assertion is required")
+	}
+	s := &Scorer{
+		assertions: assertions,
+		regexps:    make([]*regexp.Regexp, len(assertions)),
+		schemas:    make([]*schema, len(assertions)),
+	}
+	for i, a := range assertions {
+		if a.Weight < 0 {
+			return nil, fmt.Errorf("assertion %d: weight must not be negative", i+1)
+		}
+		switch a.Type {
+		case TypeEquals, TypeContains:
+		case TypeRegex:
+			pattern := a.Value
+			if a.IgnoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("assertion %d: invalid regex: %v", i+1, err)
+			}
+			s.regexps[i] = re
+		case TypeJSONSchema:
+			if len(a.Schema) == 0 {
+				continue
+			}
+			sc, err := parseSchema(a.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("assertion %d: invalid schema: %v", i+1, err)
+			}
+			s.schemas[i] = sc
+		case TypeJSONField:
+			if a.Path == "" {
+				return nil, fmt.Errorf("assertion %d: path is required", i+1)
+			}
+		case TypeLength:
+			if a.Min == nil && a.Max == nil {
+				return nil, fmt.Errorf("assertion %d: min or max is required", i+1)
+			}
+			if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+				return nil, fmt.Errorf("assertion %d: min is greater than max", i+1)
+			}
+		case TypeSimilarity:
+			if a.Threshold < 0 || a.Threshold > 1 {
+				return nil, fmt.Errorf("assertion %d: threshold must be between 0 and 1", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("assertion %d: unknown type %q", i+1, a.Type)
+		}
+	}
+	return s, nil
+}
+
+// Score 对一条输出执行全部断言
+func (s *Scorer) Score(output, expected string) *ItemResult {
+	res := &ItemResult{
+		Pass:       true,
+		Assertions: make([]*AssertionResult, len(s.assertions)),
+	}
+	var sum, weights float64
+	for i, a := range s.assertions {
+		r := s.check(i, output, expected)
+		if a.Not && !r.unscorable {
+			r.Pass = !r.Pass
+			r.Score = 1 - r.Score
+			r.Reason = "not: " + r.Reason
+		}
+		res.Assertions[i] = r
+		res.Pass = res.Pass && r.Pass
+
+		w := a.Weight
+		if w == 0 {
+			w = 1
+		}
+		sum += r.Score * w
+		weights += w
+	}
+	if weights > 0 {
+		res.Score = sum / weights
+	}
+	return res
+}
+
+// ScoreAll 对一批输出评分并汇总
+func (s *Scorer) ScoreAll(items []Item) *Report {
+	report := &Report{
+		Total:      len(items),
+		Assertions: make([]*AssertionSummary, len(s.assertions)),
+		Items:      make([]*ItemResult, len(items)),
+	}
+	for i, a := range s.assertions {
+		report.Assertions[i] = &AssertionSummary{Type: a.Type}
+	}
+	var scores float64
+	for i, item := range items {
+		res := s.Score(item.Output, item.Expected)
+		report.Items[i] = res
+		scores += res.Score
+		if res.Pass {
+			report.Passed++
+		}
+		for j, r := range res.Assertions {
+			if r.Pass {
+				report.Assertions[j].Passed++
+			}
+		}
+	}
+	if report.Total > 0 {
+		report.PassRate = float64(report.Passed) / float64(report.Total)
+		report.MeanScore = scores / float64(report.Total)
+		for _, a := range report.Assertions {
+			a.PassRate = float64(a.Passed) / float64(report.Total)
+		}
+	}
+	return report
+}
+
+func (s *Scorer) check(i int, output, expected string) *AssertionResult {
+	a := s.assertions[i]
+	target := a.Value
+	if target == "" {
+		target = expected
+	}
+	switch a.Type {
+	case TypeEquals:
+		got, want := strings.TrimSpace(output), strings.TrimSpace(target)
+		if a.IgnoreCase {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		if got == want {
+			return pass(a.Type, "output equals expected value")
+		}
+		return fail(a.Type, fmt.Sprintf("output does not equal %q", truncate(target)))
+	case TypeContains:
+		got, want := output, target
+		if a.IgnoreCase {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		if strings.Contains(got, want) {
+			return pass(a.Type, fmt.Sprintf("output contains %q", truncate(target)))
+		}
+		return fail(a.Type, fmt.Sprintf("output does not contain %q", truncate(target)))
+	case TypeRegex:
+		if s.regexps[i].MatchString(output) {
+			return pass(a.Type, fmt.Sprintf("output matches /%s/", a.Value))
+		}
+		return fail(a.Type, fmt.Sprintf("output does not match /%s/", a.Value))
+	case TypeJSONSchema:
+		var v any
+		if err := json.Unmarshal([]byte(extractJSON(output)), &v); err != nil {
+			return fail(a.Type, "output is not valid JSON: "+err.Error())
+		}
+		if s.schemas[i] == nil {
+			return pass(a.Type, "output is valid JSON")
+		}
+		if err := s.schemas[i].validate(v, "$"); err != nil {
+			return fail(a.Type, err.Error())
+		}
+		return pass(a.Type, "output matches schema")
+	case TypeJSONField:
+		return checkField(a, output, target)
+	case TypeLength:
+		n := utf8.RuneCountInString(output)
+		if a.Min != nil && n < *a.Min {
+			return fail(a.Type, fmt.Sprintf("length %d is less than %d", n, *a.Min))
+		}
+		if a.Max != nil && n > *a.Max {
+			return fail(a.Type, fmt.Sprintf("length %d is greater than %d", n, *a.Max))
+		}
+		return pass(a.Type, fmt.Sprintf("length %d is within bounds", n))
+	case TypeSimilarity:
+		threshold := a.Threshold
+		if threshold == 0 {
+			threshold = defaultThreshold
+		}
+		got, want := strings.TrimSpace(output), strings.TrimSpace(target)
+		if n := max(utf8.RuneCountInString(got), utf8.RuneCountInString(want)); n > maxSimilarityRunes {
+			r := fail(a.Type, fmt.Sprintf("text length %d exceeds %d characters supported by similarity", n, maxSimilarityRunes))
+			r.unscorable = true
+			return r
+		}
+		if a.IgnoreCase {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		sim := similarity(got, want)
+		r := &AssertionResult{Type: a.Type, Score: sim, Pass: sim >= threshold}
+		if r.Pass {
+			r.Reason = fmt.Sprintf("similarity %.3f >= %.3f", sim, threshold)
+		} else {
+			r.Reason = fmt.Sprintf("similarity %.3f < %.3f", sim, threshold)
+		}
+		return r
+	}
+	return fail(a.Type, "unknown assertion type")
+}
+
+// checkField 比较 JSON 字段与目标值; 目标值能解析为 JSON 时按 JSON 比较, 否则按字符串比较
+func checkField(a Assertion, output, target string) *AssertionResult {
+	var doc any
+	if err := json.Unmarshal([]byte(extractJSON(output)), &doc); err != nil {
+		return fail(a.Type, "output is not valid JSON: "+err.Error())
+	}
+	got, ok := lookup(doc, a.Path)
+	if !ok {
+		return fail(a.Type, fmt.Sprintf("field %s not found", a.Path))
+	}
+	var want any
+	if err := json.Unmarshal([]byte(target), &want); err != nil {
+		want = target
+	}
+	if s, ok := got.(string); ok && a.IgnoreCase {
+		if w, ok := want.(string); ok && strings.EqualFold(s, w) {
+			return pass(a.Type, fmt.Sprintf("field %s equals expected value", a.Path))
+		}
+	}
+	if equalJSON(got, want) {
+		return pass(a.Type, fmt.Sprintf("field %s equals expected value", a.Path))
+	}
+	gotText, _ := json.Marshal(got)
+	return fail(a.Type, fmt.Sprintf("field %s is %s, expected %s", a.Path, truncate(string(gotText)), truncate(target)))
+}
+
+// extractJSON 去掉模型常见的 ```json 代码块包裹
+func extractJSON(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+func pass(typ, reason string) *AssertionResult {
+	return &AssertionResult{Type: typ, Pass: true, Score: 1, Reason: reason}
+}
+
+func fail(typ, reason string) *AssertionResult {
+	return &AssertionResult{Type: typ, Pass: false, Score: 0, Reason: reason}
+}
+
+func truncate(s string) string {
+	const max = 80
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max]) + "..."
+}
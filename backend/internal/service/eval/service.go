@@ -0,0 +1,406 @@
+package eval
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/eval"
+	"backend/internal/repository/prompt"
+	"backend/internal/repository/version"
+	"backend/internal/service/dataset"
+	"backend/internal/service/eval/scorer"
+	"backend/pkg/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRunNotFound          = errors.New("eval run not found")
+	ErrVersionNotFound      = errors.New("prompt version not found")
+	ErrNoInput              = errors.New("upload a file or choose a dataset")
+	ErrNoRows               = errors.New("no rows to evaluate")
+	ErrTooManyRows          = errors.New("too many rows for one eval run")
+	ErrDatasetMismatch      = errors.New("dataset does not belong to the prompt of this version")
+	ErrRunFinished          = errors.New("eval run already finished")
+	ErrPromptNotFound       = errors.New("prompt not found")
+	ErrNoAssertions         = errors.New("provide assertions or an assertion set")
+	ErrAssertionSetNotFound = errors.New("assertion set not found")
+	ErrRubricNotFound       = errors.New("rubric prompt not found")
+	ErrRubricNotPublished   = errors.New("rubric prompt has no published version")
+	ErrInvalidScale         = errors.New("minScore must be less than maxScore")
+	ErrVersionMismatch      = errors.New("versions belong to different prompts")
+	ErrComparisonNotFound   = errors.New("eval comparison not found")
+	ErrGateNotFound         = errors.New("publish gate not found")
+	ErrTooManyCases         = errors.New("too many gate cases")
+	ErrInvalidPassRate      = errors.New("minPassRate must be between 0 and 1")
+	ErrGateTimeout          = errors.New("publish gate timed out, retry later or reduce the gate cases")
+	ErrDatabaseErr          = errors.New("query error, please contact admin")
+)
+
+const (
+	defaultWorkers        = 32
+	defaultConcurrency    = 4
+	defaultMaxConcurrency = 16
+	defaultMaxRows        = 10000
+	defaultMaxJudgeItems  = 100
+	defaultJudgeRetries   = 2
+)
+
+type IService interface {
+	CreateRun(ctx context.Context, userID int64, username string, req dto.CreateEvalRunDTO, file io.Reader, format string) (*model.EvalRun, error)
+	GetRun(ctx context.Context, id string) (*model.EvalRun, error)
+	ListRuns(ctx context.Context, filter eval.RunFilter, offset, limit int) ([]*model.EvalRun, int64, error)
+	ListResults(ctx context.Context, runID, status string, offset, limit int) ([]*model.EvalResult, int64, error)
+	Cancel(ctx context.Context, id string) error
+
+	Score(ctx context.Context, req dto.ScoreDTO) (*scorer.Report, error)
+	Judge(ctx context.Context, userID int64, username string, req dto.JudgeDTO) (*JudgeReport, error)
+
+	CreateComparison(ctx context.Context, userID int64, username string, req dto.CreateEvalCompareDTO, file io.Reader, format string) (*model.EvalComparison, error)
+	GetComparison(ctx context.Context, id string) (*model.EvalComparison, error)
+	ListComparisons(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalComparison, int64, error)
+	CancelComparison(ctx context.Context, id string) error
+	CompareReport(ctx context.Context, id, change string, offset, limit int) (*CompareReport, error)
+
+	SaveGate(ctx context.Context, userID int64, username string, req dto.SaveGateDTO) (*model.EvalGate, error)
+	GetGate(ctx context.Context, promptID string) (*model.EvalGate, error)
+	DeleteGate(ctx context.Context, promptID string) error
+	CheckGate(ctx context.Context, userID int64, username string, req dto.CheckGateDTO) (*GateReport, error)
+	CheckPublish(ctx context.Context, v *model.PromptVersion, userID int64, username string) (*GateReport, error)
+	CreateAssertionSet(ctx context.Context, userID int64, username string, req dto.CreateAssertionSetDTO) (*model.EvalAssertionSet, error)
+	UpdateAssertionSet(ctx context.Context, req dto.UpdateAssertionSetDTO) (*model.EvalAssertionSet, error)
+	GetAssertionSet(ctx context.Context, id string) (*model.EvalAssertionSet, error)
+	ListAssertionSets(ctx context.Context, promptID string, offset, limit int) ([]*model.EvalAssertionSet, int64, error)
+	DeleteAssertionSet(ctx context.Context, id string) error
+}
+
+type Service struct {
+	repo           *eval.Repo
+	versionRepo    *version.Repo
+	promptRepo     *prompt.Repo
+	datasetService *dataset.Service
+	client         *proxyClient
+	logger         *zap.Logger
+
+	// slots 所有任务共享的并发请求配额
+	slots          chan struct{}
+	concurrency    int
+	maxConcurrency int
+	maxRows        int
+	maxJudgeItems  int
+	judgeRetries   int
+	gateTimeout    time.Duration
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+func CreateEvalService(repo *eval.Repo, versionRepo *version.Repo, promptRepo *prompt.Repo, datasetService *dataset.Service, cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:           repo,
+		versionRepo:    versionRepo,
+		promptRepo:     promptRepo,
+		datasetService: datasetService,
+		client:         newProxyClient(cfg),
+		logger:         logger,
+		slots:          make(chan struct{}, orDefault(cfg.Eval.Workers, defaultWorkers)),
+		concurrency:    orDefault(cfg.Eval.Concurrency, defaultConcurrency),
+		maxConcurrency: orDefault(cfg.Eval.MaxConcurrency, defaultMaxConcurrency),
+		maxRows:        orDefault(cfg.Eval.MaxRows, defaultMaxRows),
+		maxJudgeItems:  orDefault(cfg.Eval.MaxJudgeItems, defaultMaxJudgeItems),
+		judgeRetries:   orDefault(cfg.Eval.JudgeRetries, defaultJudgeRetries),
+		gateTimeout:    cfg.Eval.GateTimeout,
+		running:        make(map[string]context.CancelFunc),
+	}
+	if s.gateTimeout <= 0 {
+		s.gateTimeout = defaultGateTimeout
+	}
+	// 任务只在内存中执行, 重启前未完成的任务无法继续
+	if n, err := repo.InterruptRuns(context.Background(), "interrupted by server restart"); err != nil {
+		logger.Error(err.Error())
+	} else if n > 0 {
+		logger.Warn("interrupted eval runs", zap.Int64("count", n))
+	}
+	return s
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// CreateRun 创建评测任务并在后台执行; 变量来自上传的文件, 未上传时使用数据集
+func (s *Service) CreateRun(ctx context.Context, userID int64, username string, req dto.CreateEvalRunDTO, file io.Reader, format string) (*model.EvalRun, error) {
+	v, err := s.getVersion(ctx, req.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.loadRows(ctx, v, req, file, format)
+	if err != nil {
+		return nil, err
+	}
+	return s.startRun(ctx, userID, username, v, req, file != nil, rows)
+}
+
+func (s *Service) getVersion(ctx context.Context, id string) (*model.PromptVersion, error) {
+	v, err := s.versionRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if v == nil {
+		return nil, ErrVersionNotFound
+	}
+	return v, nil
+}
+
+// startRun 用版本 v 对 rows 创建任务并在后台执行, uploaded 表示变量来自上传的文件
+func (s *Service) startRun(ctx context.Context, userID int64, username string, v *model.PromptVersion, req dto.CreateEvalRunDTO, uploaded bool, rows []*model.DatasetRow) (*model.EvalRun, error) {
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(rows) > s.maxRows {
+		return nil, ErrTooManyRows
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = s.concurrency
+	}
+	concurrency = min(concurrency, s.maxConcurrency)
+
+	params := make(map[string]any)
+	if req.Temperature != nil {
+		params["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		params["max_tokens"] = req.MaxTokens
+	}
+	paramsJSON, _ := json.Marshal(params)
+
+	run := &model.EvalRun{
+		ID:          uuid.New().String(),
+		PromptID:    v.PromptID,
+		VersionID:   v.ID,
+		Model:       req.Model,
+		Params:      string(paramsJSON),
+		Concurrency: concurrency,
+		Status:      model.EvalRunPending,
+		Total:       len(rows),
+		UserID:      userID,
+		Username:    username,
+	}
+	if !uploaded {
+		run.DatasetID = req.DatasetID
+		run.DatasetVersion = req.DatasetVersion
+	}
+	results := make([]*model.EvalResult, len(rows))
+	for i, row := range rows {
+		results[i] = &model.EvalResult{
+			ID:        uuid.New().String(),
+			RunID:     run.ID,
+			Position:  i + 1,
+			Variables: row.Variables,
+			Expected:  row.Expected,
+			Reference: row.Reference,
+			Status:    model.EvalResultPending,
+		}
+	}
+	if err := s.repo.CreateRun(ctx, run, results); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.running[run.ID] = cancel
+	s.mu.Unlock()
+	go s.execute(runCtx, run, v.Content, params, results)
+	return run, nil
+}
+
+func (s *Service) loadRows(ctx context.Context, v *model.PromptVersion, req dto.CreateEvalRunDTO, file io.Reader, format string) ([]*model.DatasetRow, error) {
+	if file != nil {
+		return dataset.ParseRows(format, file)
+	}
+	if req.DatasetID == "" {
+		return nil, ErrNoInput
+	}
+	d, err := s.datasetService.GetByID(ctx, req.DatasetID)
+	if err != nil {
+		return nil, err
+	}
+	if d.PromptID != v.PromptID {
+		return nil, ErrDatasetMismatch
+	}
+	return s.datasetService.Rows(ctx, req.DatasetID, req.DatasetVersion)
+}
+
+// execute 按任务并发度执行所有行; 取消后未开始的行标记为 canceled
+func (s *Service) execute(ctx context.Context, run *model.EvalRun, content string, params map[string]any, results []*model.EvalResult) {
+	defer func() {
+		s.mu.Lock()
+		cancel := s.running[run.ID]
+		delete(s.running, run.ID)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	// 数据库写入不随任务取消而中断
+	db := context.Background()
+	if err := s.repo.StartRun(db, run.ID); err != nil {
+		s.logger.Error(err.Error())
+	}
+
+	jobs := make(chan *model.EvalResult)
+	var wg sync.WaitGroup
+	for i := 0; i < min(run.Concurrency, len(results)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				s.evaluate(ctx, run, content, params, result)
+			}
+		}()
+	}
+feed:
+	for _, result := range results {
+		select {
+		case jobs <- result:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	status := model.EvalRunCompleted
+	if ctx.Err() != nil {
+		status = model.EvalRunCanceled
+		if err := s.repo.CancelPending(db, run.ID); err != nil {
+			s.logger.Error(err.Error())
+		}
+	}
+	if err := s.repo.FinishRun(db, run.ID, status, ""); err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
+// evaluate 渲染并执行一行, 任务被取消时该行保持 pending
+func (s *Service) evaluate(ctx context.Context, run *model.EvalRun, content string, params map[string]any, result *model.EvalResult) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return
+	}
+
+	row := model.DatasetRow{Variables: result.Variables}
+	input, err := render(content, row.VariableMap())
+	if err != nil {
+		result.Status = model.EvalResultFailed
+		result.Error = err.Error()
+	} else {
+		result.Input = input
+		start := time.Now()
+		out, err := s.client.chat(ctx, run.UserID, run.Username, run.Model,
+			[]chatMessage{{Role: "user", Content: input}}, params)
+		if ctx.Err() != nil {
+			return
+		}
+		result.LatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Status = model.EvalResultFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = model.EvalResultSucceeded
+			result.Output = out.Output
+			result.PromptTokens = out.PromptTokens
+			result.CompletionTokens = out.CompletionTokens
+			result.Cost = out.Cost
+		}
+	}
+	if err := s.repo.SaveResult(context.Background(), result); err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
+func (s *Service) GetRun(ctx context.Context, id string) (*model.EvalRun, error) {
+	run, err := s.repo.GetRun(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if run == nil {
+		return nil, ErrRunNotFound
+	}
+	return run, nil
+}
+
+func (s *Service) ListRuns(ctx context.Context, filter eval.RunFilter, offset, limit int) ([]*model.EvalRun, int64, error) {
+	list, err := s.repo.ListRuns(ctx, filter, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.CountRuns(ctx, filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+func (s *Service) ListResults(ctx context.Context, runID, status string, offset, limit int) ([]*model.EvalResult, int64, error) {
+	if _, err := s.GetRun(ctx, runID); err != nil {
+		return nil, 0, err
+	}
+	list, err := s.repo.ListResults(ctx, runID, status, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.CountResults(ctx, runID, status)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+// Cancel 取消任务, 已在执行的行会中断上游请求
+func (s *Service) Cancel(ctx context.Context, id string) error {
+	run, err := s.GetRun(ctx, id)
+	if err != nil {
+		return err
+	}
+	if run.Finished() {
+		return ErrRunFinished
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.running[id]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+
+	// 任务未在本进程执行(尚未启动或已退出)时直接标记为取消
+	if err := s.repo.CancelPending(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if err := s.repo.FinishRun(ctx, id, model.EvalRunCanceled, ""); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package experiment
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/experiment"
+	"backend/internal/repository/prompt"
+	"backend/internal/repository/version"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxVariants = 10
+	// buckets subject 哈希后落入的桶数, 决定分流精度为 0.01%
+	buckets = 10000
+	// maxWeight 单个分组的权重上限, 权重之和不超过 maxVariants*maxWeight, 分流时 point*total 不会溢出
+	maxWeight = buckets
+)
+
+var (
+	ErrPromptNotFound     = errors.New("prompt not found")
+	ErrExperimentNotFound = errors.New("experiment not found")
+	ErrVersionNotFound    = errors.New("variant version does not belong to the prompt")
+	ErrVersionNotPublish  = errors.New("variant version is not published")
+	ErrInvalidVariants    = errors.New("an experiment needs 2 to 10 variants with unique names and weights between 0 and 10000 summing above 0")
+	ErrDatabaseErr        = errors.New("query error, please contact admin")
+)
+
+type IService interface {
+	Save(ctx context.Context, userID int64, username string, req dto.SaveExperimentDTO) (*model.PromptExperiment, error)
+	Get(ctx context.Context, promptID string) (*model.PromptExperiment, error)
+	Delete(ctx context.Context, promptID string) error
+	Assign(ctx context.Context, promptID, subjectID string) (*Assignment, error)
+}
+
+type Service struct {
+	repo        *experiment.Repo
+	promptRepo  *prompt.Repo
+	versionRepo *version.Repo
+	logger      *zap.Logger
+}
+
+func CreateExperimentService(repo *experiment.Repo, promptRepo *prompt.Repo, versionRepo *version.Repo, logger *zap.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		promptRepo:  promptRepo,
+		versionRepo: versionRepo,
+		logger:      logger,
+	}
+}
+
+// Assignment 一次请求分到的实验分组
+type Assignment struct {
+	ExperimentID string `json:"experimentId"`
+	Variant      string `json:"variant"`
+	VersionID    string `json:"versionId"`
+	SubjectID    string `json:"subjectId,omitempty"`
+	// Sticky 为 true 时按 SubjectID 固定分组, 未提供 SubjectID 时按权重随机分配
+	Sticky bool `json:"sticky"`
+}
+
+// Save 创建或覆盖提示词的分流实验; 覆盖时保留实验 ID, 已有 subject 的分组尽量不变
+func (s *Service) Save(ctx context.Context, userID int64, username string, req dto.SaveExperimentDTO) (*model.PromptExperiment, error) {
+	p, err := s.promptRepo.GetByID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+	if err := s.validate(ctx, req); err != nil {
+		return nil, err
+	}
+	variants, _ := json.Marshal(req.Variants)
+
+	e, err := s.repo.GetByPromptID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	create := e == nil
+	if create {
+		e = &model.PromptExperiment{
+			ID:        uuid.New().String(),
+			PromptID:  req.PromptID,
+			CreatedBy: strconv.FormatInt(userID, 10),
+			Username:  username,
+		}
+	}
+	e.Variants = string(variants)
+	e.Enabled = req.Enabled == nil || *req.Enabled
+
+	if create {
+		err = s.repo.Create(ctx, e)
+	} else {
+		err = s.repo.Update(ctx, e)
+	}
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return e, nil
+}
+
+func (s *Service) validate(ctx context.Context, req dto.SaveExperimentDTO) error {
+	if len(req.Variants) < 2 || len(req.Variants) > maxVariants {
+		return ErrInvalidVariants
+	}
+	versions, err := s.versionRepo.GetByPromptID(ctx, req.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	// 分组只能使用已发布(通过发布门禁或管理员跳过)的版本, 草稿不会下发给调用方
+	owned := make(map[string]bool, len(versions))
+	published := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		owned[v.ID] = true
+		published[v.ID] = v.IsPublish
+	}
+
+	names := make(map[string]bool, len(req.Variants))
+	total := 0
+	for _, v := range req.Variants {
+		name := strings.TrimSpace(v.Name)
+		if name == "" || names[name] || v.Weight < 0 || v.Weight > maxWeight {
+			return ErrInvalidVariants
+		}
+		names[name] = true
+		total += v.Weight
+		if !owned[v.VersionID] {
+			return ErrVersionNotFound
+		}
+		if !published[v.VersionID] {
+			return ErrVersionNotPublish
+		}
+	}
+	if total <= 0 {
+		return ErrInvalidVariants
+	}
+	return nil
+}
+
+func (s *Service) Get(ctx context.Context, promptID string) (*model.PromptExperiment, error) {
+	e, err := s.repo.GetByPromptID(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if e == nil {
+		return nil, ErrExperimentNotFound
+	}
+	return e, nil
+}
+
+func (s *Service) Delete(ctx context.Context, promptID string) error {
+	if _, err := s.Get(ctx, promptID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteByPromptID(ctx, promptID); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// Assign 为一次请求选择实验分组; 没有实验或实验已停用时返回 nil, 调用方使用已发布版本
+func (s *Service) Assign(ctx context.Context, promptID, subjectID string) (*Assignment, error) {
+	e, err := s.repo.GetByPromptID(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if e == nil || !e.Enabled {
+		return nil, nil
+	}
+	var variants []dto.ExperimentVariantDTO
+	if err := json.Unmarshal([]byte(e.Variants), &variants); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+
+	// 保存后被取消发布或删除的版本不再参与分流, 其权重由其余分组按比例分摊
+	ids, err := s.versionRepo.GetPublishedIDs(ctx, promptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	published := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		published[id] = true
+	}
+	active := variants[:0]
+	total := 0
+	for _, v := range variants {
+		if published[v.VersionID] {
+			// 兼容限制权重前保存的实验
+			v.Weight = min(max(v.Weight, 0), maxWeight)
+			active = append(active, v)
+			total += v.Weight
+		}
+	}
+	if total <= 0 {
+		return nil, nil
+	}
+
+	a := &Assignment{ExperimentID: e.ID, SubjectID: subjectID, Sticky: subjectID != ""}
+	point := rand.IntN(buckets)
+	if a.Sticky {
+		point = bucket(e.ID, subjectID)
+	}
+	// 分组按配置顺序占据连续区间, 只调大末尾分组的权重时, 已在末尾分组的 subject 不会换组
+	acc := 0
+	for _, v := range active {
+		acc += v.Weight
+		if point*total < acc*buckets {
+			a.Variant = v.Name
+			a.VersionID = v.VersionID
+			break
+		}
+	}
+	return a, nil
+}
+
+// bucket 把 subject 映射到 [0, buckets), 哈希中带上实验 ID, 不同提示词的实验互不相关
+func bucket(experimentID, subjectID string) int {
+	h := fnv.New64a()
+	h.Write([]byte(experimentID))
+	h.Write([]byte{':'})
+	h.Write([]byte(subjectID))
+	return int(h.Sum64() % buckets)
+}
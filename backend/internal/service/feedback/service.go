@@ -0,0 +1,286 @@
+package feedback
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/feedback"
+	"backend/internal/repository/prompt"
+	"backend/internal/repository/version"
+	"backend/pkg/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTags          = 10
+	maxTagLength     = 64
+	maxTraceIDLength = 128
+	maxCommentLength = 4000
+
+	defaultMinScore      = -1
+	defaultMaxScore      = 5
+	defaultRatePerMinute = 60
+)
+
+var (
+	ErrPromptNotFound  = errors.New("prompt not found")
+	ErrVersionNotFound = errors.New("version does not belong to the prompt")
+	ErrTooManyTags     = errors.New("at most 10 tags of up to 64 characters each")
+	ErrTraceIDTooLong  = errors.New("traceId must be at most 128 characters")
+	ErrCommentTooLong  = errors.New("comment must be at most 4000 characters")
+	ErrTooManyRequests = errors.New("too many feedback submissions, retry later")
+	ErrDatabaseErr     = errors.New("query error, please contact admin")
+)
+
+type IService interface {
+	Submit(ctx context.Context, source string, req dto.SubmitFeedbackDTO) (*model.Feedback, error)
+	List(ctx context.Context, filter feedback.Filter, offset, limit int) ([]*model.Feedback, int64, error)
+	Summary(ctx context.Context, filter feedback.Filter) (*Summary, error)
+}
+
+type Service struct {
+	repo        *feedback.Repo
+	promptRepo  *prompt.Repo
+	versionRepo *version.Repo
+	logger      *zap.Logger
+	// minScore / maxScore 允许的分数范围, errScore 为超出范围时的错误
+	minScore float64
+	maxScore float64
+	errScore error
+	limiter  *sourceLimiter
+}
+
+func CreateFeedbackService(repo *feedback.Repo, promptRepo *prompt.Repo, versionRepo *version.Repo, cfg *config.Config, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:        repo,
+		promptRepo:  promptRepo,
+		versionRepo: versionRepo,
+		logger:      logger,
+		minScore:    cfg.Feedback.MinScore,
+		maxScore:    cfg.Feedback.MaxScore,
+	}
+	if s.maxScore <= s.minScore {
+		s.minScore, s.maxScore = defaultMinScore, defaultMaxScore
+	}
+	s.errScore = &ScoreError{Min: s.minScore, Max: s.maxScore}
+	switch rate := cfg.Feedback.RatePerMinute; {
+	case rate == 0:
+		s.limiter = newSourceLimiter(defaultRatePerMinute)
+	case rate > 0:
+		s.limiter = newSourceLimiter(rate)
+	}
+	return s
+}
+
+// ScoreError 分数超出配置的范围
+type ScoreError struct {
+	Min float64
+	Max float64
+}
+
+func (e *ScoreError) Error() string {
+	return fmt.Sprintf("score must be between %g and %g", e.Min, e.Max)
+}
+
+// sourceLimiter 按来源(客户端 IP)计数的固定窗口限流, 窗口为 1 分钟
+type sourceLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window int64
+	counts map[string]int
+}
+
+func newSourceLimiter(limit int) *sourceLimiter {
+	return &sourceLimiter{limit: limit, counts: make(map[string]int)}
+}
+
+func (l *sourceLimiter) allow(source string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 进入新窗口时整体清空, 内存只与一分钟内的来源数有关
+	if window := now.Unix() / 60; window != l.window {
+		l.window = window
+		clear(l.counts)
+	}
+	if l.counts[source] >= l.limit {
+		return false
+	}
+	l.counts[source]++
+	return true
+}
+
+// VersionSummary 一个版本的反馈汇总, Tags 为各标签出现的次数
+type VersionSummary struct {
+	*model.FeedbackStats
+	Version   string           `json:"version"`
+	IsPublish bool             `json:"isPublish"`
+	Tags      map[string]int64 `json:"tags"`
+}
+
+// Summary 一个提示词各版本的反馈汇总, 按版本创建时间倒序
+type Summary struct {
+	PromptID      string            `json:"promptId"`
+	LatestVersion string            `json:"latestVersion"`
+	Total         int64             `json:"total"`
+	Versions      []*VersionSummary `json:"versions"`
+}
+
+// Submit 记录一条反馈; 版本必须属于 path 对应的提示词, 不要求仍处于发布状态(下发后可能被取消发布)
+// source 为上报方的客户端 IP, 用于限流
+func (s *Service) Submit(ctx context.Context, source string, req dto.SubmitFeedbackDTO) (*model.Feedback, error) {
+	if s.limiter != nil && !s.limiter.allow(source, time.Now()) {
+		return nil, ErrTooManyRequests
+	}
+	if score := *req.Score; score < s.minScore || score > s.maxScore {
+		return nil, s.errScore
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(req.TraceID) > maxTraceIDLength {
+		return nil, ErrTraceIDTooLong
+	}
+	if utf8.RuneCountInString(req.Comment) > maxCommentLength {
+		return nil, ErrCommentTooLong
+	}
+
+	p, err := s.promptRepo.GetByPath(ctx, req.Path)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+	v, err := s.versionRepo.GetByID(ctx, req.VersionID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if v == nil || v.PromptID != p.ID {
+		return nil, ErrVersionNotFound
+	}
+
+	tagsJSON, _ := json.Marshal(tags)
+	f := &model.Feedback{
+		ID:        uuid.New().String(),
+		PromptID:  p.ID,
+		VersionID: v.ID,
+		TraceID:   req.TraceID,
+		Score:     *req.Score,
+		Tags:      string(tagsJSON),
+		Comment:   req.Comment,
+	}
+	if err := s.repo.Create(ctx, f, tags); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return f, nil
+}
+
+// normalizeTags 去掉首尾空白、空标签和重复标签
+func normalizeTags(raw []string) ([]string, error) {
+	tags := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, tag := range raw {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrTooManyTags
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return nil, ErrTooManyTags
+	}
+	return tags, nil
+}
+
+func (s *Service) List(ctx context.Context, filter feedback.Filter, offset, limit int) ([]*model.Feedback, int64, error) {
+	list, err := s.repo.List(ctx, filter, offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	count, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, 0, ErrDatabaseErr
+	}
+	return list, count, nil
+}
+
+// Summary 按版本汇总提示词的反馈, 只包含收到过反馈的版本
+func (s *Service) Summary(ctx context.Context, filter feedback.Filter) (*Summary, error) {
+	p, err := s.promptRepo.GetByID(ctx, filter.PromptID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if p == nil {
+		return nil, ErrPromptNotFound
+	}
+
+	stats, err := s.repo.Stats(ctx, filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	tagCounts, err := s.repo.TagCounts(ctx, filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	versions, err := s.versionRepo.GetByPromptID(ctx, p.ID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+
+	byVersion := make(map[string]*VersionSummary, len(stats))
+	for _, st := range stats {
+		byVersion[st.VersionID] = &VersionSummary{FeedbackStats: st, Tags: make(map[string]int64)}
+	}
+	for _, tc := range tagCounts {
+		if vs, ok := byVersion[tc.VersionID]; ok {
+			vs.Tags[tc.Tag] = tc.Count
+		}
+	}
+
+	summary := &Summary{
+		PromptID:      p.ID,
+		LatestVersion: p.LatestVersion,
+		Versions:      make([]*VersionSummary, 0, len(stats)),
+	}
+	for _, v := range versions {
+		vs, ok := byVersion[v.ID]
+		if !ok {
+			continue
+		}
+		vs.Version = v.Version
+		vs.IsPublish = v.IsPublish
+		summary.Versions = append(summary.Versions, vs)
+		summary.Total += vs.Count
+		delete(byVersion, v.ID)
+	}
+	// 版本已被删除的反馈仍然返回, version 为空
+	for _, st := range stats {
+		if vs, ok := byVersion[st.VersionID]; ok {
+			summary.Versions = append(summary.Versions, vs)
+			summary.Total += vs.Count
+		}
+	}
+	return summary, nil
+}
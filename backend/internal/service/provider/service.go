@@ -0,0 +1,435 @@
+package provider
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/provider"
+	"backend/pkg/config"
+	"backend/pkg/crypto"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrProviderNotFound = errors.New("provider not found")
+	ErrProviderExists   = errors.New("provider already exists")
+	ErrProviderInUse    = errors.New("provider is still used by models")
+	ErrApiKeyRequired   = errors.New("apiKey is required for this provider type")
+	ErrModelNotFound    = errors.New("model not found")
+	ErrModelExists      = errors.New("model already exists")
+	ErrDatabaseErr      = errors.New("query error, please contact admin")
+)
+
+// ModelDetail 模型及其按轮询顺序排列的上游服务
+type ModelDetail struct {
+	*model.ProxyModel
+	Providers []*model.ProxyProvider
+}
+
+type IService interface {
+	CreateProvider(ctx context.Context, req dto.CreateProviderDTO) (*model.ProxyProvider, error)
+	ListProviders(ctx context.Context) ([]*model.ProxyProvider, error)
+	UpdateProvider(ctx context.Context, id string, req dto.UpdateProviderDTO) (*model.ProxyProvider, error)
+	DeleteProvider(ctx context.Context, id string) error
+	CreateModel(ctx context.Context, req dto.ProxyModelDTO) (*ModelDetail, error)
+	ListModels(ctx context.Context) ([]*ModelDetail, error)
+	UpdateModel(ctx context.Context, id string, req dto.ProxyModelDTO) (*ModelDetail, error)
+	DeleteModel(ctx context.Context, id string) error
+	LoadModels(ctx context.Context) (map[string]config.ProxyModel, error)
+	Bootstrap(ctx context.Context, models map[string]config.ProxyModel) error
+}
+
+type Service struct {
+	repo   *provider.Repo
+	cfg    *config.Config
+	logger *zap.Logger
+}
+
+func CreateProviderService(repo *provider.Repo, cfg *config.Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+func (s *Service) CreateProvider(ctx context.Context, req dto.CreateProviderDTO) (*model.ProxyProvider, error) {
+	list, err := s.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		if strings.EqualFold(p.Name, req.Name) {
+			return nil, ErrProviderExists
+		}
+	}
+	if req.ApiKey == "" && providerType(req.Type) != "ollama" {
+		return nil, ErrApiKeyRequired
+	}
+
+	encrypted, err := s.encrypt(req.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	p := &model.ProxyProvider{
+		ID:      uuid.New().String(),
+		Name:    req.Name,
+		Type:    providerType(req.Type),
+		ApiBase: strings.TrimRight(req.ApiBase, "/"),
+		ApiKey:  encrypted,
+		Headers: encodeHeaders(req.Headers),
+	}
+	if err := s.repo.CreateProvider(ctx, p); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return p, nil
+}
+
+func (s *Service) ListProviders(ctx context.Context) ([]*model.ProxyProvider, error) {
+	list, err := s.repo.ListProviders(ctx)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return list, nil
+}
+
+func (s *Service) UpdateProvider(ctx context.Context, id string, req dto.UpdateProviderDTO) (*model.ProxyProvider, error) {
+	list, err := s.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var p *model.ProxyProvider
+	for _, item := range list {
+		if item.ID == id {
+			p = item
+		} else if strings.EqualFold(item.Name, req.Name) {
+			return nil, ErrProviderExists
+		}
+	}
+	if p == nil {
+		return nil, ErrProviderNotFound
+	}
+	if req.ApiKey == "" && p.ApiKey == "" && providerType(req.Type) != "ollama" {
+		return nil, ErrApiKeyRequired
+	}
+
+	p.Name = req.Name
+	p.Type = providerType(req.Type)
+	p.ApiBase = strings.TrimRight(req.ApiBase, "/")
+	p.Headers = encodeHeaders(req.Headers)
+	if req.ApiKey != "" {
+		encrypted, err := s.encrypt(req.ApiKey)
+		if err != nil {
+			return nil, err
+		}
+		p.ApiKey = encrypted
+	}
+	if err := s.repo.UpdateProvider(ctx, p); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return p, nil
+}
+
+// DeleteProvider 删除上游服务, 仍被模型引用时拒绝删除
+func (s *Service) DeleteProvider(ctx context.Context, id string) error {
+	p, err := s.repo.GetProvider(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if p == nil {
+		return ErrProviderNotFound
+	}
+	refs, err := s.repo.CountProviderRefs(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if refs > 0 {
+		return ErrProviderInUse
+	}
+	if err := s.repo.DeleteProvider(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+func (s *Service) CreateModel(ctx context.Context, req dto.ProxyModelDTO) (*ModelDetail, error) {
+	models, err := s.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range models {
+		if strings.EqualFold(m.Name, req.Name) {
+			return nil, ErrModelExists
+		}
+	}
+	providers, err := s.resolveProviders(ctx, req.ProviderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.ProxyModel{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		InputPrice:  req.InputPrice,
+		OutputPrice: req.OutputPrice,
+	}
+	if err := s.repo.CreateModel(ctx, m, providerIDs(providers)); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return &ModelDetail{ProxyModel: m, Providers: providers}, nil
+}
+
+func (s *Service) ListModels(ctx context.Context) ([]*ModelDetail, error) {
+	models, err := s.repo.ListModels(ctx)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	providers, err := s.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := s.repo.ListEndpoints(ctx)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+
+	providerMap := make(map[string]*model.ProxyProvider, len(providers))
+	for _, p := range providers {
+		providerMap[p.ID] = p
+	}
+	res := make([]*ModelDetail, 0, len(models))
+	index := make(map[string]*ModelDetail, len(models))
+	for _, m := range models {
+		d := &ModelDetail{ProxyModel: m, Providers: make([]*model.ProxyProvider, 0)}
+		index[m.ID] = d
+		res = append(res, d)
+	}
+	for _, ep := range endpoints {
+		d, ok := index[ep.ModelID]
+		if !ok {
+			continue
+		}
+		if p, ok := providerMap[ep.ProviderID]; ok {
+			d.Providers = append(d.Providers, p)
+		}
+	}
+	return res, nil
+}
+
+func (s *Service) UpdateModel(ctx context.Context, id string, req dto.ProxyModelDTO) (*ModelDetail, error) {
+	models, err := s.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var m *model.ProxyModel
+	for _, item := range models {
+		if item.ID == id {
+			m = item.ProxyModel
+		} else if strings.EqualFold(item.Name, req.Name) {
+			return nil, ErrModelExists
+		}
+	}
+	if m == nil {
+		return nil, ErrModelNotFound
+	}
+	providers, err := s.resolveProviders(ctx, req.ProviderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Name = req.Name
+	m.InputPrice = req.InputPrice
+	m.OutputPrice = req.OutputPrice
+	if err := s.repo.UpdateModel(ctx, m, providerIDs(providers)); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return &ModelDetail{ProxyModel: m, Providers: providers}, nil
+}
+
+func (s *Service) DeleteModel(ctx context.Context, id string) error {
+	m, err := s.repo.GetModel(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if m == nil {
+		return ErrModelNotFound
+	}
+	if err := s.repo.DeleteModel(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// LoadModels 读取数据库中的模型配置并解密上游 key, 转换为代理使用的配置结构
+func (s *Service) LoadModels(ctx context.Context) (map[string]config.ProxyModel, error) {
+	models, err := s.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]config.ProxyModel, len(models))
+	for _, m := range models {
+		pm := config.ProxyModel{
+			Price:     config.ModelPrice{Input: m.InputPrice, Output: m.OutputPrice},
+			Endpoints: make([]config.ProxyEndpoint, 0, len(m.Providers)),
+		}
+		for _, p := range m.Providers {
+			apiKey, err := s.decrypt(p.ApiKey)
+			if err != nil {
+				s.logger.Error("decrypt provider api key failed", zap.String("provider", p.Name), zap.Error(err))
+				continue
+			}
+			pm.Endpoints = append(pm.Endpoints, config.ProxyEndpoint{
+				Name:    p.Name,
+				Type:    p.Type,
+				ApiBase: p.ApiBase,
+				ApiKey:  apiKey,
+				Headers: p.ExtraHeaders(),
+			})
+		}
+		res[m.Name] = pm
+	}
+	return res, nil
+}
+
+// Bootstrap 数据库中还没有任何上游服务时, 将配置文件中的模型导入数据库
+func (s *Service) Bootstrap(ctx context.Context, models map[string]config.ProxyModel) error {
+	if len(models) == 0 {
+		return nil
+	}
+	existing, err := s.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// 配置文件中同名 endpoint 视为同一个上游服务
+	providers := make(map[string]*model.ProxyProvider)
+	for _, name := range names {
+		mc := models[name]
+		ids := make([]string, 0, len(mc.Endpoints))
+		for _, ep := range mc.Endpoints {
+			if ep.Name == "" || ep.ApiBase == "" || (ep.ApiKey == "" && providerType(ep.Type) != "ollama") {
+				continue
+			}
+			p, ok := providers[ep.Name]
+			if !ok {
+				p, err = s.CreateProvider(ctx, dto.CreateProviderDTO{
+					Name:    ep.Name,
+					Type:    ep.Type,
+					ApiBase: ep.ApiBase,
+					ApiKey:  ep.ApiKey,
+					Headers: ep.Headers,
+				})
+				if err != nil {
+					return err
+				}
+				providers[ep.Name] = p
+			}
+			ids = append(ids, p.ID)
+		}
+		_, err := s.CreateModel(ctx, dto.ProxyModelDTO{
+			Name:        name,
+			InputPrice:  mc.Price.Input,
+			OutputPrice: mc.Price.Output,
+			ProviderIDs: ids,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.logger.Info("proxy models imported from config", zap.Int("models", len(names)), zap.Int("providers", len(providers)))
+	return nil
+}
+
+// resolveProviders 校验并按请求顺序返回上游服务, 重复的 id 只保留第一个
+func (s *Service) resolveProviders(ctx context.Context, ids []string) ([]*model.ProxyProvider, error) {
+	res := make([]*model.ProxyProvider, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		p, err := s.repo.GetProvider(ctx, id)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return nil, ErrDatabaseErr
+		}
+		if p == nil {
+			return nil, ErrProviderNotFound
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// encrypt 空 key(如本地 ollama)不加密, 直接存空串
+func (s *Service) encrypt(apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", nil
+	}
+	encrypted, err := crypto.Encrypt(apiKey, s.cfg.Security.EncryptKey)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return "", err
+	}
+	return encrypted, nil
+}
+
+func (s *Service) decrypt(apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", nil
+	}
+	return crypto.Decrypt(apiKey, s.cfg.Security.EncryptKey)
+}
+
+func providerType(t string) string {
+	if t == "" {
+		return "openai"
+	}
+	return t
+}
+
+func providerIDs(list []*model.ProxyProvider) []string {
+	res := make([]string, 0, len(list))
+	for _, p := range list {
+		res = append(res, p.ID)
+	}
+	return res
+}
+
+// encodeHeaders 附加请求头以 JSON 存储, 为空时存空串
+func encodeHeaders(h map[string]string) string {
+	if len(h) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(h)
+	return string(b)
+}
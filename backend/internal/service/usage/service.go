@@ -0,0 +1,196 @@
+package usage
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository/usage"
+	"backend/pkg/config"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDatabaseErr = errors.New("query error, please contact admin")
+)
+
+// BudgetError 预算耗尽时返回, 携带超限的范围与金额
+type BudgetError struct {
+	Scope  string // user | department
+	Name   string
+	Period string // daily | monthly
+	Spent  float64
+	Limit  float64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf(
+		"%s budget exhausted for %s %s: spent %.4f of %.4f",
+		e.Period, e.Scope, e.Name, e.Spent, e.Limit,
+	)
+}
+
+// Spend 某个周期内的花费与预算, Limit 为 0 表示不限制
+type Spend struct {
+	Spent float64 `json:"spent"`
+	Limit float64 `json:"limit"`
+}
+
+type Summary struct {
+	Username          string `json:"username"`
+	Department        string `json:"department"`
+	Daily             Spend  `json:"daily"`
+	Monthly           Spend  `json:"monthly"`
+	DepartmentDaily   *Spend `json:"departmentDaily,omitempty"`
+	DepartmentMonthly *Spend `json:"departmentMonthly,omitempty"`
+}
+
+type IService interface {
+	Cost(price config.ModelPrice, promptTokens, completionTokens int64) float64
+	CheckBudget(ctx context.Context, userID int64, username, department string) error
+	Record(ctx context.Context, u *model.ProxyUsage) error
+	Summary(ctx context.Context, userID int64, username, department string) (*Summary, error)
+}
+
+type Service struct {
+	repo   *usage.Repo
+	cfg    *config.Config
+	logger *zap.Logger
+}
+
+func CreateUsageService(repo *usage.Repo, cfg *config.Config, logger *zap.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Cost 按模型单价(每百万 token)计算费用, 未配置价格的模型费用为 0
+func (s *Service) Cost(price config.ModelPrice, promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000
+}
+
+// CheckBudget 检查用户及其部门的日/月预算, 超限返回 *BudgetError
+func (s *Service) CheckBudget(ctx context.Context, userID int64, username, department string) error {
+	if userID > 0 {
+		budget := s.userBudget(username)
+		err := s.checkBudget("user", username, budget, func(since time.Time) (float64, error) {
+			return s.repo.SumCostByUser(ctx, userID, since)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if department != "" {
+		budget, ok := s.cfg.Proxy.Budgets.Departments[strings.ToLower(department)]
+		if !ok {
+			return nil
+		}
+		return s.checkBudget("department", department, budget, func(since time.Time) (float64, error) {
+			return s.repo.SumCostByDepartment(ctx, department, since)
+		})
+	}
+	return nil
+}
+
+func (s *Service) checkBudget(scope, name string, budget config.Budget, sum func(since time.Time) (float64, error)) error {
+	dayStart, monthStart := periodStarts(time.Now())
+
+	periods := []struct {
+		name  string
+		limit float64
+		since time.Time
+	}{
+		{"daily", budget.Daily, dayStart},
+		{"monthly", budget.Monthly, monthStart},
+	}
+	for _, p := range periods {
+		if p.limit <= 0 {
+			continue
+		}
+		spent, err := sum(p.since)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return ErrDatabaseErr
+		}
+		if spent >= p.limit {
+			return &BudgetError{
+				Scope:  scope,
+				Name:   name,
+				Period: p.name,
+				Spent:  spent,
+				Limit:  p.limit,
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) userBudget(username string) config.Budget {
+	if b, ok := s.cfg.Proxy.Budgets.Users[strings.ToLower(username)]; ok {
+		return b
+	}
+	return s.cfg.Proxy.Budgets.Default
+}
+
+func (s *Service) Record(ctx context.Context, u *model.ProxyUsage) error {
+	u.ID = uuid.New().String()
+	if err := s.repo.Create(ctx, u); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+func (s *Service) Summary(ctx context.Context, userID int64, username, department string) (*Summary, error) {
+	dayStart, monthStart := periodStarts(time.Now())
+	budget := s.userBudget(username)
+
+	daily, err := s.repo.SumCostByUser(ctx, userID, dayStart)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	monthly, err := s.repo.SumCostByUser(ctx, userID, monthStart)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+
+	res := &Summary{
+		Username:   username,
+		Department: department,
+		Daily:      Spend{Spent: daily, Limit: budget.Daily},
+		Monthly:    Spend{Spent: monthly, Limit: budget.Monthly},
+	}
+
+	if department == "" {
+		return res, nil
+	}
+	deptBudget := s.cfg.Proxy.Budgets.Departments[strings.ToLower(department)]
+	deptDaily, err := s.repo.SumCostByDepartment(ctx, department, dayStart)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	deptMonthly, err := s.repo.SumCostByDepartment(ctx, department, monthStart)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	res.DepartmentDaily = &Spend{Spent: deptDaily, Limit: deptBudget.Daily}
+	res.DepartmentMonthly = &Spend{Spent: deptMonthly, Limit: deptBudget.Monthly}
+	return res, nil
+}
+
+// periodStarts 返回当天及当月的起始时间(本地时区)
+func periodStarts(now time.Time) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()),
+		time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+}
@@ -5,8 +5,10 @@ import (
 	"backend/internal/model"
 	"backend/internal/repository/prompt"
 	"backend/internal/repository/version"
+	"backend/internal/service/eval"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -18,9 +20,26 @@ var (
 	ErrDatabaseErr          = errors.New("query error, please contact admin")
 )
 
+// GateError 发布被质量门禁拦截, Report 为门禁的执行结果
+type GateError struct {
+	Report *eval.GateReport
+}
+
+func (e *GateError) Error() string {
+	return fmt.Sprintf("publish blocked by quality gate: pass rate %.2f is below %.2f",
+		e.Report.PassRate, e.Report.MinPassRate)
+}
+
+// Publisher 发布请求的发起人, 门禁以其身份调用模型; Override 为管理员跳过门禁
+type Publisher struct {
+	UserID   int64
+	Username string
+	Override bool
+}
+
 type IService interface {
-	Create(ctx context.Context, req dto.CreatePromptVersionDTO) (*model.PromptVersion, error)
-	Update(ctx context.Context, v *model.PromptVersion) error
+	Create(ctx context.Context, req dto.CreatePromptVersionDTO, pub Publisher) (*model.PromptVersion, error)
+	Update(ctx context.Context, v *model.PromptVersion, pub Publisher) error
 	GetByID(ctx context.Context, id string) (*model.PromptVersion, error)
 	GetByPromptID(ctx context.Context, promptID string) ([]*model.PromptVersion, error)
 	GetLatestByPromptID(ctx context.Context, promptID string) (*model.PromptVersion, error)
@@ -32,18 +51,37 @@ type IService interface {
 type Service struct {
 	repo       *version.Repo
 	promptRepo *prompt.Repo
+	gate       *eval.Service
 	logger     *zap.Logger
 }
 
-func CreateVersionService(repo *version.Repo, promptRepo *prompt.Repo, logger *zap.Logger) *Service {
+func CreateVersionService(repo *version.Repo, promptRepo *prompt.Repo, gate *eval.Service, logger *zap.Logger) *Service {
 	return &Service{
 		repo:       repo,
 		promptRepo: promptRepo,
+		gate:       gate,
 		logger:     logger,
 	}
 }
 
-func (s *Service) Create(ctx context.Context, req dto.CreatePromptVersionDTO) (*model.PromptVersion, error) {
+// checkGate 发布前执行提示词的质量门禁, 未通过时返回 *GateError
+func (s *Service) checkGate(ctx context.Context, v *model.PromptVersion, pub Publisher) error {
+	if pub.Override {
+		s.logger.Warn("publish gate overridden",
+			zap.String("promptId", v.PromptID), zap.String("versionId", v.ID), zap.String("username", pub.Username))
+		return nil
+	}
+	report, err := s.gate.CheckPublish(ctx, v, pub.UserID, pub.Username)
+	if err != nil {
+		return err
+	}
+	if report != nil && !report.Pass {
+		return &GateError{Report: report}
+	}
+	return nil
+}
+
+func (s *Service) Create(ctx context.Context, req dto.CreatePromptVersionDTO, pub Publisher) (*model.PromptVersion, error) {
 	v := &model.PromptVersion{
 		ID:        uuid.New().String(),
 		PromptID:  req.PromptID,
@@ -55,6 +93,11 @@ func (s *Service) Create(ctx context.Context, req dto.CreatePromptVersionDTO) (*
 		Username:  req.Username,
 		IsPublish: req.IsPublish,
 	}
+	if v.IsPublish {
+		if err := s.checkGate(ctx, v, pub); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.repo.Create(ctx, v); err != nil {
 		s.logger.Error(err.Error())
@@ -88,7 +131,7 @@ func (s *Service) updatePromptMeta(ctx context.Context, promptID string, version
 	return s.promptRepo.Update(ctx, p)
 }
 
-func (s *Service) Update(ctx context.Context, v *model.PromptVersion) error {
+func (s *Service) Update(ctx context.Context, v *model.PromptVersion, pub Publisher) error {
 	old, err := s.repo.GetByID(ctx, v.ID)
 	if err != nil {
 		s.logger.Error(err.Error())
@@ -98,6 +141,14 @@ func (s *Service) Update(ctx context.Context, v *model.PromptVersion) error {
 		return ErrVersionNotFound
 	}
 
+	// 已发布且内容未变时不重复检查
+	v.PromptID = old.PromptID
+	if v.IsPublish && (!old.IsPublish || v.Content != old.Content) {
+		if err := s.checkGate(ctx, v, pub); err != nil {
+			return err
+		}
+	}
+
 	v.BaseModel.CreatedAt = old.CreatedAt
 	// 如果发布版本，同步更新prompt原数据
 	if v.IsPublish {
@@ -0,0 +1,206 @@
+package virtual_key
+
+import (
+	"backend/internal/api/dto"
+	"backend/internal/model"
+	"backend/internal/repository/usage"
+	"backend/internal/repository/virtual_key"
+	"backend/pkg/common"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// KeyPrefix 虚拟 key 的固定前缀, 便于与用户 JWT 区分
+const KeyPrefix = "pm-"
+
+var (
+	ErrKeyNotFound      = errors.New("virtual key not found")
+	ErrKeyRevoked       = errors.New("virtual key has been revoked")
+	ErrKeyExpired       = errors.New("virtual key has expired")
+	ErrInvalidExpiresAt = errors.New("invalid expiresAt, expected format 2006-01-02 15:04:05")
+	ErrDatabaseErr      = errors.New("query error, please contact admin")
+)
+
+// SpendLimitError 虚拟 key 累计花费达到上限
+type SpendLimitError struct {
+	Name  string
+	Spent float64
+	Limit float64
+}
+
+func (e *SpendLimitError) Error() string {
+	return fmt.Sprintf("spend limit exhausted for api key %s: spent %.4f of %.4f", e.Name, e.Spent, e.Limit)
+}
+
+type IService interface {
+	Create(ctx context.Context, ownerID int64, ownerName string, req dto.CreateVirtualKeyDTO) (*model.VirtualKey, string, error)
+	List(ctx context.Context, ownerID int64) ([]*model.VirtualKey, error)
+	Rotate(ctx context.Context, ownerID int64, id string) (*model.VirtualKey, string, error)
+	Revoke(ctx context.Context, ownerID int64, id string) error
+	Resolve(ctx context.Context, key string) (*model.VirtualKey, error)
+	CheckSpend(ctx context.Context, k *model.VirtualKey) error
+}
+
+type Service struct {
+	repo      *virtual_key.Repo
+	usageRepo *usage.Repo
+	logger    *zap.Logger
+}
+
+func CreateVirtualKeyService(repo *virtual_key.Repo, usageRepo *usage.Repo, logger *zap.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		usageRepo: usageRepo,
+		logger:    logger,
+	}
+}
+
+// Create 创建虚拟 key, 明文只在创建时返回一次
+func (s *Service) Create(ctx context.Context, ownerID int64, ownerName string, req dto.CreateVirtualKeyDTO) (*model.VirtualKey, string, error) {
+	k := &model.VirtualKey{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		OwnerID:       ownerID,
+		OwnerName:     ownerName,
+		AllowedModels: strings.Join(req.AllowedModels, ","),
+		SpendLimit:    req.SpendLimit,
+	}
+	if strings.TrimSpace(req.ExpiresAt) != "" {
+		t, err := time.ParseInLocation(common.DateTimeLayout, req.ExpiresAt, time.Local)
+		if err != nil {
+			return nil, "", ErrInvalidExpiresAt
+		}
+		k.ExpiresAt = &t
+	}
+
+	plain, err := generateKey()
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", err
+	}
+	k.KeyHash = HashKey(plain)
+	k.KeyPrefix = displayPrefix(plain)
+
+	if err := s.repo.Create(ctx, k); err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", ErrDatabaseErr
+	}
+	return k, plain, nil
+}
+
+func (s *Service) List(ctx context.Context, ownerID int64) ([]*model.VirtualKey, error) {
+	list, err := s.repo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	return list, nil
+}
+
+// Rotate 重新生成 key 明文, 旧 key 立即失效
+func (s *Service) Rotate(ctx context.Context, ownerID int64, id string) (*model.VirtualKey, string, error) {
+	k, err := s.getOwned(ctx, ownerID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if k.IsRevoked {
+		return nil, "", ErrKeyRevoked
+	}
+
+	plain, err := generateKey()
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", err
+	}
+	k.KeyHash = HashKey(plain)
+	k.KeyPrefix = displayPrefix(plain)
+	if err := s.repo.UpdateHash(ctx, k.ID, k.KeyHash, k.KeyPrefix); err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", ErrDatabaseErr
+	}
+	return k, plain, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, ownerID int64, id string) error {
+	if _, err := s.getOwned(ctx, ownerID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	return nil
+}
+
+// Resolve 根据明文 key 查找可用的虚拟 key
+func (s *Service) Resolve(ctx context.Context, key string) (*model.VirtualKey, error) {
+	k, err := s.repo.GetByHash(ctx, HashKey(key))
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if k == nil {
+		return nil, ErrKeyNotFound
+	}
+	if k.IsRevoked {
+		return nil, ErrKeyRevoked
+	}
+	if k.IsExpired(time.Now()) {
+		return nil, ErrKeyExpired
+	}
+	return k, nil
+}
+
+// CheckSpend 检查虚拟 key 的累计花费, 超限返回 *SpendLimitError
+func (s *Service) CheckSpend(ctx context.Context, k *model.VirtualKey) error {
+	if k.SpendLimit <= 0 {
+		return nil
+	}
+	spent, err := s.usageRepo.SumCostByKey(ctx, k.ID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return ErrDatabaseErr
+	}
+	if spent >= k.SpendLimit {
+		return &SpendLimitError{Name: k.Name, Spent: spent, Limit: k.SpendLimit}
+	}
+	return nil
+}
+
+func (s *Service) getOwned(ctx context.Context, ownerID int64, id string) (*model.VirtualKey, error) {
+	k, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrDatabaseErr
+	}
+	if k == nil || k.OwnerID != ownerID {
+		return nil, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return KeyPrefix + hex.EncodeToString(b), nil
+}
+
+// displayPrefix 用于列表展示的 key 前缀, 如 pm-1a2b3c4d
+func displayPrefix(key string) string {
+	return key[:len(KeyPrefix)+8]
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -21,7 +22,39 @@ func Load() *Config {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		log.Fatal(err)
 	}
+	applyDefaults(&cfg)
+	return &cfg
+}
+
+// Reload 重新读取配置文件, 返回新的配置, 不影响已加载的配置
+func Reload() (*Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
 
+// Watch 监听配置文件变化, 每次变化后以新配置回调
+func Watch(onChange func(cfg *Config)) {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		log.Printf("config file changed: %s", e.Name)
+		var cfg Config
+		if err := viper.Unmarshal(&cfg); err != nil {
+			log.Printf("reload config failed: %v", err)
+			return
+		}
+		applyDefaults(&cfg)
+		onChange(&cfg)
+	})
+	viper.WatchConfig()
+}
+
+func applyDefaults(cfg *Config) {
 	if strings.TrimSpace(cfg.Server.Storage) == "" {
 		cfg.Server.Storage = "storage"
 		cfg.Log.RootDir = fmt.Sprintf("./%s/%s", cfg.Server.Storage, cfg.Log.RootDir)
@@ -41,5 +74,7 @@ func Load() *Config {
 		log.Printf("default html: %+v", cfg.Web.DefaultHtml)
 	}
 
-	return &cfg
+	if strings.TrimSpace(cfg.Security.EncryptKey) == "" {
+		cfg.Security.EncryptKey = cfg.Security.SecretKey
+	}
 }
@@ -9,6 +9,8 @@ type Config struct {
 		ApiPrefix string `mapstructure:"apiPrefix" yaml:"apiPrefix"`
 		Storage   string `mapstructure:"storage" yaml:"storage"`
 		Buffer    int    `mapstructure:"buffer" yaml:"buffer"`
+		// TrustedProxies 可信反向代理的 IP / CIDR, 只有来自这些地址的请求才读取 X-Forwarded-For 等头, 为空时使用连接地址
+		TrustedProxies []string `mapstructure:"trustedProxies" yaml:"trustedProxies"`
 	} `mapstructure:"server" yaml:"server"`
 	Log struct {
 		Level      string `mapstructure:"level" yaml:"level"`
@@ -26,10 +28,43 @@ type Config struct {
 	Security struct {
 		SecretKey       string `mapstructure:"secretKey" yaml:"secretKey"`
 		TokenExpireHour int    `mapstructure:"tokenExpireHour" yaml:"tokenExpireHour"`
+		// EncryptKey 数据库中敏感字段(如上游 api key)的加密密钥, 为空时使用 secretKey
+		EncryptKey string `mapstructure:"encryptKey" yaml:"encryptKey"`
+		// Admins 管理员用户名列表
+		Admins []string `mapstructure:"admins" yaml:"admins"`
 	} `mapstructure:"security" yaml:"security"`
-	DB    DBConfig `mapstructure:"db" yaml:"db"`
-	Proxy Proxy    `mapstructure:"proxy" yaml:"proxy"`
-	Else  Else     `mapstructure:"else" yaml:"else"`
+	DB       DBConfig `mapstructure:"db" yaml:"db"`
+	Proxy    Proxy    `mapstructure:"proxy" yaml:"proxy"`
+	Eval     Eval     `mapstructure:"eval" yaml:"eval"`
+	Feedback Feedback `mapstructure:"feedback" yaml:"feedback"`
+	Else     Else     `mapstructure:"else" yaml:"else"`
+}
+
+// Feedback 下游应用上报的评价
+type Feedback struct {
+	// MinScore / MaxScore 允许的分数范围(含两端), 未配置或 MaxScore 不大于 MinScore 时为 -1 ~ 5, 兼容点赞点踩与 5 分制评分
+	MinScore float64 `mapstructure:"minScore" yaml:"minScore"`
+	MaxScore float64 `mapstructure:"maxScore" yaml:"maxScore"`
+	// RatePerMinute 每个客户端 IP 每分钟最多上报次数, 默认 60, 小于 0 不限制
+	RatePerMinute int `mapstructure:"ratePerMinute" yaml:"ratePerMinute"`
+}
+
+// Eval 批量评测任务, 经模型代理调用模型
+type Eval struct {
+	// Workers 所有评测任务合计的并发请求上限, 默认 32
+	Workers int `mapstructure:"workers" yaml:"workers"`
+	// Concurrency 单个任务未指定并发时的默认值, 默认 4; MaxConcurrency 单个任务并发上限, 默认 16
+	Concurrency    int `mapstructure:"concurrency" yaml:"concurrency"`
+	MaxConcurrency int `mapstructure:"maxConcurrency" yaml:"maxConcurrency"`
+	// RequestTimeout 单行请求超时, 默认 5m
+	RequestTimeout time.Duration `mapstructure:"requestTimeout" yaml:"requestTimeout"`
+	// MaxRows 单个任务最多行数, 默认 10000
+	MaxRows int `mapstructure:"maxRows" yaml:"maxRows"`
+	// MaxJudgeItems 单次评审最多条数, 默认 100; JudgeRetries 评审结果无法解析时的重试次数, 默认 2
+	MaxJudgeItems int `mapstructure:"maxJudgeItems" yaml:"maxJudgeItems"`
+	JudgeRetries  int `mapstructure:"judgeRetries" yaml:"judgeRetries"`
+	// GateTimeout 发布门禁整体超时, 超时后拒绝发布, 默认 2m
+	GateTimeout time.Duration `mapstructure:"gateTimeout" yaml:"gateTimeout"`
 }
 
 type DBConfig struct {
@@ -66,6 +101,9 @@ type PostgresConfig struct {
 }
 
 type Proxy struct {
+	// HotReload 监听配置文件变化, 自动重新加载 models 等配置; server / limits / cache / capture / budgets 需要重启
+	HotReload bool `mapstructure:"hot_reload" yaml:"hot_reload"`
+
 	Server struct {
 		Host         string        `mapstructure:"host" yaml:"host"`
 		Port         int           `mapstructure:"port" yaml:"port"`
@@ -77,8 +115,31 @@ type Proxy struct {
 	Limits struct {
 		NonStreamConcurrency int `mapstructure:"non_stream_concurrency" yaml:"non_stream_concurrency"`
 		StreamConcurrency    int `mapstructure:"stream_concurrency" yaml:"stream_concurrency"`
+		// Queue 并发打满时排队等待而不是直接返回 429
+		Queue struct {
+			Enabled bool          `mapstructure:"enabled" yaml:"enabled"`
+			MaxSize int           `mapstructure:"max_size" yaml:"max_size"`
+			Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+		} `mapstructure:"queue" yaml:"queue"`
 	} `mapstructure:"limits" yaml:"limits"`
 
+	// Timeouts 上游请求超时, models 按模型覆盖(模型名会被 viper 转为小写), 未配置的项使用默认值
+	Timeouts struct {
+		ProxyTimeouts `mapstructure:",squash" yaml:",inline"`
+		Models        map[string]ProxyTimeouts `mapstructure:"models" yaml:"models"`
+	} `mapstructure:"timeouts" yaml:"timeouts"`
+
+	// Headers 请求/响应头转发规则, 在内置 allow 列表基础上追加; providers 按上游服务名或协议类型覆盖
+	Headers struct {
+		HeaderRules `mapstructure:",squash" yaml:",inline"`
+		Providers   map[string]HeaderRules `mapstructure:"providers" yaml:"providers"`
+	} `mapstructure:"headers" yaml:"headers"`
+
+	// Stream 上游 stream 两次数据之间超过 idle_timeout(默认 2m) 时中断并向客户端返回错误事件
+	Stream struct {
+		IdleTimeout time.Duration `mapstructure:"idle_timeout" yaml:"idle_timeout"`
+	} `mapstructure:"stream" yaml:"stream"`
+
 	HttpClient struct {
 		MaxIdleConns        int           `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`
 		MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host"`
@@ -86,13 +147,155 @@ type Proxy struct {
 		IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout" yaml:"idle_conn_timeout"`
 	} `mapstructure:"http_client" yaml:"http_client"`
 
-	Models map[string]struct {
-		Endpoints []struct {
-			Name    string `mapstructure:"name" yaml:"name"`
-			ApiBase string `mapstructure:"api_base" yaml:"api_base"`
-			ApiKey  string `mapstructure:"api_key" yaml:"api_key"`
-		} `mapstructure:"endpoints" yaml:"endpoints"`
-	} `mapstructure:"models" yaml:"models"`
+	// Auth 代理鉴权: 除用户 JWT 外, 可在此签发 API key 并限制可用模型
+	Auth struct {
+		Keys []ProxyKey `mapstructure:"keys" yaml:"keys"`
+	} `mapstructure:"auth" yaml:"auth"`
+
+	// RateLimits 按调用方(用户/API key)+模型的令牌桶限流, 调用方通过 users/keys 映射到 tier
+	RateLimits struct {
+		Enabled     bool                     `mapstructure:"enabled" yaml:"enabled"`
+		DefaultTier string                   `mapstructure:"default_tier" yaml:"default_tier"`
+		Tiers       map[string]RateLimitTier `mapstructure:"tiers" yaml:"tiers"`
+		Users       map[string]string        `mapstructure:"users" yaml:"users"`
+		Keys        map[string]string        `mapstructure:"keys" yaml:"keys"`
+	} `mapstructure:"rate_limits" yaml:"rate_limits"`
+
+	// Cache 确定性请求(temperature=0)的完全匹配响应缓存
+	Cache struct {
+		Enabled       bool          `mapstructure:"enabled" yaml:"enabled"`
+		TTL           time.Duration `mapstructure:"ttl" yaml:"ttl"`
+		MaxEntries    int           `mapstructure:"max_entries" yaml:"max_entries"`
+		MaxBytes      int64         `mapstructure:"max_bytes" yaml:"max_bytes"`
+		MaxEntryBytes int64         `mapstructure:"max_entry_bytes" yaml:"max_entry_bytes"`
+	} `mapstructure:"cache" yaml:"cache"`
+
+	// Capture 持久化完整的请求/响应用于排查与重放, 页面调试的请求不受采样率限制
+	Capture struct {
+		Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+		// SampleRate 其余请求的采样率, 0 不采样, 1 全部记录; 未配置时为 1
+		SampleRate *float64 `mapstructure:"sample_rate" yaml:"sample_rate"`
+		// MaxBodyBytes 单个请求/响应体保存的最大字节数, 超出部分截断
+		MaxBodyBytes int `mapstructure:"max_body_bytes" yaml:"max_body_bytes"`
+		// RedactHeaders / RedactPatterns 在内置规则之外追加需要脱敏的请求头和正则
+		RedactHeaders  []string `mapstructure:"redact_headers" yaml:"redact_headers"`
+		RedactPatterns []string `mapstructure:"redact_patterns" yaml:"redact_patterns"`
+	} `mapstructure:"capture" yaml:"capture"`
+
+	// Routes 按接口分组覆盖转发策略, key 为路由名: chat / completions / embeddings / moderations / images / audio / files / batches
+	Routes map[string]ProxyRoute `mapstructure:"routes" yaml:"routes"`
+
+	// Guardrails 请求/响应内容检查, 随 models 一起热加载
+	Guardrails struct {
+		Guards []GuardrailGuard `mapstructure:"guards" yaml:"guards"`
+		// Models 按模型选择检查规则和处理方式, key 为模型名, "*" 为未单独配置模型的默认值
+		Models map[string]GuardrailPolicy `mapstructure:"models" yaml:"models"`
+		// MaxStreamBytes 响应检查时 stream 在代理中最多缓存的字节数, 默认 1MB
+		MaxStreamBytes int64 `mapstructure:"max_stream_bytes" yaml:"max_stream_bytes"`
+	} `mapstructure:"guardrails" yaml:"guardrails"`
+
+	Models map[string]ProxyModel `mapstructure:"models" yaml:"models"`
+
+	// Budgets 费用预算, 0 表示不限制; users/departments 的 key 会被 viper 转为小写
+	Budgets struct {
+		Default     Budget            `mapstructure:"default" yaml:"default"`
+		Users       map[string]Budget `mapstructure:"users" yaml:"users"`
+		Departments map[string]Budget `mapstructure:"departments" yaml:"departments"`
+	} `mapstructure:"budgets" yaml:"budgets"`
+}
+
+type ProxyModel struct {
+	Price     ModelPrice      `mapstructure:"price" yaml:"price"`
+	Endpoints []ProxyEndpoint `mapstructure:"endpoints" yaml:"endpoints"`
+}
+
+// ModelPrice 每百万 token 的价格
+type ModelPrice struct {
+	Input  float64 `mapstructure:"input" yaml:"input"`
+	Output float64 `mapstructure:"output" yaml:"output"`
+}
+
+type ProxyKey struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	Key  string `mapstructure:"key" yaml:"key"`
+	// Models 允许调用的模型, 为空表示不限制
+	Models []string `mapstructure:"models" yaml:"models"`
+	// Interactive 为 true 时该 key 可通过 X-Proxy-Priority: interactive 优先出队
+	Interactive bool `mapstructure:"interactive" yaml:"interactive"`
+}
+
+type GuardrailGuard struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// Type regex / keyword / length / webhook
+	Type string `mapstructure:"type" yaml:"type"`
+	// Stage request / response, 为空时两者都检查
+	Stage string `mapstructure:"stage" yaml:"stage"`
+	// Patterns regex 类型的正则列表
+	Patterns []string `mapstructure:"patterns" yaml:"patterns"`
+	// Keywords keyword 类型的屏蔽词, 不区分大小写
+	Keywords []string `mapstructure:"keywords" yaml:"keywords"`
+	// MaxLength length 类型允许的最大字符数(所有文本合计)
+	MaxLength int `mapstructure:"max_length" yaml:"max_length"`
+	// URL webhook 类型的检查地址
+	URL     string            `mapstructure:"url" yaml:"url"`
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+	Timeout time.Duration     `mapstructure:"timeout" yaml:"timeout"`
+	// FailOpen webhook 调用失败时放行, 默认按命中处理
+	FailOpen bool `mapstructure:"fail_open" yaml:"fail_open"`
+}
+
+type GuardrailPolicy struct {
+	// Mode block 拦截 / redact 脱敏后放行 / log 只记录日志
+	Mode   string   `mapstructure:"mode" yaml:"mode"`
+	Guards []string `mapstructure:"guards" yaml:"guards"`
+}
+
+type ProxyRoute struct {
+	// Enabled 为空时使用路由的默认值(files / batches 默认关闭)
+	Enabled *bool `mapstructure:"enabled" yaml:"enabled"`
+	// Concurrency 大于 0 时该路由使用独立的并发池
+	Concurrency int `mapstructure:"concurrency" yaml:"concurrency"`
+	// Model 请求中不带 model 的接口(files / batches)固定使用该模型的第一个上游
+	Model string `mapstructure:"model" yaml:"model"`
+}
+
+// HeaderPolicy 支持 "X-Stainless-*" 形式的前缀通配, deny 优先于 allow
+type HeaderPolicy struct {
+	Allow []string `mapstructure:"allow" yaml:"allow"`
+	Deny  []string `mapstructure:"deny" yaml:"deny"`
+}
+
+type HeaderRules struct {
+	Request  HeaderPolicy `mapstructure:"request" yaml:"request"`
+	Response HeaderPolicy `mapstructure:"response" yaml:"response"`
+}
+
+// ProxyTimeouts 上游超时: 建立连接、收到首字节、整个请求(包括 stream 读取), 0 表示未配置
+type ProxyTimeouts struct {
+	Connect   time.Duration `mapstructure:"connect" yaml:"connect"`
+	FirstByte time.Duration `mapstructure:"first_byte" yaml:"first_byte"`
+	Total     time.Duration `mapstructure:"total" yaml:"total"`
+}
+
+type ProxyEndpoint struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// Type 上游协议: openai(默认) | anthropic | gemini | ollama
+	Type    string `mapstructure:"type" yaml:"type"`
+	ApiBase string `mapstructure:"api_base" yaml:"api_base"`
+	ApiKey  string `mapstructure:"api_key" yaml:"api_key"`
+	// Headers 转发时附加的请求头, 如 OpenAI-Organization / OpenAI-Project
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+}
+
+// RateLimitTier 每分钟请求数及 token 数, 0 表示不限制
+type RateLimitTier struct {
+	RequestsPerMinute int64 `mapstructure:"rpm" yaml:"rpm"`
+	TokensPerMinute   int64 `mapstructure:"tpm" yaml:"tpm"`
+}
+
+type Budget struct {
+	Daily   float64 `mapstructure:"daily" yaml:"daily"`
+	Monthly float64 `mapstructure:"monthly" yaml:"monthly"`
 }
 
 type Else struct {
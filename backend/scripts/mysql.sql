@@ -106,3 +106,313 @@ CREATE TABLE IF NOT EXISTS recently_used
     UNIQUE KEY uk_recently_used_user_prompt (user_id, prompt_id)
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4 COMMENT ='最近使用表';
+
+
+-- proxy_usage (模型代理用量及费用)
+CREATE TABLE IF NOT EXISTS proxy_usage
+(
+    id                CHAR(36)       NOT NULL PRIMARY KEY,
+    user_id           BIGINT         NOT NULL DEFAULT 0 COMMENT '用户ID, 0 为匿名',
+    username          VARCHAR(64)    NOT NULL DEFAULT '',
+    department        VARCHAR(64)    NOT NULL DEFAULT '',
+    key_id            VARCHAR(36)    NOT NULL DEFAULT '' COMMENT '虚拟 key ID',
+    model             VARCHAR(128)   NOT NULL,
+    endpoint          VARCHAR(128)   NOT NULL,
+    stream            TINYINT(1)     NOT NULL DEFAULT 0,
+    status_code       INT            NOT NULL DEFAULT 0,
+    prompt_tokens     BIGINT         NOT NULL DEFAULT 0,
+    completion_tokens BIGINT         NOT NULL DEFAULT 0,
+    cost              DECIMAL(18, 8) NOT NULL DEFAULT 0 COMMENT '费用',
+    created_at        TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_proxy_usage_user (user_id, created_at),
+    INDEX idx_proxy_usage_department (department, created_at),
+    INDEX idx_proxy_usage_key (key_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模型代理用量表';
+
+
+-- virtual_keys (代理虚拟 API key)
+CREATE TABLE IF NOT EXISTS virtual_keys
+(
+    id             CHAR(36)       NOT NULL PRIMARY KEY,
+    name           VARCHAR(128)   NOT NULL,
+    key_prefix     VARCHAR(16)    NOT NULL,
+    key_hash       CHAR(64)       NOT NULL COMMENT 'sha256(key)',
+    owner_id       BIGINT         NOT NULL,
+    owner_name     VARCHAR(64)    NOT NULL,
+    allowed_models TEXT           NOT NULL COMMENT '逗号分隔, 为空不限制',
+    spend_limit    DECIMAL(18, 8) NOT NULL DEFAULT 0 COMMENT '0 不限制',
+    expires_at     TIMESTAMP      NULL,
+    is_revoked     TINYINT(1)     NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_virtual_keys_hash (key_hash),
+    INDEX idx_virtual_keys_owner (owner_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='代理虚拟 key 表';
+
+-- proxy_providers (模型代理上游服务, api_key 加密存储)
+CREATE TABLE IF NOT EXISTS proxy_providers
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    name       VARCHAR(128) NOT NULL,
+    type       VARCHAR(32)  NOT NULL DEFAULT 'openai' COMMENT 'openai | anthropic | gemini | ollama',
+    api_base   VARCHAR(512) NOT NULL,
+    api_key    TEXT         NOT NULL COMMENT 'AES-GCM 加密',
+    headers    TEXT         NOT NULL COMMENT '附加请求头 JSON',
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_proxy_providers_name (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模型代理上游服务表';
+
+-- proxy_models (模型代理对外暴露的模型)
+CREATE TABLE IF NOT EXISTS proxy_models
+(
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    name         VARCHAR(128)   NOT NULL,
+    input_price  DECIMAL(18, 8) NOT NULL DEFAULT 0 COMMENT '每百万输入 token',
+    output_price DECIMAL(18, 8) NOT NULL DEFAULT 0 COMMENT '每百万输出 token',
+    created_at   TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_proxy_models_name (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模型代理模型表';
+
+-- proxy_model_endpoints (模型 -> 上游服务映射)
+CREATE TABLE IF NOT EXISTS proxy_model_endpoints
+(
+    model_id    CHAR(36) NOT NULL,
+    provider_id CHAR(36) NOT NULL,
+    position    INT      NOT NULL DEFAULT 0 COMMENT '轮询顺序',
+    PRIMARY KEY (model_id, provider_id),
+    INDEX idx_proxy_model_endpoints_provider (provider_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模型上游映射表';
+
+-- proxy_captures (模型代理请求/响应抓取记录)
+CREATE TABLE IF NOT EXISTS proxy_captures
+(
+    id              CHAR(36)     NOT NULL PRIMARY KEY,
+    user_id         BIGINT       NOT NULL DEFAULT 0,
+    username        VARCHAR(64)  NOT NULL DEFAULT '',
+    key_id          VARCHAR(36)  NOT NULL DEFAULT '',
+    prompt_id       VARCHAR(64)  NOT NULL DEFAULT '',
+    replay_of       VARCHAR(36)  NOT NULL DEFAULT '' COMMENT '重放来源的抓取记录 id',
+    model           VARCHAR(128) NOT NULL,
+    endpoint        VARCHAR(128) NOT NULL DEFAULT '',
+    path            VARCHAR(255) NOT NULL,
+    stream          TINYINT(1)   NOT NULL DEFAULT 0,
+    status_code     INT          NOT NULL DEFAULT 0,
+    latency_ms      BIGINT       NOT NULL DEFAULT 0,
+    request_headers TEXT         NOT NULL COMMENT 'JSON, 已脱敏',
+    request_body    MEDIUMTEXT   NOT NULL,
+    upstream_url    VARCHAR(512) NOT NULL DEFAULT '',
+    upstream_body   MEDIUMTEXT   NOT NULL,
+    response_body   MEDIUMTEXT   NOT NULL,
+    created_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_proxy_captures_user (user_id, created_at),
+    INDEX idx_proxy_captures_prompt (prompt_id, created_at)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='模型代理请求抓取表';
+
+-- datasets (提示词评测数据集)
+CREATE TABLE IF NOT EXISTS datasets
+(
+    id          CHAR(36)     NOT NULL PRIMARY KEY,
+    prompt_id   CHAR(36)     NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    version     INT          NOT NULL DEFAULT 0 COMMENT '最新快照版本号, 0 表示未生成快照',
+    row_count   INT          NOT NULL DEFAULT 0 COMMENT '当前可编辑数据行数',
+    created_by  VARCHAR(64)  NOT NULL,
+    username    VARCHAR(64)  NOT NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_datasets_prompt (prompt_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='评测数据集表';
+
+-- dataset_versions (数据集快照)
+CREATE TABLE IF NOT EXISTS dataset_versions
+(
+    id         CHAR(36)    NOT NULL PRIMARY KEY,
+    dataset_id CHAR(36)    NOT NULL,
+    version    INT         NOT NULL,
+    row_count  INT         NOT NULL DEFAULT 0,
+    change_log TEXT        NOT NULL,
+    created_by VARCHAR(64) NOT NULL,
+    username   VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_dataset_versions (dataset_id, version)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='评测数据集快照表';
+
+-- dataset_rows (数据集行)
+CREATE TABLE IF NOT EXISTS dataset_rows
+(
+    id         CHAR(36)   NOT NULL PRIMARY KEY,
+    dataset_id CHAR(36)   NOT NULL,
+    version    INT        NOT NULL DEFAULT 0 COMMENT '0 为当前可编辑数据, 其余为快照',
+    position   INT        NOT NULL DEFAULT 0,
+    variables  MEDIUMTEXT NOT NULL COMMENT '变量取值 JSON 对象',
+    expected   MEDIUMTEXT NOT NULL COMMENT '期望输出',
+    reference  MEDIUMTEXT NOT NULL COMMENT '参考资料',
+    created_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_dataset_rows_dataset (dataset_id, version, position)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='评测数据集行表';
+
+-- eval_runs (批量评测任务)
+CREATE TABLE IF NOT EXISTS eval_runs
+(
+    id                CHAR(36)       NOT NULL PRIMARY KEY,
+    prompt_id         CHAR(36)       NOT NULL,
+    version_id        CHAR(36)       NOT NULL,
+    dataset_id        VARCHAR(36)    NOT NULL DEFAULT '' COMMENT '为空表示使用上传的文件',
+    dataset_version   INT            NOT NULL DEFAULT 0,
+    model             VARCHAR(128)   NOT NULL,
+    params            TEXT           NOT NULL COMMENT '模型参数 JSON',
+    concurrency       INT            NOT NULL DEFAULT 1,
+    status            VARCHAR(16)    NOT NULL COMMENT 'pending | running | completed | failed | canceled',
+    total             INT            NOT NULL DEFAULT 0,
+    succeeded         INT            NOT NULL DEFAULT 0,
+    failed            INT            NOT NULL DEFAULT 0,
+    prompt_tokens     BIGINT         NOT NULL DEFAULT 0,
+    completion_tokens BIGINT         NOT NULL DEFAULT 0,
+    cost              DECIMAL(18, 8) NOT NULL DEFAULT 0,
+    error             TEXT           NOT NULL,
+    user_id           BIGINT         NOT NULL DEFAULT 0,
+    username          VARCHAR(64)    NOT NULL DEFAULT '',
+    started_at        TIMESTAMP      NULL,
+    finished_at       TIMESTAMP      NULL,
+    created_at        TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_eval_runs_prompt (prompt_id, created_at),
+    INDEX idx_eval_runs_version (version_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='批量评测任务表';
+
+-- eval_results (评测任务逐行结果)
+CREATE TABLE IF NOT EXISTS eval_results
+(
+    id                CHAR(36)       NOT NULL PRIMARY KEY,
+    run_id            CHAR(36)       NOT NULL,
+    position          INT            NOT NULL DEFAULT 0,
+    variables         MEDIUMTEXT     NOT NULL,
+    expected          MEDIUMTEXT     NOT NULL,
+    reference         MEDIUMTEXT     NOT NULL,
+    input             MEDIUMTEXT     NOT NULL COMMENT '渲染后的提示词',
+    output            MEDIUMTEXT     NOT NULL COMMENT '模型输出',
+    status            VARCHAR(16)    NOT NULL COMMENT 'pending | succeeded | failed | canceled',
+    error             TEXT           NOT NULL,
+    latency_ms        BIGINT         NOT NULL DEFAULT 0,
+    prompt_tokens     BIGINT         NOT NULL DEFAULT 0,
+    completion_tokens BIGINT         NOT NULL DEFAULT 0,
+    cost              DECIMAL(18, 8) NOT NULL DEFAULT 0,
+    created_at        TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_eval_results_run (run_id, position)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='评测任务结果表';
+
+-- eval_assertion_sets (评分断言集)
+CREATE TABLE IF NOT EXISTS eval_assertion_sets
+(
+    id          CHAR(36)     NOT NULL PRIMARY KEY,
+    prompt_id   CHAR(36)     NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    assertions  MEDIUMTEXT   NOT NULL COMMENT '断言列表 JSON',
+    created_by  VARCHAR(64)  NOT NULL,
+    username    VARCHAR(64)  NOT NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_eval_assertion_sets_prompt (prompt_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='评分断言集表';
+
+-- eval_comparisons (两个版本的对比评测)
+CREATE TABLE IF NOT EXISTS eval_comparisons
+(
+    id                   CHAR(36)    NOT NULL PRIMARY KEY,
+    prompt_id            CHAR(36)    NOT NULL,
+    base_version_id      CHAR(36)    NOT NULL,
+    candidate_version_id CHAR(36)    NOT NULL,
+    base_run_id          CHAR(36)    NOT NULL,
+    candidate_run_id     CHAR(36)    NOT NULL,
+    assertions           MEDIUMTEXT  NOT NULL COMMENT '逐行评分断言 JSON',
+    regression_threshold DOUBLE      NOT NULL DEFAULT 0 COMMENT '得分下降超过该值记为退化',
+    user_id              BIGINT      NOT NULL DEFAULT 0,
+    username             VARCHAR(64) NOT NULL DEFAULT '',
+    created_at           TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at           TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_eval_comparisons_prompt (prompt_id, created_at)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='版本对比评测表';
+
+-- eval_gates (发布质量门禁, 每个提示词一个)
+CREATE TABLE IF NOT EXISTS eval_gates
+(
+    id            CHAR(36)     NOT NULL PRIMARY KEY,
+    prompt_id     CHAR(36)     NOT NULL,
+    model         VARCHAR(128) NOT NULL,
+    params        TEXT         NOT NULL COMMENT '模型参数 JSON',
+    cases         MEDIUMTEXT   NOT NULL COMMENT '测试用例 JSON',
+    assertions    MEDIUMTEXT   NOT NULL COMMENT '断言 JSON',
+    min_pass_rate DOUBLE       NOT NULL DEFAULT 1 COMMENT '最低通过率 0 ~ 1',
+    enabled       TINYINT(1)   NOT NULL DEFAULT 1,
+    created_by    VARCHAR(64)  NOT NULL,
+    username      VARCHAR(64)  NOT NULL,
+    created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX uk_eval_gates_prompt (prompt_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='发布质量门禁表';
+
+-- prompt_experiments (A/B 分流实验, 每个提示词一个)
+CREATE TABLE IF NOT EXISTS prompt_experiments
+(
+    id         CHAR(36)    NOT NULL PRIMARY KEY,
+    prompt_id  CHAR(36)    NOT NULL,
+    variants   TEXT        NOT NULL COMMENT '分组 JSON, 每项为 {name, versionId, weight}',
+    enabled    TINYINT(1)  NOT NULL DEFAULT 1,
+    created_by VARCHAR(64) NOT NULL,
+    username   VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX uk_prompt_experiments_prompt (prompt_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='提示词 A/B 分流实验表';
+
+-- prompt_feedback (下游应用对提示词输出的评价)
+CREATE TABLE IF NOT EXISTS prompt_feedback
+(
+    id         CHAR(36)     NOT NULL PRIMARY KEY,
+    prompt_id  CHAR(36)     NOT NULL,
+    version_id CHAR(36)     NOT NULL,
+    trace_id   VARCHAR(128) NOT NULL DEFAULT '' COMMENT '下游 trace / request ID',
+    score      DOUBLE       NOT NULL,
+    tags       TEXT         NOT NULL COMMENT '标签 JSON 数组',
+    comment    TEXT         NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_prompt_feedback_prompt (prompt_id, created_at),
+    INDEX idx_prompt_feedback_version (version_id, created_at),
+    INDEX idx_prompt_feedback_trace (trace_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='提示词反馈表';
+
+-- prompt_feedback_tags (反馈标签, 用于按版本统计)
+CREATE TABLE IF NOT EXISTS prompt_feedback_tags
+(
+    feedback_id CHAR(36)    NOT NULL,
+    prompt_id   CHAR(36)    NOT NULL,
+    version_id  CHAR(36)    NOT NULL,
+    tag         VARCHAR(64) NOT NULL,
+    created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feedback_id, tag),
+    INDEX idx_prompt_feedback_tags_tag (tag)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='提示词反馈标签表';
@@ -96,3 +96,277 @@ CREATE INDEX IF NOT EXISTS idx_recently_used_user_id ON recently_used(user_id);
 CREATE INDEX IF NOT EXISTS idx_recently_used_used_at ON recently_used(user_id, used_at);
 
 
+
+
+-- proxy_usage (模型代理用量及费用)
+CREATE TABLE IF NOT EXISTS proxy_usage (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    username TEXT NOT NULL DEFAULT '',
+    department TEXT NOT NULL DEFAULT '',
+    key_id TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    stream BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_usage_user ON proxy_usage(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_proxy_usage_department ON proxy_usage(department, created_at);
+CREATE INDEX IF NOT EXISTS idx_proxy_usage_key ON proxy_usage(key_id);
+
+
+-- virtual_keys (代理虚拟 API key)
+CREATE TABLE IF NOT EXISTS virtual_keys (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    owner_id BIGINT NOT NULL,
+    owner_name TEXT NOT NULL,
+    allowed_models TEXT NOT NULL DEFAULT '',
+    spend_limit DOUBLE PRECISION NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NULL,
+    is_revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_virtual_keys_hash ON virtual_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_virtual_keys_owner ON virtual_keys(owner_id);
+
+-- proxy_providers (模型代理上游服务, api_key 加密存储)
+CREATE TABLE IF NOT EXISTS proxy_providers (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'openai',
+    api_base TEXT NOT NULL,
+    api_key TEXT NOT NULL,
+    headers TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_proxy_providers_name ON proxy_providers(name);
+
+-- proxy_models (模型代理对外暴露的模型)
+CREATE TABLE IF NOT EXISTS proxy_models (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    input_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    output_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_proxy_models_name ON proxy_models(name);
+
+-- proxy_model_endpoints (模型 -> 上游服务映射)
+CREATE TABLE IF NOT EXISTS proxy_model_endpoints (
+    model_id UUID NOT NULL,
+    provider_id UUID NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (model_id, provider_id)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_model_endpoints_provider ON proxy_model_endpoints(provider_id);
+
+-- proxy_captures (模型代理请求/响应抓取记录)
+CREATE TABLE IF NOT EXISTS proxy_captures (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    username TEXT NOT NULL DEFAULT '',
+    key_id TEXT NOT NULL DEFAULT '',
+    prompt_id TEXT NOT NULL DEFAULT '',
+    replay_of TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL,
+    endpoint TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL,
+    stream BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    request_headers TEXT NOT NULL DEFAULT '',
+    request_body TEXT NOT NULL DEFAULT '',
+    upstream_url TEXT NOT NULL DEFAULT '',
+    upstream_body TEXT NOT NULL DEFAULT '',
+    response_body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_captures_user ON proxy_captures(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_proxy_captures_prompt ON proxy_captures(prompt_id, created_at);
+
+-- datasets (提示词评测数据集, version 为最新快照版本号, 0 表示未生成快照)
+CREATE TABLE IF NOT EXISTS datasets (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 0,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    created_by TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_datasets_prompt ON datasets(prompt_id);
+
+-- dataset_versions (数据集快照)
+CREATE TABLE IF NOT EXISTS dataset_versions (
+    id UUID PRIMARY KEY,
+    dataset_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    change_log TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_dataset_versions ON dataset_versions(dataset_id, version);
+
+-- dataset_rows (数据集行, version 为 0 的是当前可编辑数据, 其余为快照)
+CREATE TABLE IF NOT EXISTS dataset_rows (
+    id UUID PRIMARY KEY,
+    dataset_id UUID NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    variables TEXT NOT NULL DEFAULT '{}',
+    expected TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_dataset_rows_dataset ON dataset_rows(dataset_id, version, position);
+
+-- eval_runs (批量评测任务)
+CREATE TABLE IF NOT EXISTS eval_runs (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL,
+    version_id UUID NOT NULL,
+    dataset_id TEXT NOT NULL DEFAULT '',
+    dataset_version INTEGER NOT NULL DEFAULT 0,
+    model TEXT NOT NULL,
+    params TEXT NOT NULL DEFAULT '{}',
+    concurrency INTEGER NOT NULL DEFAULT 1,
+    status TEXT NOT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    succeeded INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    cost NUMERIC(18, 8) NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    user_id BIGINT NOT NULL DEFAULT 0,
+    username TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_eval_runs_prompt ON eval_runs(prompt_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_eval_runs_version ON eval_runs(version_id);
+
+-- eval_results (评测任务逐行结果)
+CREATE TABLE IF NOT EXISTS eval_results (
+    id UUID PRIMARY KEY,
+    run_id UUID NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    variables TEXT NOT NULL DEFAULT '{}',
+    expected TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    input TEXT NOT NULL DEFAULT '',
+    output TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    cost NUMERIC(18, 8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_eval_results_run ON eval_results(run_id, position);
+
+-- eval_assertion_sets (评分断言集)
+CREATE TABLE IF NOT EXISTS eval_assertion_sets (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    assertions TEXT NOT NULL DEFAULT '[]',
+    created_by TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_eval_assertion_sets_prompt ON eval_assertion_sets(prompt_id);
+
+-- eval_comparisons (两个版本的对比评测)
+CREATE TABLE IF NOT EXISTS eval_comparisons (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL,
+    base_version_id UUID NOT NULL,
+    candidate_version_id UUID NOT NULL,
+    base_run_id UUID NOT NULL,
+    candidate_run_id UUID NOT NULL,
+    assertions TEXT NOT NULL DEFAULT '[]',
+    regression_threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    username TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_eval_comparisons_prompt ON eval_comparisons(prompt_id, created_at);
+
+-- eval_gates (发布质量门禁, 每个提示词一个)
+CREATE TABLE IF NOT EXISTS eval_gates (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL UNIQUE,
+    model TEXT NOT NULL,
+    params TEXT NOT NULL DEFAULT '{}',
+    cases TEXT NOT NULL DEFAULT '[]',
+    assertions TEXT NOT NULL DEFAULT '[]',
+    min_pass_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- prompt_experiments (A/B 分流实验, 每个提示词一个)
+CREATE TABLE IF NOT EXISTS prompt_experiments (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL UNIQUE,
+    variants TEXT NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- prompt_feedback (下游应用对提示词输出的评价)
+CREATE TABLE IF NOT EXISTS prompt_feedback (
+    id UUID PRIMARY KEY,
+    prompt_id UUID NOT NULL,
+    version_id UUID NOT NULL,
+    trace_id TEXT NOT NULL DEFAULT '',
+    score DOUBLE PRECISION NOT NULL,
+    tags TEXT NOT NULL DEFAULT '[]',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_prompt_feedback_prompt ON prompt_feedback(prompt_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_prompt_feedback_version ON prompt_feedback(version_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_prompt_feedback_trace ON prompt_feedback(trace_id);
+
+-- prompt_feedback_tags (反馈标签, 用于按版本统计)
+CREATE TABLE IF NOT EXISTS prompt_feedback_tags (
+    feedback_id UUID NOT NULL,
+    prompt_id UUID NOT NULL,
+    version_id UUID NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (feedback_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_prompt_feedback_tags_tag ON prompt_feedback_tags(tag);